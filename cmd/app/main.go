@@ -2,25 +2,55 @@ package main
 
 import (
 	"log"
-	"os"
+	"time"
 
+	"truck-checkout/internal/calendar"
+	"truck-checkout/internal/config"
 	"truck-checkout/internal/slack"
 	db "truck-checkout/internal/database"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/socketmode"
+	gcal "google.golang.org/api/calendar/v3"
 )
 
 func main() {
-	dbPath := os.Getenv("DATABASE_URL")
-	db.InitDB(dbPath)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	db.InitDB(cfg.DatabasePath)
+
+	// Business days are counted in the organization's time zone, not the
+	// server's (which is usually UTC in containers).
+	businessCalendar, err := cfg.BusinessCalendar()
+	if err != nil {
+		log.Fatalf("failed to load business calendar: %v", err)
+	}
+
+	var calendarService *gcal.Service
+	if cfg.GoogleCredentialsPath != "" {
+		calendarService, err = calendar.NewCalendarService(cfg.GoogleCredentialsPath)
+		if err != nil {
+			log.Fatalf("failed to connect to Google Calendar: %v", err)
+		}
+	}
+	h := handlers.NewHandler(cfg, businessCalendar, calendarService)
 
 	api := slack.New(
-		os.Getenv("SLACK_BOT_TOKEN"),
-		slack.OptionDebug(true),
-		slack.OptionAppLevelToken(os.Getenv("SLACK_APP_TOKEN")),
+		cfg.SlackBotToken,
+		slack.OptionDebug(cfg.Debug),
+		slack.OptionAppLevelToken(cfg.SlackAppToken),
 	)
 	client := socketmode.New(api)
+	messenger := handlers.NewSocketMessenger(client)
+
+	handlers.ReconcileTruckStatus()
+	go handlers.RunStatusReconciler(5 * time.Minute)
+	go h.RunWaitlistExpiry(messenger, time.Minute)
+	go h.RunQualificationReminders(messenger, time.Hour)
+	go h.RunProfileSync(client, time.Hour)
 
 	go func() {
 		for evt := range client.Events {
@@ -35,10 +65,10 @@ func main() {
 				log.Printf("Connection error: %v\n", evt)
 			case socketmode.EventTypeSlashCommand:
 				log.Println("Slash command received")
-				handlers.HandleSlashCommand(client, evt)
+				h.HandleSlashCommand(client, evt)
 			case socketmode.EventTypeInteractive:
 				log.Println("Interactive event received")
-				handlers.HandleInteractive(client, evt)
+				h.HandleInteractive(client, evt)
 			default:
 				log.Printf("Unhandled event: %+v\n", evt.Type)
 			}
@@ -11,6 +11,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// seedActor is recorded in the audit log for seeded changes.
+var seedActor = models.Actor{SlackUserID: "seed", Source: models.SourceAPI}
+
 func main() {
 	// Determine the database path, with a fallback for local development.
 	dbPath := os.Getenv("DATABASE_URL")
@@ -25,10 +28,14 @@ func main() {
 
 	// --- Data Cleanup ---
 	log.Println("🗑️  Clearing existing data...")
-	_, err := db.DB.Exec(`DELETE FROM checkouts; DELETE FROM trucks; DELETE FROM users;`)
+	// audit_events is append-only, so it is dropped and recreated instead.
+	_, err := db.DB.Exec(`DROP TABLE IF EXISTS audit_events; DELETE FROM waitlist_entries; DELETE FROM custody_events; DELETE FROM driver_qualifications; DELETE FROM fuel_purchases; DELETE FROM odometer_readings; DELETE FROM checkout_assets; DELETE FROM checkouts; DELETE FROM checkout_series; DELETE FROM assets; DELETE FROM trucks; DELETE FROM users;`)
 	if err != nil {
 		log.Fatalf("❌ Failed to reset database: %v", err)
 	}
+	if err := db.CreateTables(db.DB); err != nil {
+		log.Fatalf("❌ Failed to recreate tables: %v", err)
+	}
 	log.Println("🟢 All tables cleared.")
 
 	// --- Seed Trucks ---
@@ -48,7 +55,7 @@ func main() {
 		calendarID := uuid.NewString()
 
 		// Insert the truck with its default state. IsAvailable is false by default.
-		err := models.InsertTruck(truckData.Name, &truckData.DefaultTeam, calendarID, false) // Initially, all trucks are available.
+		err := models.InsertTruck(truckData.Name, &truckData.DefaultTeam, calendarID, false, seedActor) // Initially, all trucks are available.
 		if err != nil {
 			log.Fatalf("❌ Failed to insert truck %s: %v", truckData.Name, err)
 		}
@@ -56,6 +63,46 @@ func main() {
 	}
 	log.Println("🟢 Trucks seeded successfully.")
 
+	// --- Seed Assets ---
+	log.Println("🌱 Seeding trailers and equipment...")
+	for _, name := range []string{"Tulip", "Watson"} {
+		truck, err := models.GetTruckByName(name)
+		if err != nil {
+			log.Fatalf("❌ Failed to retrieve truck %s: %v", name, err)
+		}
+		truck.Features = []string{"hitch"}
+		if err := models.UpdateTruck(*truck, seedActor); err != nil {
+			log.Fatalf("❌ Failed to add hitch to %s: %v", name, err)
+		}
+	}
+	assetSeedData := []struct {
+		Name     string
+		Type     models.AssetType
+		Features []string
+		Requires []string
+	}{
+		{"Trailer", models.AssetTrailer, []string{"trailer"}, []string{"hitch"}},
+		{"Chipper", models.AssetEquipment, nil, []string{"hitch"}},
+		{"Watertank", models.AssetEquipment, nil, []string{"trailer"}},
+	}
+	for _, a := range assetSeedData {
+		if _, err := models.InsertAsset(a.Name, a.Type, a.Features, a.Requires, seedActor); err != nil {
+			log.Fatalf("❌ Failed to insert asset %s: %v", a.Name, err)
+		}
+		log.Printf("   🧰 Inserted %s: %s", a.Type, a.Name)
+	}
+	log.Println("🟢 Assets seeded successfully.")
+
+	// Andre350 has the dump bed, so drivers need a certification for it.
+	andre, err := models.GetTruckByName("Andre350")
+	if err != nil {
+		log.Fatalf("❌ Failed to retrieve truck Andre350: %v", err)
+	}
+	andre.Class = "dump"
+	if err := models.UpdateTruck(*andre, seedActor); err != nil {
+		log.Fatalf("❌ Failed to set class of Andre350: %v", err)
+	}
+
 	// --- Seed Checkouts ---
 	log.Println("🚛 Seeding example checkouts for Tulip and Bert...")
 
@@ -64,11 +111,14 @@ func main() {
 	testUserName := "Seeder McSeedface"
 	testUserTeam := "seeders" // Arbitrary team, since team doesn't restrict checkout
 
-	user, err := models.CreateUser(testUserSlackID, testUserName, testUserTeam)
+	user, err := models.CreateUser(testUserSlackID, testUserName, testUserTeam, seedActor)
 	if err != nil {
 		log.Fatalf("❌ Failed to create seed user: %v", err)
 	}
 	log.Printf("   👤 Created seed user: %s (ID: %s, Team: %s)", testUserName, testUserSlackID, testUserTeam)
+	if _, err := models.RecordQualification(testUserSlackID, models.QualificationLicense, "", time.Now().AddDate(2, 0, 0), seedActor); err != nil {
+		log.Fatalf("❌ Failed to record license for seed user: %v", err)
+	}
 	userID := user.ID
 	start := time.Now()
 	end := start.Add(8 * time.Hour) // A standard 8-hour checkout
@@ -96,17 +146,12 @@ func main() {
 			CreatedAt:       time.Now(),
 		}
 
-		// Insert the checkout record into the database.
-		if err = models.CreateCheckout(checkout); err != nil {
+		// Insert the checkout record into the database. This also marks
+		// the truck checked out.
+		if err = models.CreateCheckout(checkout, nil, seedActor); err != nil {
 			log.Fatalf("❌ Failed to insert checkout for %s: %v", truckName, err)
 		}
 
-		// Update the truck's availability status to checked out.
-		truck.IsCheckedOut = true
-		if err = models.UpdateTruck(*truck); err != nil {
-			log.Fatalf("❌ Failed to update availability for truck %s: %v", truckName, err)
-		}
-
 		log.Printf("   🟡 Checked out %s, status: UNAVAILABLE", truckName)
 	}
 
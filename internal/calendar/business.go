@@ -0,0 +1,346 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	// Embed the zone database so the organization time zone resolves even in
+	// minimal containers without /usr/share/zoneinfo.
+	_ "time/tzdata"
+)
+
+// DateFormat is the layout used for holiday dates.
+const DateFormat = "2006-01-02"
+
+// Hours is the working window for one weekday, in minutes after midnight.
+type Hours struct {
+	Open  int
+	Close int
+}
+
+// ParseHours parses a window such as "07:00", "15:30".
+func ParseHours(open, close string) (Hours, error) {
+	o, err := parseClock(open)
+	if err != nil {
+		return Hours{}, err
+	}
+	c, err := parseClock(close)
+	if err != nil {
+		return Hours{}, err
+	}
+	if c <= o {
+		return Hours{}, fmt.Errorf("closing time %s must be after opening time %s", close, open)
+	}
+	return Hours{Open: o, Close: c}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+type Holiday struct {
+	Date string // YYYY-MM-DD
+	Name string
+}
+
+// BusinessCalendar knows which days the organization works, the hours on
+// each of those days, its holidays and its time zone. All checkout dates are
+// computed through it, so business-day math never depends on the server's TZ.
+type BusinessCalendar struct {
+	hours    map[time.Weekday]Hours
+	holidays map[string]string
+	loc      *time.Location
+}
+
+// DefaultBusinessCalendar is Monday to Friday, 7:00 AM to 3:30 PM in the
+// server's local time zone, with no holidays.
+func DefaultBusinessCalendar() *BusinessCalendar {
+	c := &BusinessCalendar{
+		hours:    make(map[time.Weekday]Hours),
+		holidays: make(map[string]string),
+		loc:      time.Local,
+	}
+	for d := time.Monday; d <= time.Friday; d++ {
+		c.hours[d] = Hours{Open: 7 * 60, Close: 15*60 + 30}
+	}
+	return c
+}
+
+// NewBusinessCalendar builds a calendar from explicit hours; weekdays missing
+// from the map are days off.
+func NewBusinessCalendar(hours map[time.Weekday]Hours, holidays []Holiday, loc *time.Location) *BusinessCalendar {
+	c := &BusinessCalendar{
+		hours:    make(map[time.Weekday]Hours, len(hours)),
+		holidays: make(map[string]string, len(holidays)),
+		loc:      loc,
+	}
+	for d, h := range hours {
+		c.hours[d] = h
+	}
+	for _, h := range holidays {
+		c.AddHoliday(h)
+	}
+	return c
+}
+
+// Location is the organization time zone used for all business-day math.
+func (c *BusinessCalendar) Location() *time.Location {
+	return c.loc
+}
+
+func (c *BusinessCalendar) SetLocation(loc *time.Location) {
+	c.loc = loc
+}
+
+// Now returns the current time in the organization time zone.
+func (c *BusinessCalendar) Now() time.Time {
+	return time.Now().In(c.loc)
+}
+
+func (c *BusinessCalendar) AddHoliday(h Holiday) {
+	c.holidays[h.Date] = h.Name
+}
+
+// Holidays returns the configured holidays in date order.
+func (c *BusinessCalendar) Holidays() []Holiday {
+	list := make([]Holiday, 0, len(c.holidays))
+	for date, name := range c.holidays {
+		list = append(list, Holiday{Date: date, Name: name})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Date < list[j].Date })
+	return list
+}
+
+// IsBusinessDay reports whether t falls on a working weekday that is not a
+// holiday, judged by the date in the organization time zone.
+func (c *BusinessCalendar) IsBusinessDay(t time.Time) bool {
+	t = t.In(c.loc)
+	if _, ok := c.hours[t.Weekday()]; !ok {
+		return false
+	}
+	_, holiday := c.holidays[t.Format(DateFormat)]
+	return !holiday
+}
+
+// HoursOn returns the working hours on t's date, if it is a business day.
+func (c *BusinessCalendar) HoursOn(t time.Time) (Hours, bool) {
+	if !c.IsBusinessDay(t) {
+		return Hours{}, false
+	}
+	return c.hours[t.In(c.loc).Weekday()], true
+}
+
+// NextBusinessDay returns the first business day on or after t's date, at
+// midnight in the organization time zone.
+func (c *BusinessCalendar) NextBusinessDay(t time.Time) time.Time {
+	t = t.In(c.loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.loc)
+	// A year without a single business day means the calendar is misconfigured;
+	// give up rather than loop forever.
+	for i := 0; i < 366 && !c.IsBusinessDay(day); i++ {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// Opening returns the opening time on t's date, or zero hours if closed.
+func (c *BusinessCalendar) Opening(t time.Time) time.Time {
+	t = t.In(c.loc)
+	h := c.hours[t.Weekday()]
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, h.Open, 0, 0, c.loc)
+}
+
+// Closing returns the closing time on t's date, or midnight if closed.
+func (c *BusinessCalendar) Closing(t time.Time) time.Time {
+	t = t.In(c.loc)
+	h := c.hours[t.Weekday()]
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, h.Close, 0, 0, c.loc)
+}
+
+// CheckoutWindow returns the start and end of a checkout lasting
+// businessDays working days, beginning on the first business day on or
+// after from that hasn't closed yet. The start day counts as day one, and
+// the checkout ends at closing time on the last day.
+func (c *BusinessCalendar) CheckoutWindow(from time.Time, businessDays int) (time.Time, time.Time) {
+	first := c.NextBusinessDay(from)
+	if !c.Closing(first).After(from) {
+		first = c.NextBusinessDay(first.AddDate(0, 0, 1))
+	}
+	last := first
+	for i := 1; i < businessDays; i++ {
+		last = c.NextBusinessDay(last.AddDate(0, 0, 1))
+	}
+	return c.Opening(first), c.Closing(last)
+}
+
+// ExtendEnd returns the new end of a checkout ending at end that is extended
+// by businessDays: closing time on the businessDays-th business day after
+// end's date.
+func (c *BusinessCalendar) ExtendEnd(end time.Time, businessDays int) time.Time {
+	last := end.In(c.loc)
+	for i := 0; i < businessDays; i++ {
+		last = c.NextBusinessDay(last.AddDate(0, 0, 1))
+	}
+	return c.Closing(last)
+}
+
+// BusinessDaysIn counts the business days from start's date through end's
+// date, the way CheckoutWindow counts a checkout's length.
+func (c *BusinessCalendar) BusinessDaysIn(start, end time.Time) int {
+	start, end = start.In(c.loc), end.In(c.loc)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, c.loc)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, c.loc)
+	n := 0
+	for ; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if c.IsBusinessDay(day) {
+			n++
+		}
+	}
+	return n
+}
+
+// ErrOutsideHours is returned for a slot that doesn't fit within the
+// working hours of the day it would be booked on.
+var ErrOutsideHours = errors.New("slot is outside business hours")
+
+// Slot is a span of hours within a single day, in minutes after midnight,
+// for checkouts shorter than a full business day.
+type Slot struct {
+	Start int
+	End   int
+}
+
+// ParseSlot parses a span such as "9am-12pm", "9:30am-1pm" or "13:00-15:30".
+func ParseSlot(s string) (Slot, error) {
+	from, to, ok := strings.Cut(strings.ToLower(strings.ReplaceAll(s, " ", "")), "-")
+	if !ok {
+		return Slot{}, fmt.Errorf("invalid time range %q, expected e.g. 9am-12pm", s)
+	}
+	start, err := parseTimeOfDay(from)
+	if err != nil {
+		return Slot{}, err
+	}
+	end, err := parseTimeOfDay(to)
+	if err != nil {
+		return Slot{}, err
+	}
+	if end <= start {
+		return Slot{}, fmt.Errorf("end of time range %q must be after its start", s)
+	}
+	return Slot{Start: start, End: end}, nil
+}
+
+func parseTimeOfDay(s string) (int, error) {
+	for _, layout := range []string{"3pm", "3:04pm", "15:04"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Hour()*60 + t.Minute(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time of day %q, expected e.g. 9am, 9:30am or 13:00", s)
+}
+
+// String renders the slot in a form ParseSlot accepts, e.g. "9:00am-12:00pm".
+func (s Slot) String() string {
+	clock := func(min int) string {
+		return time.Date(0, 1, 1, 0, min, 0, 0, time.UTC).Format("3:04pm")
+	}
+	return clock(s.Start) + "-" + clock(s.End)
+}
+
+// SlotWindow returns the start and end of the next time slot comes around
+// on a business day: on from's date if it's a business day and the slot
+// hasn't ended yet, otherwise on the next business day. It returns
+// ErrOutsideHours if the slot doesn't fit within that day's hours.
+func (c *BusinessCalendar) SlotWindow(from time.Time, slot Slot) (time.Time, time.Time, error) {
+	from = from.In(c.loc)
+	day := c.NextBusinessDay(from)
+	if !time.Date(day.Year(), day.Month(), day.Day(), 0, slot.End, 0, 0, c.loc).After(from) {
+		day = c.NextBusinessDay(day.AddDate(0, 0, 1))
+	}
+	hours, _ := c.HoursOn(day)
+	if slot.Start < hours.Open || slot.End > hours.Close {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %s on %s", ErrOutsideHours, slot, day.Format(DateFormat))
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, slot.Start, 0, 0, c.loc),
+		time.Date(day.Year(), day.Month(), day.Day(), 0, slot.End, 0, 0, c.loc), nil
+}
+
+// Window is the start and end of one checkout.
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// RecurringWindows returns a checkout window of businessDays for each date
+// from first through last that falls on one of weekdays. Dates that are not
+// business days, such as holidays, are skipped rather than moved.
+func (c *BusinessCalendar) RecurringWindows(first, last time.Time, weekdays []time.Weekday, businessDays int) []Window {
+	first, last = first.In(c.loc), last.In(c.loc)
+	day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, c.loc)
+	end := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, c.loc)
+
+	var windows []Window
+	for ; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if !slices.Contains(weekdays, day.Weekday()) || !c.IsBusinessDay(day) {
+			continue
+		}
+		start, finish := c.CheckoutWindow(day, businessDays)
+		windows = append(windows, Window{Start: start, End: finish})
+	}
+	return windows
+}
+
+// FormatRange renders a checkout window for display in the organization time zone.
+func (c *BusinessCalendar) FormatRange(start, end time.Time) string {
+	start, end = start.In(c.loc), end.In(c.loc)
+	if start.Year() == end.Year() && start.Month() == end.Month() && start.Day() == end.Day() {
+		return fmt.Sprintf("%s (%s - %s)", start.Format("Jan 2, 2006"), start.Format("3:04 PM"), end.Format("3:04 PM"))
+	}
+	return fmt.Sprintf("%s %s - %s %s", start.Format("Jan 2"), start.Format("3:04 PM"), end.Format("Jan 2, 2006"), end.Format("3:04 PM"))
+}
+
+// ParseWeekday parses a weekday name such as "monday" or "Mon".
+func ParseWeekday(name string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(name, d.String()) || strings.EqualFold(name, d.String()[:3]) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
+
+// ParseWeekdays parses a comma-separated list of weekday names such as
+// "tue,thu" or "Tuesday,Thursday", returned Monday first without repeats.
+func ParseWeekdays(s string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, name := range strings.Split(s, ",") {
+		d, err := ParseWeekday(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(days, d) {
+			days = append(days, d)
+		}
+	}
+	slices.SortFunc(days, func(a, b time.Weekday) int {
+		return (int(a)+6)%7 - (int(b)+6)%7
+	})
+	return days, nil
+}
+
+// FormatWeekdays is the inverse of ParseWeekdays, e.g. "tue,thu".
+func FormatWeekdays(days []time.Weekday) string {
+	names := make([]string, len(days))
+	for i, d := range days {
+		names[i] = strings.ToLower(d.String()[:3])
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,286 @@
+package calendar
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestDefaultBusinessCalendar(t *testing.T) {
+	cal := DefaultBusinessCalendar()
+
+	tests := []struct {
+		day  time.Time
+		want bool
+	}{
+		{date(2026, time.October, 19, 0, 0), true},  // Monday
+		{date(2026, time.October, 23, 0, 0), true},  // Friday
+		{date(2026, time.October, 24, 0, 0), false}, // Saturday
+		{date(2026, time.October, 25, 0, 0), false}, // Sunday
+	}
+	for _, tt := range tests {
+		if got := cal.IsBusinessDay(tt.day); got != tt.want {
+			t.Errorf("IsBusinessDay(%s) = %v, want %v", tt.day.Format("Mon Jan 2"), got, tt.want)
+		}
+	}
+}
+
+func TestCheckoutWindow(t *testing.T) {
+	cal := DefaultBusinessCalendar()
+	cal.AddHoliday(Holiday{Date: "2026-11-26", Name: "Thanksgiving"})
+	cal.AddHoliday(Holiday{Date: "2026-11-27", Name: "Day after Thanksgiving"})
+
+	tests := []struct {
+		name      string
+		from      time.Time
+		days      int
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "single day",
+			from:      date(2026, time.October, 19, 9, 15),
+			days:      1,
+			wantStart: date(2026, time.October, 19, 7, 0),
+			wantEnd:   date(2026, time.October, 19, 15, 30),
+		},
+		{
+			name:      "over a weekend",
+			from:      date(2026, time.October, 22, 8, 0), // Thursday
+			days:      3,
+			wantStart: date(2026, time.October, 22, 7, 0),
+			wantEnd:   date(2026, time.October, 26, 15, 30), // Monday
+		},
+		{
+			name:      "starting on a weekend",
+			from:      date(2026, time.October, 24, 8, 0), // Saturday
+			days:      1,
+			wantStart: date(2026, time.October, 26, 7, 0),
+			wantEnd:   date(2026, time.October, 26, 15, 30),
+		},
+		{
+			name:      "over Thanksgiving",
+			from:      date(2026, time.November, 25, 7, 30), // Wednesday
+			days:      3,
+			wantStart: date(2026, time.November, 25, 7, 0),
+			wantEnd:   date(2026, time.December, 1, 15, 30), // Wed, Mon, Tue
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := cal.CheckoutWindow(tt.from, tt.days)
+			if !start.Equal(tt.wantStart) {
+				t.Errorf("start = %s, want %s", start, tt.wantStart)
+			}
+			if !end.Equal(tt.wantEnd) {
+				t.Errorf("end = %s, want %s", end, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestRecurringWindows(t *testing.T) {
+	cal := DefaultBusinessCalendar()
+	cal.AddHoliday(Holiday{Date: "2026-11-26", Name: "Thanksgiving"})
+
+	days, err := ParseWeekdays("thu, Tuesday,tue")
+	if err != nil {
+		t.Fatalf("failed to parse weekdays: %v", err)
+	}
+	if got := FormatWeekdays(days); got != "tue,thu" {
+		t.Errorf("FormatWeekdays = %q, want tue,thu", got)
+	}
+	if _, err := ParseWeekdays("tue,someday"); err == nil {
+		t.Error("expected an error for an unknown weekday")
+	}
+
+	// Two weeks of Tuesdays and Thursdays, with Thanksgiving skipped
+	windows := cal.RecurringWindows(date(2026, time.November, 17, 0, 0), date(2026, time.November, 26, 0, 0), days, 1)
+	want := []time.Time{
+		date(2026, time.November, 17, 7, 0),
+		date(2026, time.November, 19, 7, 0),
+		date(2026, time.November, 24, 7, 0),
+	}
+	if len(windows) != len(want) {
+		t.Fatalf("expected %d windows, got %+v", len(want), windows)
+	}
+	for i, w := range windows {
+		if !w.Start.Equal(want[i]) || !w.End.Equal(want[i].Add(8*time.Hour+30*time.Minute)) {
+			t.Errorf("window %d = %s - %s, want a day starting %s", i, w.Start, w.End, want[i])
+		}
+	}
+}
+
+func TestExtendEnd(t *testing.T) {
+	cal := DefaultBusinessCalendar()
+	cal.AddHoliday(Holiday{Date: "2026-11-26", Name: "Thanksgiving"})
+
+	tests := []struct {
+		name string
+		end  time.Time
+		days int
+		want time.Time
+		// wantDays is the length of a checkout starting Monday Nov 23 and
+		// ending at want.
+		wantDays int
+	}{
+		{"next day", date(2026, time.November, 23, 15, 30), 1, date(2026, time.November, 24, 15, 30), 2},
+		{"over a holiday", date(2026, time.November, 25, 15, 30), 1, date(2026, time.November, 27, 15, 30), 4},
+		{"over a weekend", date(2026, time.November, 27, 15, 30), 2, date(2026, time.December, 1, 15, 30), 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cal.ExtendEnd(tt.end, tt.days)
+			if !got.Equal(tt.want) {
+				t.Errorf("ExtendEnd = %s, want %s", got, tt.want)
+			}
+			if n := cal.BusinessDaysIn(date(2026, time.November, 23, 7, 0), got); n != tt.wantDays {
+				t.Errorf("BusinessDaysIn = %d, want %d", n, tt.wantDays)
+			}
+		})
+	}
+}
+
+func TestParseSlot(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Slot
+		wantErr bool
+	}{
+		{"9am-12pm", Slot{9 * 60, 12 * 60}, false},
+		{"9:30AM-1pm", Slot{9*60 + 30, 13 * 60}, false},
+		{"13:00-15:30", Slot{13 * 60, 15*60 + 30}, false},
+		{"12pm-9am", Slot{}, true},
+		{"9-12", Slot{}, true},
+		{"9am", Slot{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSlot(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSlot(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSlot(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if err == nil {
+				if again, err := ParseSlot(got.String()); err != nil || again != got {
+					t.Errorf("ParseSlot(%q) = %+v, %v, want it to round-trip", got.String(), again, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSlotWindow(t *testing.T) {
+	cal := DefaultBusinessCalendar()
+	cal.SetLocation(time.UTC)
+	morning := Slot{Start: 9 * 60, End: 12 * 60}
+
+	tests := []struct {
+		name      string
+		from      time.Time
+		slot      Slot
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   error
+	}{
+		{"later today", date(2026, time.October, 19, 8, 0), morning, date(2026, time.October, 19, 9, 0), date(2026, time.October, 19, 12, 0), nil},
+		{"under way", date(2026, time.October, 19, 10, 15), morning, date(2026, time.October, 19, 9, 0), date(2026, time.October, 19, 12, 0), nil},
+		{"over for today", date(2026, time.October, 19, 12, 0), morning, date(2026, time.October, 20, 9, 0), date(2026, time.October, 20, 12, 0), nil},
+		{"after a weekend", date(2026, time.October, 24, 8, 0), morning, date(2026, time.October, 26, 9, 0), date(2026, time.October, 26, 12, 0), nil},
+		{"after closing", date(2026, time.October, 19, 8, 0), Slot{Start: 14 * 60, End: 17 * 60}, time.Time{}, time.Time{}, ErrOutsideHours},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := cal.SlotWindow(tt.from, tt.slot)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SlotWindow error = %v, want %v", err, tt.wantErr)
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("SlotWindow = %s - %s, want %s - %s", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestFormatRange(t *testing.T) {
+	cal := DefaultBusinessCalendar()
+
+	got := cal.FormatRange(date(2026, time.October, 19, 7, 0), date(2026, time.October, 19, 15, 30))
+	if want := "Oct 19, 2026 (7:00 AM - 3:30 PM)"; got != want {
+		t.Errorf("FormatRange same day = %q, want %q", got, want)
+	}
+
+	got = cal.FormatRange(date(2026, time.October, 19, 9, 0), date(2026, time.October, 21, 12, 0))
+	if want := "Oct 19 9:00 AM - Oct 21, 2026 12:00 PM"; got != want {
+		t.Errorf("FormatRange multi-day = %q, want %q", got, want)
+	}
+}
+
+func TestCheckoutWindow_OrgTimeZone(t *testing.T) {
+	eastern, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	cal := DefaultBusinessCalendar()
+	cal.SetLocation(eastern)
+
+	tests := []struct {
+		name      string
+		from      time.Time // as the server in UTC sees it
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			// 11 PM Tuesday Eastern is already Wednesday in UTC, and Tuesday
+			// has closed, so the checkout is for Wednesday
+			name:      "11 PM Tuesday Eastern",
+			from:      time.Date(2026, time.October, 21, 3, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2026, time.October, 21, 7, 0, 0, 0, eastern),
+			wantEnd:   time.Date(2026, time.October, 21, 15, 30, 0, 0, eastern),
+		},
+		{
+			// 11 PM Friday Eastern is Saturday in UTC; Friday has closed and
+			// the weekend isn't business days, so the checkout is for Monday
+			name:      "11 PM Friday Eastern",
+			from:      time.Date(2026, time.October, 24, 3, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2026, time.October, 26, 7, 0, 0, 0, eastern),
+			wantEnd:   time.Date(2026, time.October, 26, 15, 30, 0, 0, eastern),
+		},
+		{
+			// Across the end of daylight saving time
+			name:      "over the DST change",
+			from:      time.Date(2026, time.October, 30, 12, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2026, time.October, 30, 7, 0, 0, 0, eastern),
+			wantEnd:   time.Date(2026, time.November, 2, 15, 30, 0, 0, eastern),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days := 1
+			if tt.wantEnd.Day() != tt.wantStart.Day() {
+				days = 2
+			}
+			start, end := cal.CheckoutWindow(tt.from, days)
+			if !start.Equal(tt.wantStart) {
+				t.Errorf("start = %s, want %s", start, tt.wantStart)
+			}
+			if !end.Equal(tt.wantEnd) {
+				t.Errorf("end = %s, want %s", end, tt.wantEnd)
+			}
+		})
+	}
+
+	// Times read back from the database are UTC; display is in Eastern time
+	got := cal.FormatRange(time.Date(2026, time.October, 20, 11, 0, 0, 0, time.UTC), time.Date(2026, time.October, 20, 19, 30, 0, 0, time.UTC))
+	if want := "Oct 20, 2026 (7:00 AM - 3:30 PM)"; got != want {
+		t.Errorf("FormatRange = %q, want %q", got, want)
+	}
+}
@@ -2,10 +2,14 @@ package calendar
 
 import (
     "context"
+    "errors"
+    "net/http"
     "os"
+    "time"
 
     "golang.org/x/oauth2/google"
     "google.golang.org/api/calendar/v3"
+    "google.golang.org/api/googleapi"
     "google.golang.org/api/option"
 )
 
@@ -35,4 +39,40 @@ func NewCalendarService(jsonKeyPath string) (*calendar.Service, error) {
     }
 
     return srv, nil
-}
\ No newline at end of file
+}
+
+// InsertEvent adds an event spanning start to end to a truck's calendar and
+// returns its ID.
+func InsertEvent(srv *calendar.Service, calendarID, summary string, start, end time.Time) (string, error) {
+    event := &calendar.Event{
+        Summary: summary,
+        Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+        End:     &calendar.EventDateTime{DateTime: end.Format(time.RFC3339)},
+    }
+    created, err := srv.Events.Insert(calendarID, event).Context(context.Background()).Do()
+    if err != nil {
+        return "", err
+    }
+    return created.Id, nil
+}
+
+// MoveEvent changes when an event on a truck's calendar starts and ends.
+func MoveEvent(srv *calendar.Service, calendarID, eventID string, start, end time.Time) error {
+    event := &calendar.Event{
+        Start: &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+        End:   &calendar.EventDateTime{DateTime: end.Format(time.RFC3339)},
+    }
+    _, err := srv.Events.Patch(calendarID, eventID, event).Context(context.Background()).Do()
+    return err
+}
+
+// DeleteEvent removes an event from a truck's calendar. An event that is
+// already gone counts as deleted.
+func DeleteEvent(srv *calendar.Service, calendarID, eventID string) error {
+    err := srv.Events.Delete(calendarID, eventID).Context(context.Background()).Do()
+    var apiErr *googleapi.Error
+    if errors.As(err, &apiErr) && (apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusGone) {
+        return nil
+    }
+    return err
+}
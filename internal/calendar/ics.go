@@ -0,0 +1,84 @@
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// ParseICSHolidays reads all-day events from an iCalendar (.ics) file, such
+// as a holiday calendar exported from Google Calendar. Multi-day events
+// produce one holiday per day; timed events are ignored.
+func ParseICSHolidays(r io.Reader) ([]Holiday, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var holidays []Holiday
+	var inEvent bool
+	var summary, start, end string
+
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			inEvent = true
+			summary, start, end = "", "", ""
+		case line == "END:VEVENT":
+			inEvent = false
+			holidays = append(holidays, expandAllDayEvent(summary, start, end)...)
+		case inEvent:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			// Drop parameters such as DTSTART;VALUE=DATE
+			prop, _, _ := strings.Cut(name, ";")
+			switch strings.ToUpper(prop) {
+			case "SUMMARY":
+				summary = strings.ReplaceAll(value, `\,`, ",")
+			case "DTSTART":
+				start = value
+			case "DTEND":
+				end = value
+			}
+		}
+	}
+
+	return holidays, nil
+}
+
+// unfoldICS joins continuation lines (RFC 5545 section 3.1).
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func expandAllDayEvent(summary, start, end string) []Holiday {
+	// All-day events use a bare date; anything with a time component is skipped.
+	first, err := time.Parse("20060102", start)
+	if err != nil {
+		return nil
+	}
+	// DTEND is exclusive for all-day events and optional for single days.
+	last := first
+	if e, err := time.Parse("20060102", end); err == nil && e.After(first) {
+		last = e.AddDate(0, 0, -1)
+	}
+
+	var holidays []Holiday
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		holidays = append(holidays, Holiday{Date: d.Format(DateFormat), Name: summary})
+	}
+	return holidays
+}
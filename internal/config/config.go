@@ -0,0 +1,311 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"truck-checkout/internal/calendar"
+	"truck-checkout/internal/models"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds everything the bot needs at startup. Values are layered:
+// defaults, then the optional config file named by CONFIG_FILE, then the
+// environment (including a .env file in the working directory).
+type Config struct {
+	SlackBotToken string `yaml:"slack_bot_token" toml:"slack_bot_token"`
+	SlackAppToken string `yaml:"slack_app_token" toml:"slack_app_token"`
+	DatabasePath  string `yaml:"database_path" toml:"database_path"`
+	Debug         bool   `yaml:"debug" toml:"debug"`
+
+	// AnnounceChannel is where checkouts and releases are posted.
+	AnnounceChannel string `yaml:"announce_channel" toml:"announce_channel"`
+	// MaxCheckoutDays is the longest checkout allowed, in business days.
+	MaxCheckoutDays int `yaml:"max_checkout_days" toml:"max_checkout_days"`
+
+	// OpenTime and CloseTime are the Monday to Friday working hours, unless
+	// BusinessCalendarPath points at a calendar file with its own hours.
+	OpenTime             string `yaml:"open_time" toml:"open_time"`
+	CloseTime            string `yaml:"close_time" toml:"close_time"`
+	TimeZone             string `yaml:"time_zone" toml:"time_zone"`
+	BusinessCalendarPath string `yaml:"business_calendar_path" toml:"business_calendar_path"`
+
+	// GoogleCredentialsPath is a service account key for the trucks' Google
+	// calendars. Calendar events are left alone when it is empty.
+	GoogleCredentialsPath string `yaml:"google_credentials_path" toml:"google_credentials_path"`
+
+	// FleetAdmins are Slack user IDs that are always fleet admins, whatever
+	// role is stored for them; use it to appoint the first admins.
+	FleetAdmins []string `yaml:"fleet_admins" toml:"fleet_admins"`
+
+	// Policy holds the checkout rules beyond the maximum length; see
+	// models.CheckoutPolicy for the file format.
+	Policy models.CheckoutPolicy `yaml:"policy" toml:"policy"`
+}
+
+// Default returns the configuration used when nothing is overridden. It has
+// no Slack tokens or database path, so it does not pass Validate on its own.
+func Default() *Config {
+	return &Config{
+		AnnounceChannel: "vehicleupdates",
+		MaxCheckoutDays: 6,
+		OpenTime:        "07:00",
+		CloseTime:       "15:30",
+		TimeZone:        "America/New_York",
+	}
+}
+
+// Load builds the configuration from defaults, the config file, .env and the
+// environment, and validates it.
+func Load() (*Config, error) {
+	// Variables already set in the environment win over .env.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("loading .env: %w", err)
+	}
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile overlays a YAML or TOML file, chosen by extension.
+func (c *Config) loadFile(path string) error {
+	if err := decodeFile(path, c); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// decodeFile decodes a YAML or TOML file into v, chosen by extension. A
+// .json file is read as YAML, of which JSON is a subset.
+func decodeFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return yaml.Unmarshal(data, v)
+	case ".toml":
+		return toml.Unmarshal(data, v)
+	default:
+		return errors.New("unsupported format, use .yaml or .toml")
+	}
+}
+
+func (c *Config) loadEnv() error {
+	for name, field := range map[string]*string{
+		"SLACK_BOT_TOKEN":         &c.SlackBotToken,
+		"SLACK_APP_TOKEN":         &c.SlackAppToken,
+		"DATABASE_URL":            &c.DatabasePath,
+		"ANNOUNCE_CHANNEL":        &c.AnnounceChannel,
+		"BUSINESS_OPEN_TIME":      &c.OpenTime,
+		"BUSINESS_CLOSE_TIME":     &c.CloseTime,
+		"ORG_TIMEZONE":            &c.TimeZone,
+		"BUSINESS_CALENDAR_PATH":  &c.BusinessCalendarPath,
+		"GOOGLE_CREDENTIALS_PATH": &c.GoogleCredentialsPath,
+	} {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			*field = v
+		}
+	}
+
+	if v := os.Getenv("SLACK_DEBUG"); v != "" {
+		debug, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid SLACK_DEBUG %q: expected true or false", v)
+		}
+		c.Debug = debug
+	}
+	if v := os.Getenv("FLEET_ADMINS"); v != "" {
+		c.FleetAdmins = nil
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				c.FleetAdmins = append(c.FleetAdmins, id)
+			}
+		}
+	}
+	if v := os.Getenv("MAX_CHECKOUT_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid MAX_CHECKOUT_DAYS %q: expected a whole number", v)
+		}
+		c.MaxCheckoutDays = days
+	}
+	return nil
+}
+
+// Validate reports every missing or malformed setting at once, so a bad
+// deployment fails at startup with one complete message.
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch {
+	case c.SlackBotToken == "":
+		errs = append(errs, errors.New("SLACK_BOT_TOKEN is required"))
+	case !strings.HasPrefix(c.SlackBotToken, "xoxb-"):
+		errs = append(errs, errors.New("SLACK_BOT_TOKEN must be a bot token starting with xoxb-"))
+	}
+	switch {
+	case c.SlackAppToken == "":
+		errs = append(errs, errors.New("SLACK_APP_TOKEN is required"))
+	case !strings.HasPrefix(c.SlackAppToken, "xapp-"):
+		errs = append(errs, errors.New("SLACK_APP_TOKEN must be an app-level token starting with xapp-"))
+	}
+	if c.DatabasePath == "" {
+		errs = append(errs, errors.New("DATABASE_URL is required"))
+	}
+
+	if c.AnnounceChannel == "" {
+		errs = append(errs, errors.New("announce channel must not be empty"))
+	}
+	if c.MaxCheckoutDays < 1 {
+		errs = append(errs, fmt.Errorf("max checkout days must be at least 1, got %d", c.MaxCheckoutDays))
+	}
+	if _, err := calendar.ParseHours(c.OpenTime, c.CloseTime); err != nil {
+		errs = append(errs, fmt.Errorf("business hours: %w", err))
+	}
+	if _, err := time.LoadLocation(c.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("invalid time zone %q: %w", c.TimeZone, err))
+	}
+	if err := c.Policy.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("checkout policy: %w", err))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// IsFleetAdmin reports whether the Slack user is listed in FleetAdmins.
+func (c *Config) IsFleetAdmin(slackUserID string) bool {
+	for _, id := range c.FleetAdmins {
+		if id == slackUserID {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckoutPolicy returns the checkout policy with the fleet-wide maximum
+// checkout length filled in.
+func (c *Config) CheckoutPolicy() *models.CheckoutPolicy {
+	policy := c.Policy
+	policy.MaxDays = c.MaxCheckoutDays
+	return &policy
+}
+
+// calendarFile is the business calendar file named by BusinessCalendarPath,
+// in the same formats as the config file, e.g.
+//
+//	hours:
+//	  monday: {open: "07:00", close: "15:30"}
+//	  saturday: {open: "08:00", close: "12:00"}
+//	holidays:
+//	  - {date: "2026-11-26", name: Thanksgiving}
+//	holiday_ics: holidays.ics
+type calendarFile struct {
+	Hours map[string]struct {
+		Open  string `yaml:"open" toml:"open"`
+		Close string `yaml:"close" toml:"close"`
+	} `yaml:"hours" toml:"hours"`
+	Holidays []struct {
+		Date string `yaml:"date" toml:"date"`
+		Name string `yaml:"name" toml:"name"`
+	} `yaml:"holidays" toml:"holidays"`
+	HolidayICS string `yaml:"holiday_ics" toml:"holiday_ics"`
+}
+
+// BusinessCalendar builds the organization's calendar: Monday to Friday with
+// the configured hours, or the calendar file if one is configured. A file
+// that lists no hours keeps the configured ones, and a relative holiday_ics
+// path is resolved against the file's directory.
+func (c *Config) BusinessCalendar() (*calendar.BusinessCalendar, error) {
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", c.TimeZone, err)
+	}
+
+	hours, err := calendar.ParseHours(c.OpenTime, c.CloseTime)
+	if err != nil {
+		return nil, err
+	}
+	week := make(map[time.Weekday]calendar.Hours)
+	for d := time.Monday; d <= time.Friday; d++ {
+		week[d] = hours
+	}
+	if c.BusinessCalendarPath == "" {
+		return calendar.NewBusinessCalendar(week, nil, loc), nil
+	}
+
+	path := c.BusinessCalendarPath
+	var file calendarFile
+	if err := decodeFile(path, &file); err != nil {
+		return nil, fmt.Errorf("business calendar %s: %w", path, err)
+	}
+
+	if len(file.Hours) > 0 {
+		week = make(map[time.Weekday]calendar.Hours, len(file.Hours))
+		for name, window := range file.Hours {
+			day, err := calendar.ParseWeekday(name)
+			if err != nil {
+				return nil, fmt.Errorf("business calendar %s: %w", path, err)
+			}
+			h, err := calendar.ParseHours(window.Open, window.Close)
+			if err != nil {
+				return nil, fmt.Errorf("business calendar %s: hours for %s: %w", path, name, err)
+			}
+			week[day] = h
+		}
+	}
+
+	var holidays []calendar.Holiday
+	for _, h := range file.Holidays {
+		if _, err := time.Parse(calendar.DateFormat, h.Date); err != nil {
+			return nil, fmt.Errorf("business calendar %s: invalid holiday date %q", path, h.Date)
+		}
+		holidays = append(holidays, calendar.Holiday{Date: h.Date, Name: h.Name})
+	}
+
+	if file.HolidayICS != "" {
+		icsPath := file.HolidayICS
+		if !filepath.IsAbs(icsPath) {
+			icsPath = filepath.Join(filepath.Dir(path), icsPath)
+		}
+		f, err := os.Open(icsPath)
+		if err != nil {
+			return nil, fmt.Errorf("opening holiday calendar: %w", err)
+		}
+		defer f.Close()
+
+		fromICS, err := calendar.ParseICSHolidays(f)
+		if err != nil {
+			return nil, fmt.Errorf("parsing holiday calendar %s: %w", icsPath, err)
+		}
+		holidays = append(holidays, fromICS...)
+	}
+
+	return calendar.NewBusinessCalendar(week, holidays, loc), nil
+}
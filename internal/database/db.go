@@ -2,7 +2,10 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -11,13 +14,28 @@ var DB *sql.DB
 
 func InitDB(path string) {
 	var err error
-	DB, err = sql.Open("sqlite3", path)
+	DB, err = Open(path)
 	if err != nil {
 		log.Fatalf("failed to open db: %v", err)
 	}
 	if err := CreateTables(DB); err != nil {
 		log.Fatalf("failed to create tables: %v", err)
 	}
+	if err := NormalizeTimestamps(DB); err != nil {
+		log.Fatalf("failed to normalize timestamps: %v", err)
+	}
+}
+
+// Open opens the SQLite database at path. Transactions take the write lock
+// when they begin (BEGIN IMMEDIATE), so a check-then-insert inside one
+// transaction cannot interleave with another writer; competing writers wait
+// on the busy timeout instead of failing straight away.
+func Open(path string) (*sql.DB, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return sql.Open("sqlite3", path+sep+"_txlock=immediate&_busy_timeout=5000")
 }
 
 func CreateTables(database *sql.DB) error {
@@ -27,7 +45,11 @@ func CreateTables(database *sql.DB) error {
 		slack_user_id TEXT NOT NULL UNIQUE,
 		username TEXT NOT NULL,
 		team TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		role TEXT NOT NULL DEFAULT 'member',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		display_name TEXT NOT NULL DEFAULT '',
+		phone TEXT NOT NULL DEFAULT '',
+		deactivated_at DATETIME
 	);`
 
 	truckSQL := `
@@ -36,7 +58,9 @@ func CreateTables(database *sql.DB) error {
 		name TEXT NOT NULL UNIQUE,
 		default_team TEXT,
 		google_calendar_id TEXT,
-		is_checked_out BOOLEAN DEFAULT FALSE
+		is_checked_out BOOLEAN DEFAULT FALSE,
+		features TEXT NOT NULL DEFAULT '',
+		class TEXT NOT NULL DEFAULT ''
 	);`
 
 	checkoutSQL := `
@@ -53,18 +77,283 @@ func CreateTables(database *sql.DB) error {
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		released_by TEXT,
 		released_at TIMESTAMP,
-		FOREIGN KEY(truck_id) REFERENCES trucks(id)
+		cancelled_by TEXT,
+		cancelled_at TIMESTAMP,
+		series_id TEXT,
+		announce_channel TEXT,
+		announce_ts TEXT,
+		FOREIGN KEY(truck_id) REFERENCES trucks(id),
+		FOREIGN KEY(series_id) REFERENCES checkout_series(id)
 	);	
 	`
 
-	if _, err := database.Exec(userSQL); err != nil {
-		return err
+	// Trailers and equipment booked along with a truck. features and requires
+	// are comma-separated, e.g. a chipper requires "hitch", which the truck
+	// (trucks.features) or another attached asset has to provide.
+	assetSQL := `
+	CREATE TABLE IF NOT EXISTS assets (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+		asset_type TEXT NOT NULL,
+		features TEXT NOT NULL DEFAULT '',
+		requires TEXT NOT NULL DEFAULT ''
+	);`
+
+	checkoutAssetSQL := `
+	CREATE TABLE IF NOT EXISTS checkout_assets (
+		checkout_id TEXT NOT NULL,
+		asset_id TEXT NOT NULL,
+		PRIMARY KEY(checkout_id, asset_id),
+		FOREIGN KEY(checkout_id) REFERENCES checkouts(id),
+		FOREIGN KEY(asset_id) REFERENCES assets(id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_checkout_assets_asset ON checkout_assets(asset_id);`
+
+	// A recurring reservation; each occurrence is its own row in checkouts
+	// pointing back here through series_id. weekdays is e.g. "tue,thu".
+	seriesSQL := `
+	CREATE TABLE IF NOT EXISTS checkout_series (
+		id TEXT PRIMARY KEY,
+		truck_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		user_name TEXT NOT NULL,
+		team_name TEXT NOT NULL,
+		weekdays TEXT NOT NULL,
+		first_date DATETIME NOT NULL,
+		last_date DATETIME NOT NULL,
+		business_days INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(truck_id) REFERENCES trucks(id)
+	);`
+
+	// One row per odometer reading; kind is either 'start' or 'end'.
+	odometerSQL := `
+	CREATE TABLE IF NOT EXISTS odometer_readings (
+		id TEXT PRIMARY KEY,
+		checkout_id TEXT NOT NULL,
+		truck_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		reading INTEGER NOT NULL,
+		recorded_by TEXT NOT NULL,
+		recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(checkout_id, kind),
+		FOREIGN KEY(checkout_id) REFERENCES checkouts(id),
+		FOREIGN KEY(truck_id) REFERENCES trucks(id)
+	);`
+
+	fuelSQL := `
+	CREATE TABLE IF NOT EXISTS fuel_purchases (
+		id TEXT PRIMARY KEY,
+		checkout_id TEXT NOT NULL,
+		gallons REAL NOT NULL,
+		cost_cents INTEGER NOT NULL,
+		receipt_note TEXT,
+		purchased_by TEXT NOT NULL,
+		purchased_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(checkout_id) REFERENCES checkouts(id)
+	);`
+
+	// truck_id is NULL for entries waiting on "any truck"; offered_truck_id
+	// records which truck was actually offered to them.
+	waitlistSQL := `
+	CREATE TABLE IF NOT EXISTS waitlist_entries (
+		id TEXT PRIMARY KEY,
+		truck_id TEXT,
+		slack_user_id TEXT NOT NULL,
+		user_name TEXT NOT NULL,
+		day TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'waiting',
+		offered_truck_id TEXT,
+		offer_expires_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(truck_id) REFERENCES trucks(id)
+	);`
+
+	// Who has a truck's keys or fuel card. holder_slack_id is NULL when the
+	// item went back to the lockbox; the latest row per item is where it is now.
+	custodySQL := `
+	CREATE TABLE IF NOT EXISTS custody_events (
+		id TEXT PRIMARY KEY,
+		truck_id TEXT NOT NULL,
+		item TEXT NOT NULL,
+		action TEXT NOT NULL,
+		holder_slack_id TEXT,
+		checkout_id TEXT,
+		recorded_by TEXT NOT NULL,
+		recorded_at DATETIME NOT NULL,
+		FOREIGN KEY(truck_id) REFERENCES trucks(id),
+		FOREIGN KEY(checkout_id) REFERENCES checkouts(id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_custody_events_truck ON custody_events(truck_id, item, recorded_at);`
+
+	// A driver's license (truck_class '') or certification for a class of
+	// truck, e.g. "dump". Recording one again replaces the expiry date and
+	// clears reminded_at so the next expiry gets its own reminder.
+	qualificationSQL := `
+	CREATE TABLE IF NOT EXISTS driver_qualifications (
+		id TEXT PRIMARY KEY,
+		slack_user_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		truck_class TEXT NOT NULL DEFAULT '',
+		expires_at DATETIME NOT NULL,
+		recorded_by TEXT NOT NULL,
+		recorded_at DATETIME NOT NULL,
+		reminded_at DATETIME,
+		UNIQUE(slack_user_id, kind, truck_class)
+	);`
+
+	// A period a truck is out of service, e.g. in the shop. No checkout may
+	// overlap one.
+	maintenanceSQL := `
+	CREATE TABLE IF NOT EXISTS maintenance_blocks (
+		id TEXT PRIMARY KEY,
+		truck_id TEXT NOT NULL,
+		start_date DATETIME NOT NULL,
+		end_date DATETIME NOT NULL,
+		created_by TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY(truck_id) REFERENCES trucks(id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_maintenance_blocks_truck ON maintenance_blocks(truck_id, start_date);`
+
+	// Append-only: the triggers reject any change to a recorded event.
+	auditSQL := `
+	CREATE TABLE IF NOT EXISTS audit_events (
+		id TEXT PRIMARY KEY,
+		occurred_at DATETIME NOT NULL,
+		actor_slack_id TEXT NOT NULL,
+		action TEXT NOT NULL,
+		entity_type TEXT NOT NULL,
+		entity_id TEXT NOT NULL,
+		truck_id TEXT,
+		before_json TEXT,
+		after_json TEXT,
+		source TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_audit_events_truck ON audit_events(truck_id, occurred_at);
+	CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+	BEGIN
+		SELECT RAISE(ABORT, 'audit_events is append-only');
+	END;
+	CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+	BEGIN
+		SELECT RAISE(ABORT, 'audit_events is append-only');
+	END;`
+
+	for _, stmt := range []string{userSQL, truckSQL, assetSQL, seriesSQL, checkoutSQL, checkoutAssetSQL, odometerSQL, fuelSQL, waitlistSQL, custodySQL, qualificationSQL, maintenanceSQL, auditSQL} {
+		if _, err := database.Exec(stmt); err != nil {
+			return err
+		}
 	}
-	if _, err := database.Exec(truckSQL); err != nil {
-		return err
+
+	// Columns added after the tables were first created
+	for _, col := range []struct{ table, column, definition string }{
+		{"users", "role", "TEXT NOT NULL DEFAULT 'member'"},
+		{"users", "display_name", "TEXT NOT NULL DEFAULT ''"},
+		{"users", "phone", "TEXT NOT NULL DEFAULT ''"},
+		{"users", "deactivated_at", "DATETIME"},
+		{"trucks", "features", "TEXT NOT NULL DEFAULT ''"},
+		{"trucks", "class", "TEXT NOT NULL DEFAULT ''"},
+		{"checkouts", "cancelled_by", "TEXT"},
+		{"checkouts", "cancelled_at", "TIMESTAMP"},
+		{"checkouts", "series_id", "TEXT REFERENCES checkout_series(id)"},
+		{"checkouts", "announce_channel", "TEXT"},
+		{"checkouts", "announce_ts", "TEXT"},
+	} {
+		if err := addColumn(database, col.table, col.column, col.definition); err != nil {
+			return err
+		}
 	}
-	if _, err := database.Exec(checkoutSQL); err != nil {
+	return nil
+}
+
+// addColumn adds a column to a table created before the column existed. It
+// does nothing if the column is already there.
+func addColumn(database *sql.DB, table, column, definition string) error {
+	rows, err := database.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return fmt.Errorf("reading columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return fmt.Errorf("reading columns of %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
 		return err
 	}
+	rows.Close()
+
+	if _, err := database.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition)); err != nil {
+		return fmt.Errorf("adding %s.%s: %w", table, column, err)
+	}
 	return nil
 }
+
+// timestampColumns lists every DATETIME column, keyed by table. audit_events
+// is left out: it has always been written in UTC and cannot be updated.
+var timestampColumns = map[string][]string{
+	"users":                 {"created_at", "deactivated_at"},
+	"checkouts":             {"start_date", "end_date", "created_at", "released_at", "cancelled_at"},
+	"checkout_series":       {"first_date", "last_date", "created_at"},
+	"odometer_readings":     {"recorded_at"},
+	"fuel_purchases":        {"purchased_at"},
+	"waitlist_entries":      {"offer_expires_at", "created_at"},
+	"custody_events":        {"recorded_at"},
+	"driver_qualifications": {"expires_at", "recorded_at", "reminded_at"},
+	"maintenance_blocks":    {"start_date", "end_date", "created_at"},
+}
+
+// NormalizeTimestamps rewrites timestamps stored with a non-UTC offset as
+// UTC. All timestamps are written in UTC so that SQLite's string comparisons
+// order them correctly; rows written before that rule existed may carry the
+// server's local offset instead.
+func NormalizeTimestamps(database *sql.DB) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for table, columns := range timestampColumns {
+		for _, column := range columns {
+			query := fmt.Sprintf(`SELECT id, %[1]s FROM %[2]s WHERE %[1]s IS NOT NULL AND %[1]s NOT LIKE '%%Z' AND %[1]s NOT LIKE '%%+00:00'`, column, table)
+			rows, err := tx.Query(query)
+			if err != nil {
+				return fmt.Errorf("reading %s.%s: %w", table, column, err)
+			}
+
+			fixed := make(map[string]time.Time)
+			for rows.Next() {
+				var id string
+				var ts time.Time
+				if err := rows.Scan(&id, &ts); err != nil {
+					rows.Close()
+					return fmt.Errorf("scanning %s.%s: %w", table, column, err)
+				}
+				fixed[id] = ts.UTC()
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			update := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ?`, table, column)
+			for id, ts := range fixed {
+				if _, err := tx.Exec(update, ts, id); err != nil {
+					return fmt.Errorf("updating %s.%s: %w", table, column, err)
+				}
+			}
+		}
+	}
+
+	return tx.Commit()
+}
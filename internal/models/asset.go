@@ -0,0 +1,298 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	db "truck-checkout/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// AssetType says what kind of thing is being booked. Trucks are kept in
+// their own table; trailers and equipment are assets attached to a truck's
+// checkout.
+type AssetType string
+
+const (
+	AssetTrailer   AssetType = "trailer"
+	AssetEquipment AssetType = "equipment"
+)
+
+// ParseAssetType accepts the attachable asset types.
+func ParseAssetType(s string) (AssetType, bool) {
+	switch t := AssetType(strings.ToLower(s)); t {
+	case AssetTrailer, AssetEquipment:
+		return t, true
+	}
+	return "", false
+}
+
+// Asset is a trailer or piece of equipment that is booked with a truck.
+// Requires lists features the truck, or another asset on the same checkout,
+// must provide; Features lists what this asset provides itself.
+type Asset struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Type     AssetType `json:"asset_type"`
+	Features []string  `json:"features,omitempty"`
+	Requires []string  `json:"requires,omitempty"`
+}
+
+const assetColumns = `id, name, asset_type, features, requires`
+
+func scanAsset(row rowScanner) (*Asset, error) {
+	var a Asset
+	var features, requires string
+	if err := row.Scan(&a.ID, &a.Name, &a.Type, &features, &requires); err != nil {
+		return nil, err
+	}
+	a.Features = splitList(features)
+	a.Requires = splitList(requires)
+	return &a, nil
+}
+
+// InsertAsset adds a trailer or piece of equipment to the fleet on behalf of
+// actor.
+func InsertAsset(name string, assetType AssetType, features, requires []string, actor Actor) (*Asset, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("asset name cannot be empty")
+	}
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	a := Asset{ID: uuid.New(), Name: name, Type: assetType, Features: features, Requires: requires}
+	_, err = tx.Exec(`
+		INSERT INTO assets (id, name, asset_type, features, requires) VALUES (?, ?, ?, ?, ?)
+	`, a.ID.String(), a.Name, a.Type, joinList(features), joinList(requires))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert asset %s: %w", name, err)
+	}
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionAssetCreate,
+		entityType: "asset",
+		entityID:   a.ID.String(),
+		after:      a,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetAssetByName looks an asset up by name, ignoring case.
+func GetAssetByName(name string) (*Asset, error) {
+	a, err := scanAsset(db.DB.QueryRow(`SELECT `+assetColumns+` FROM assets WHERE name = ?`, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, name)
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// GetAssets returns every asset, ordered by name.
+func GetAssets() ([]Asset, error) {
+	rows, err := db.DB.Query(`SELECT ` + assetColumns + ` FROM assets ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("querying assets: %w", err)
+	}
+	defer rows.Close()
+
+	var assets []Asset
+	for rows.Next() {
+		a, err := scanAsset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning asset: %w", err)
+		}
+		assets = append(assets, *a)
+	}
+	return assets, rows.Err()
+}
+
+// GetCheckoutAssets returns the assets booked with a checkout.
+func GetCheckoutAssets(checkoutID uuid.UUID) ([]Asset, error) {
+	rows, err := db.DB.Query(`
+		SELECT a.id, a.name, a.asset_type, a.features, a.requires
+		FROM assets a JOIN checkout_assets ca ON ca.asset_id = a.id
+		WHERE ca.checkout_id = ?
+		ORDER BY a.name
+	`, checkoutID.String())
+	if err != nil {
+		return nil, fmt.Errorf("querying checkout assets: %w", err)
+	}
+	defer rows.Close()
+
+	var assets []Asset
+	for rows.Next() {
+		a, err := scanAsset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning asset: %w", err)
+		}
+		assets = append(assets, *a)
+	}
+	return assets, rows.Err()
+}
+
+// CheckAssetCompatibility returns an *IncompatibleAssetError for the first
+// asset whose requirements neither the truck nor the other assets provide.
+func CheckAssetCompatibility(truck *Truck, assets []Asset) error {
+	provided := append([]string(nil), truck.Features...)
+	for _, a := range assets {
+		provided = append(provided, a.Features...)
+	}
+	for _, a := range assets {
+		for _, need := range a.Requires {
+			if !slices.Contains(provided, need) {
+				return &IncompatibleAssetError{Asset: a.Name, Truck: truck.Name, Requires: need}
+			}
+		}
+	}
+	return nil
+}
+
+// missingFeatures returns what the assets require that they don't provide
+// among themselves, so the truck has to.
+func missingFeatures(assets []Asset) []string {
+	var provided, missing []string
+	for _, a := range assets {
+		provided = append(provided, a.Features...)
+	}
+	for _, a := range assets {
+		for _, need := range a.Requires {
+			if !slices.Contains(provided, need) && !slices.Contains(missing, need) {
+				missing = append(missing, need)
+			}
+		}
+	}
+	return missing
+}
+
+// loadAssets reads the assets by ID inside tx, keeping the given order.
+func loadAssets(tx *sql.Tx, ids []uuid.UUID) ([]Asset, error) {
+	assets := make([]Asset, 0, len(ids))
+	for _, id := range ids {
+		a, err := scanAsset(tx.QueryRow(`SELECT `+assetColumns+` FROM assets WHERE id = ?`, id.String()))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, id)
+			}
+			return nil, fmt.Errorf("failed to load asset: %w", err)
+		}
+		assets = append(assets, *a)
+	}
+	return assets, nil
+}
+
+// attachAssets checks the checkout's assets suit the truck and are free for
+// its dates, then books them. It runs inside the checkout's transaction so
+// the truck and every asset are claimed together or not at all.
+func attachAssets(tx *sql.Tx, checkout Checkout, truck *Truck) error {
+	if len(checkout.AssetIDs) == 0 {
+		return nil
+	}
+	assets, err := loadAssets(tx, checkout.AssetIDs)
+	if err != nil {
+		return err
+	}
+	if err := CheckAssetCompatibility(truck, assets); err != nil {
+		return err
+	}
+
+	for _, a := range assets {
+		if err := checkAssetFree(tx, a, checkout.StartDate, checkout.EndDate); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO checkout_assets (checkout_id, asset_id) VALUES (?, ?)`, checkout.ID.String(), a.ID.String())
+		if err != nil {
+			return fmt.Errorf("failed to attach asset %s: %w", a.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkAssetFree returns an *AssetUnavailableError if the asset is on another
+// open booking within [start, end).
+func checkAssetFree(tx *sql.Tx, asset Asset, start, end time.Time) error {
+	var overlapping int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM checkout_assets ca
+		JOIN checkouts c ON c.id = ca.checkout_id
+		WHERE ca.asset_id = ?
+		  AND c.start_date < ?
+		  AND c.end_date > ?
+		  AND c.released_at IS NULL AND c.cancelled_at IS NULL
+	`, asset.ID.String(), end.UTC(), start.UTC()).Scan(&overlapping)
+	if err != nil {
+		return fmt.Errorf("failed to check asset availability: %w", err)
+	}
+	if overlapping > 0 {
+		return &AssetUnavailableError{Asset: asset.Name}
+	}
+	return nil
+}
+
+// checkAttachedAssetsFree checks the assets already booked with a checkout
+// are free for [start, end), e.g. before extending it.
+func checkAttachedAssetsFree(tx *sql.Tx, checkoutID uuid.UUID, start, end time.Time) error {
+	ids, err := attachedAssetIDs(tx, checkoutID)
+	if err != nil {
+		return err
+	}
+	assets, err := loadAssets(tx, ids)
+	if err != nil {
+		return err
+	}
+	for _, a := range assets {
+		if err := checkAssetFree(tx, a, start, end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachedAssetIDs returns the IDs of the assets booked with a checkout.
+func attachedAssetIDs(tx *sql.Tx, checkoutID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := tx.Query(`SELECT asset_id FROM checkout_assets WHERE checkout_id = ?`, checkoutID.String())
+	if err != nil {
+		return nil, fmt.Errorf("querying checkout assets: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning checkout asset: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// splitList and joinList convert the comma-separated lists stored in the
+// features and requires columns.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, strings.ToLower(v))
+		}
+	}
+	return list
+}
+
+func joinList(list []string) string {
+	return strings.Join(splitList(strings.Join(list, ",")), ",")
+}
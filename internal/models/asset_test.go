@@ -0,0 +1,152 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckoutWithAssets(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	for _, name := range []string{"Tulip", "Watson"} {
+		if err := InsertTruck(name, &team, uuid.NewString(), false, testActor); err != nil {
+			t.Fatalf("failed to insert truck: %v", err)
+		}
+	}
+	tulip, _ := GetTruckByName("Tulip")
+	watson, _ := GetTruckByName("Watson")
+	watson.Features = []string{"Hitch"}
+	if err := UpdateTruck(*watson, testActor); err != nil {
+		t.Fatalf("failed to update truck: %v", err)
+	}
+	if watson, _ = GetTruckByName("Watson"); len(watson.Features) != 1 || watson.Features[0] != "hitch" {
+		t.Fatalf("expected Watson to have a hitch, got %v", watson.Features)
+	}
+
+	chipper, err := InsertAsset("Chipper", AssetEquipment, nil, []string{"hitch"}, testActor)
+	if err != nil {
+		t.Fatalf("failed to insert asset: %v", err)
+	}
+	trailer, err := InsertAsset("Trailer", AssetTrailer, []string{"trailer"}, []string{"hitch"}, testActor)
+	if err != nil {
+		t.Fatalf("failed to insert asset: %v", err)
+	}
+	tank, err := InsertAsset("Watertank", AssetEquipment, nil, []string{"trailer"}, testActor)
+	if err != nil {
+		t.Fatalf("failed to insert asset: %v", err)
+	}
+	if a, err := GetAssetByName("chipper"); err != nil || a.ID != chipper.ID {
+		t.Fatalf("expected to find the chipper ignoring case, got %+v (%v)", a, err)
+	}
+	if _, err := GetAssetByName("Mower"); !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("expected ErrAssetNotFound, got %v", err)
+	}
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	newCheckout := func(truckID uuid.UUID, assets ...*Asset) Checkout {
+		c := Checkout{
+			ID:        uuid.New(),
+			TruckID:   truckID,
+			UserID:    "user123",
+			UserName:  "John Doe",
+			TeamName:  "beltline",
+			StartDate: start,
+			EndDate:   start.Add(8 * time.Hour),
+		}
+		for _, a := range assets {
+			c.AssetIDs = append(c.AssetIDs, a.ID)
+		}
+		return c
+	}
+
+	// Tulip has no hitch, so the chipper can't go with it, and the truck
+	// stays free because nothing was saved.
+	var incompatible *IncompatibleAssetError
+	if err := CreateCheckout(newCheckout(tulip.ID, chipper), nil, testActor); !errors.As(err, &incompatible) || incompatible.Requires != "hitch" {
+		t.Fatalf("expected IncompatibleAssetError for hitch, got %v", err)
+	}
+	if truck, _ := GetTruckByID(tulip.ID); truck.IsCheckedOut {
+		t.Error("expected Tulip to stay free after the failed checkout")
+	}
+
+	// The water tank's need for a trailer is met by the trailer on the same checkout.
+	if err := CheckAssetCompatibility(watson, []Asset{*tank}); !errors.Is(err, ErrAssetIncompatible) {
+		t.Errorf("expected the water tank alone to be incompatible, got %v", err)
+	}
+	first := newCheckout(watson.ID, trailer, tank)
+	if err := CreateCheckout(first, nil, testActor); err != nil {
+		t.Fatalf("failed to check out with trailer and water tank: %v", err)
+	}
+	assets, err := GetCheckoutAssets(first.ID)
+	if err != nil {
+		t.Fatalf("failed to get checkout assets: %v", err)
+	}
+	if len(assets) != 2 || assets[0].Name != "Trailer" || assets[1].Name != "Watertank" {
+		t.Errorf("expected Trailer and Watertank on the checkout, got %+v", assets)
+	}
+
+	// The trailer is booked, so another checkout for the same period fails
+	// as a whole and leaves its truck free.
+	tulip.Features = []string{"hitch"}
+	if err := UpdateTruck(*tulip, testActor); err != nil {
+		t.Fatalf("failed to update truck: %v", err)
+	}
+	var unavailable *AssetUnavailableError
+	if err := CreateCheckout(newCheckout(tulip.ID, chipper, trailer), nil, testActor); !errors.As(err, &unavailable) || unavailable.Asset != "Trailer" {
+		t.Fatalf("expected AssetUnavailableError for the trailer, got %v", err)
+	}
+	if truck, _ := GetTruckByID(tulip.ID); truck.IsCheckedOut {
+		t.Error("expected Tulip to stay free after the failed checkout")
+	}
+}
+
+func TestCreateCheckoutForAnyTruckWithAssets(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	for _, name := range []string{"Libby", "Tulip"} {
+		if err := InsertTruck(name, &team, uuid.NewString(), false, testActor); err != nil {
+			t.Fatalf("failed to insert truck: %v", err)
+		}
+	}
+	tulip, _ := GetTruckByName("Tulip")
+	tulip.Features = []string{"hitch"}
+	if err := UpdateTruck(*tulip, testActor); err != nil {
+		t.Fatalf("failed to update truck: %v", err)
+	}
+	chipper, err := InsertAsset("Chipper", AssetEquipment, nil, []string{"hitch"}, testActor)
+	if err != nil {
+		t.Fatalf("failed to insert asset: %v", err)
+	}
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	newCheckout := func() Checkout {
+		return Checkout{
+			ID:        uuid.New(),
+			UserID:    "user123",
+			UserName:  "John Doe",
+			TeamName:  "beltline",
+			StartDate: start,
+			EndDate:   start.Add(8 * time.Hour),
+			AssetIDs:  []uuid.UUID{chipper.ID},
+		}
+	}
+
+	// Libby sorts first but has no hitch, so Tulip is picked.
+	truck, _, err := CreateCheckoutForAnyTruck(newCheckout(), nil, nil, testActor)
+	if err != nil {
+		t.Fatalf("failed to check out any truck: %v", err)
+	}
+	if truck.Name != "Tulip" {
+		t.Errorf("expected Tulip, the truck with a hitch, got %s", truck.Name)
+	}
+
+	// The only hitched truck is taken, so nothing suits the chipper.
+	if _, _, err := CreateCheckoutForAnyTruck(newCheckout(), nil, nil, testActor); !errors.Is(err, ErrNoTrucksAvailable) {
+		t.Errorf("expected ErrNoTrucksAvailable, got %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	db "truck-checkout/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// AuditSource records which front end a change came through.
+type AuditSource string
+
+const (
+	SourceSlashCommand AuditSource = "slash_command"
+	SourceModal        AuditSource = "modal"
+	SourceButton       AuditSource = "button"
+	SourceAPI          AuditSource = "api"
+	SourceScheduler    AuditSource = "scheduler"
+)
+
+// Actor is who made a change and how. Every mutating function takes one so
+// the change lands in the audit log.
+type Actor struct {
+	SlackUserID string
+	Source      AuditSource
+}
+
+// Audit actions.
+const (
+	ActionCheckoutCreate  = "checkout.create"
+	ActionCheckoutCancel  = "checkout.cancel"
+	ActionCheckoutExtend  = "checkout.extend"
+	ActionCheckoutRelease = "checkout.release"
+	ActionTruckCreate     = "truck.create"
+	ActionTruckUpdate     = "truck.update"
+	ActionMaintenance     = "truck.maintenance"
+	ActionAssetCreate     = "asset.create"
+	ActionUserCreate      = "user.create"
+	ActionUserUpdate      = "user.update"
+	ActionUserRole        = "user.role"
+	ActionQualification   = "qualification.record"
+	ActionOverride        = "override"
+)
+
+// AuditEvent is one row of the append-only audit log. Before and After hold
+// JSON snapshots of the entity and are empty when there is nothing to show.
+type AuditEvent struct {
+	ID         uuid.UUID   `json:"id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	ActorID    string      `json:"actor_slack_id"`
+	Action     string      `json:"action"`
+	EntityType string      `json:"entity_type"`
+	EntityID   string      `json:"entity_id"`
+	TruckID    *uuid.UUID  `json:"truck_id,omitempty"`
+	Before     string      `json:"before,omitempty"`
+	After      string      `json:"after,omitempty"`
+	Source     AuditSource `json:"source"`
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx, so audit events can be
+// written inside the transaction that makes the change.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// auditEntry describes a change for writeAuditEvent; before and after are
+// marshalled to JSON, and nil means "nothing".
+type auditEntry struct {
+	action     string
+	entityType string
+	entityID   string
+	truckID    *uuid.UUID
+	before     any
+	after      any
+}
+
+func writeAuditEvent(ex execer, actor Actor, entry auditEntry) error {
+	before, err := auditJSON(entry.before)
+	if err != nil {
+		return err
+	}
+	after, err := auditJSON(entry.after)
+	if err != nil {
+		return err
+	}
+
+	var truckID *string
+	if entry.truckID != nil {
+		s := entry.truckID.String()
+		truckID = &s
+	}
+
+	_, err = ex.Exec(`
+		INSERT INTO audit_events (id, occurred_at, actor_slack_id, action, entity_type, entity_id, truck_id, before_json, after_json, source)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, uuid.NewString(), time.Now().UTC(), actor.SlackUserID, entry.action, entry.entityType, entry.entityID,
+		truckID, before, after, actor.Source)
+	if err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	return nil
+}
+
+func auditJSON(v any) (sql.NullString, error) {
+	if v == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// RecordOverride logs that actor stepped past a rule or permission, such as a
+// team lead releasing someone else's truck. details is stored as the after
+// snapshot.
+func RecordOverride(actor Actor, entityType, entityID string, truckID *uuid.UUID, details any) error {
+	return writeAuditEvent(db.DB, actor, auditEntry{
+		action:     ActionOverride,
+		entityType: entityType,
+		entityID:   entityID,
+		truckID:    truckID,
+		after:      details,
+	})
+}
+
+const auditEventColumns = `id, occurred_at, actor_slack_id, action, entity_type, entity_id, truck_id, before_json, after_json, source`
+
+func scanAuditEvent(row rowScanner) (*AuditEvent, error) {
+	var e AuditEvent
+	var id string
+	var truckID, before, after sql.NullString
+	if err := row.Scan(&id, &e.OccurredAt, &e.ActorID, &e.Action, &e.EntityType, &e.EntityID,
+		&truckID, &before, &after, &e.Source); err != nil {
+		return nil, err
+	}
+
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit event ID %q: %w", id, err)
+	}
+	e.ID = parsed
+	if truckID.Valid {
+		tid, err := uuid.Parse(truckID.String)
+		if err != nil {
+			return nil, fmt.Errorf("invalid truck ID %q: %w", truckID.String, err)
+		}
+		e.TruckID = &tid
+	}
+	e.Before = before.String
+	e.After = after.String
+	return &e, nil
+}
+
+func queryAuditEvents(query string, args ...any) ([]AuditEvent, error) {
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []AuditEvent
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+// GetAuditEventsByTruckID returns the truck's most recent events, newest first.
+func GetAuditEventsByTruckID(truckID uuid.UUID, limit int) ([]AuditEvent, error) {
+	return queryAuditEvents(`
+		SELECT `+auditEventColumns+` FROM audit_events
+		WHERE truck_id = ?
+		ORDER BY occurred_at DESC, rowid DESC
+		LIMIT ?
+	`, truckID.String(), limit)
+}
+
+// GetAuditEventsBetween returns every event in [from, to), oldest first.
+func GetAuditEventsBetween(from, to time.Time) ([]AuditEvent, error) {
+	return queryAuditEvents(`
+		SELECT `+auditEventColumns+` FROM audit_events
+		WHERE occurred_at >= ? AND occurred_at < ?
+		ORDER BY occurred_at, rowid
+	`, from.UTC(), to.UTC())
+}
+
+// WriteAuditCSV writes events as CSV with a header row.
+func WriteAuditCSV(w io.Writer, events []AuditEvent) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"occurred_at", "actor_slack_id", "action", "entity_type", "entity_id", "truck_id", "source", "before", "after"}); err != nil {
+		return err
+	}
+	for _, e := range events {
+		truckID := ""
+		if e.TruckID != nil {
+			truckID = e.TruckID.String()
+		}
+		record := []string{e.OccurredAt.UTC().Format(time.RFC3339), e.ActorID, e.Action, e.EntityType, e.EntityID,
+			truckID, string(e.Source), e.Before, e.After}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	db "truck-checkout/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// testActor is the actor tests pass to mutating functions.
+var testActor = Actor{SlackUserID: "U_TEST", Source: SourceAPI}
+
+func TestAuditEvents(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	now := time.Now()
+	checkout := Checkout{
+		ID:        uuid.New(),
+		TruckID:   truck.ID,
+		UserID:    "user123",
+		UserName:  "John Doe",
+		TeamName:  "beltline",
+		StartDate: now.Add(-time.Hour),
+		EndDate:   now.Add(8 * time.Hour),
+	}
+	if err := CreateCheckout(checkout, nil, Actor{SlackUserID: "user123", Source: SourceSlashCommand}); err != nil {
+		t.Fatalf("failed to create checkout: %v", err)
+	}
+	lead := Actor{SlackUserID: "U_LEAD", Source: SourceButton}
+	if err := ReleaseTruckFromCheckout(truck.ID, lead); err != nil {
+		t.Fatalf("failed to release truck: %v", err)
+	}
+	if err := RecordOverride(lead, "checkout", checkout.ID.String(), &truck.ID, map[string]string{"reason": "released another user's checkout"}); err != nil {
+		t.Fatalf("failed to record override: %v", err)
+	}
+
+	events, err := GetAuditEventsByTruckID(truck.ID, 10)
+	if err != nil {
+		t.Fatalf("failed to get audit events: %v", err)
+	}
+	wantActions := []string{ActionOverride, ActionCheckoutRelease, ActionCheckoutCreate, ActionTruckCreate}
+	if len(events) != len(wantActions) {
+		t.Fatalf("expected %d events, got %+v", len(wantActions), events)
+	}
+	for i, action := range wantActions {
+		if events[i].Action != action {
+			t.Errorf("event %d action = %s, want %s", i, events[i].Action, action)
+		}
+	}
+
+	release := events[1]
+	if release.ActorID != "U_LEAD" || release.Source != SourceButton {
+		t.Errorf("expected release by U_LEAD via button, got %s via %s", release.ActorID, release.Source)
+	}
+	if strings.Contains(release.Before, "released_at") || !strings.Contains(release.After, `"released_by":"U_LEAD"`) {
+		t.Errorf("expected before/after snapshots around the release, got before=%s after=%s", release.Before, release.After)
+	}
+	if events[2].Before != "" {
+		t.Errorf("expected no before snapshot for a new checkout, got %s", events[2].Before)
+	}
+	if events[3].ActorID != testActor.SlackUserID || !strings.Contains(events[3].After, `"name":"Tulip"`) {
+		t.Errorf("expected the new truck audited for %s, got %+v", testActor.SlackUserID, events[3])
+	}
+
+	// The log is append-only
+	if _, err := db.DB.Exec(`UPDATE audit_events SET actor_slack_id = 'nobody'`); err == nil {
+		t.Error("expected audit events to reject updates")
+	}
+	if _, err := db.DB.Exec(`DELETE FROM audit_events`); err == nil {
+		t.Error("expected audit events to reject deletes")
+	}
+
+	all, err := GetAuditEventsBetween(now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to get audit events: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteAuditCSV(&buf, all); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV back: %v", err)
+	}
+	if len(records) != len(all)+1 || records[1][2] != ActionTruckCreate {
+		t.Errorf("expected header plus %d events oldest first, got %v", len(all), records)
+	}
+}
+
+func TestUpdateTruck_Audited(t *testing.T) {
+	ResetTestDB(t)
+
+	if err := InsertTruck("Tulip", nil, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	team := "education"
+	truck.DefaultTeam = &team
+	if err := UpdateTruck(*truck, Actor{SlackUserID: "U_ADMIN", Source: SourceSlashCommand}); err != nil {
+		t.Fatalf("failed to update truck: %v", err)
+	}
+
+	events, err := GetAuditEventsByTruckID(truck.ID, 10)
+	if err != nil {
+		t.Fatalf("failed to get audit events: %v", err)
+	}
+	if len(events) != 2 || events[0].Action != ActionTruckUpdate || events[1].Action != ActionTruckCreate {
+		t.Fatalf("expected truck.update after truck.create, got %+v", events)
+	}
+	if strings.Contains(events[0].Before, "default_team") || !strings.Contains(events[0].After, `"default_team":"education"`) {
+		t.Errorf("expected team change in snapshots, got before=%s after=%s", events[0].Before, events[0].After)
+	}
+}
+
+func TestCreateUserAndAsset_Audited(t *testing.T) {
+	ResetTestDB(t)
+
+	now := time.Now()
+	if _, err := CreateUser("U123", "jo", "beltline", Actor{SlackUserID: "U123", Source: SourceModal}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	chipper, err := InsertAsset("Chipper", AssetEquipment, nil, []string{"hitch"}, testActor)
+	if err != nil {
+		t.Fatalf("failed to insert asset: %v", err)
+	}
+
+	events, err := GetAuditEventsBetween(now.Add(-time.Minute), now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to get audit events: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected two audit events, got %+v", events)
+	}
+	if e := events[0]; e.Action != ActionUserCreate || e.EntityID != "U123" || e.ActorID != "U123" || e.Source != SourceModal {
+		t.Errorf("expected the user's own sign-up audited, got %+v", e)
+	}
+	if e := events[1]; e.Action != ActionAssetCreate || e.EntityID != chipper.ID.String() || !strings.Contains(e.After, `"requires":["hitch"]`) {
+		t.Errorf("expected the new asset audited, got %+v", e)
+	}
+}
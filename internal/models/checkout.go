@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 	db "truck-checkout/internal/database"
 
@@ -22,21 +23,46 @@ type Checkout struct {
 	CreatedAt       time.Time  `json:"created_at"`
 	ReleasedBy      *string    `json:"released_by,omitempty"`
 	ReleasedAt      *time.Time `json:"released_at,omitempty"`
+	CancelledBy     *string    `json:"cancelled_by,omitempty"`
+	CancelledAt     *time.Time `json:"cancelled_at,omitempty"`
+	// SeriesID links an occurrence of a recurring reservation to its series.
+	SeriesID *uuid.UUID `json:"series_id,omitempty"`
+	// AnnounceChannel and AnnounceTS identify the announce channel message
+	// for the checkout, so it can be updated in place.
+	AnnounceChannel string `json:"announce_channel,omitempty"`
+	AnnounceTS      string `json:"announce_ts,omitempty"`
+	// AssetIDs are the trailers and equipment booked with the truck. They
+	// are only read when creating a checkout; see GetCheckoutAssets.
+	AssetIDs []uuid.UUID `json:"asset_ids,omitempty"`
+}
+
+// HeldUntil is when the checkout stopped holding the truck: its end, or its
+// release if that came first.
+func (c *Checkout) HeldUntil() time.Time {
+	if c.ReleasedAt != nil && c.ReleasedAt.Before(c.EndDate) {
+		return *c.ReleasedAt
+	}
+	return c.EndDate
 }
 
 func InsertCheckout(checkout Checkout) error {
 	if !IsValidTeam(checkout.TeamName) {
-		return fmt.Errorf("invalid team name: %s", checkout.TeamName)
+		return fmt.Errorf("%w: %s", ErrInvalidTeam, checkout.TeamName)
 	}
 	_, err := db.DB.Exec(`
 		INSERT INTO checkouts (id, truck_id, user_id, user_name, team_name, start_date, end_date, purpose)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`, checkout.ID.String(), checkout.TruckID.String(), checkout.UserID,
-		checkout.UserName, checkout.TeamName, checkout.StartDate, checkout.EndDate, checkout.Purpose)
+		checkout.UserName, checkout.TeamName, checkout.StartDate.UTC(), checkout.EndDate.UTC(), checkout.Purpose)
 	return err
 }
 
-func CreateCheckout(checkout Checkout) error {
+// CreateCheckout checks the truck is free for the checkout's dates and
+// inserts the checkout in a single transaction. It returns
+// ErrTruckUnavailable if the truck is already out when the checkout would
+// start, or ErrCheckoutOverlap if a later booking collides with it. If check
+// is not nil the checkout must also pass it, in the same transaction.
+func CreateCheckout(checkout Checkout, check *PolicyCheck, actor Actor) error {
 	tx, err := db.DB.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -44,71 +70,147 @@ func CreateCheckout(checkout Checkout) error {
 	// Defer a rollback in case of an error
 	defer tx.Rollback()
 
-	// Step 1: Insert the checkout record
-	_, err = tx.Exec(`
-		INSERT INTO checkouts (id, truck_id, user_id, user_name, team_name, start_date, end_date, purpose)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`, checkout.ID.String(), checkout.TruckID.String(), checkout.UserID,
-		checkout.UserName, checkout.TeamName, checkout.StartDate, checkout.EndDate, checkout.Purpose)
-	if err != nil {
-		return fmt.Errorf("failed to insert checkout: %w", err)
+	// Step 1: Make sure nobody else has the truck for these dates
+	if err := checkTruckFree(tx, checkout.TruckID, checkout.StartDate, checkout.EndDate); err != nil {
+		return err
+	}
+	if err := check.enforce(tx, checkout, actor); err != nil {
+		return err
 	}
 
-	// Step 2: Update the truck's status to checked out
-	_, err = tx.Exec(`UPDATE trucks SET is_checked_out = true WHERE id = ?`, checkout.TruckID.String())
-	if err != nil {
-		return fmt.Errorf("failed to update truck status: %w", err)
+	// Step 2: Insert the checkout record
+	if err := insertCheckout(tx, checkout); err != nil {
+		return err
+	}
+
+	// Step 3: Book any trailers and equipment along with the truck
+	if len(checkout.AssetIDs) > 0 {
+		truck, err := scanTruck(tx.QueryRow(`SELECT `+truckColumns+` FROM trucks WHERE id = ?`, checkout.TruckID.String()))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: %s", ErrTruckNotFound, checkout.TruckID)
+			}
+			return fmt.Errorf("failed to load truck: %w", err)
+		}
+		if err := attachAssets(tx, checkout, truck); err != nil {
+			return err
+		}
+	}
+
+	// Step 4: Mark the truck checked out if the checkout has already begun
+	if err := refreshTruckStatus(tx, checkout.TruckID, time.Now()); err != nil {
+		return err
+	}
+
+	if err := auditCheckoutCreate(tx, actor, checkout); err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
 
-func GetCheckoutByID(id uuid.UUID) (*Checkout, error) {
-	var checkout Checkout
-	var purpose sql.NullString
-
-	row := db.DB.QueryRow(`
-		SELECT id, truck_id, user_id, user_name, team_name, start_date, end_date, purpose
-		FROM checkouts WHERE id = ?
-	`, id.String())
+// checkTruckFree returns ErrInMaintenance if the truck is out of service for
+// any of [start, end), ErrTruckUnavailable if it is already out at start, or
+// ErrCheckoutOverlap if another open booking falls within [start, end).
+func checkTruckFree(tx *sql.Tx, truckID uuid.UUID, start, end time.Time) error {
+	var maintenance int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM maintenance_blocks
+		WHERE truck_id = ? AND start_date < ? AND end_date > ?
+	`, truckID.String(), end.UTC(), start.UTC()).Scan(&maintenance)
+	if err != nil {
+		return fmt.Errorf("failed to check truck maintenance: %w", err)
+	}
+	if maintenance > 0 {
+		return ErrInMaintenance
+	}
 
-	err := row.Scan(&checkout.ID, &checkout.TruckID, &checkout.UserID,
-		&checkout.UserName, &checkout.TeamName, &checkout.StartDate, &checkout.EndDate, &purpose)
+	var overlapping, inUse int
+	err = tx.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(start_date <= ?), 0) FROM checkouts
+		WHERE truck_id = ?
+		  AND start_date < ?
+		  AND end_date > ?
+		  AND released_at IS NULL AND cancelled_at IS NULL
+	`, start.UTC(), truckID.String(), end.UTC(), start.UTC()).Scan(&overlapping, &inUse)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to check truck availability: %w", err)
 	}
+	if inUse > 0 {
+		return ErrTruckUnavailable
+	}
+	if overlapping > 0 {
+		return ErrCheckoutOverlap
+	}
+	return nil
+}
 
-	if purpose.Valid {
-		checkout.Purpose = purpose.String
+func insertCheckout(ex execer, checkout Checkout) error {
+	var seriesID any
+	if checkout.SeriesID != nil {
+		seriesID = checkout.SeriesID.String()
+	}
+	_, err := ex.Exec(`
+		INSERT INTO checkouts (id, truck_id, user_id, user_name, team_name, start_date, end_date, purpose, series_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, checkout.ID.String(), checkout.TruckID.String(), checkout.UserID,
+		checkout.UserName, checkout.TeamName, checkout.StartDate.UTC(), checkout.EndDate.UTC(), checkout.Purpose, seriesID)
+	if err != nil {
+		return fmt.Errorf("failed to insert checkout: %w", err)
 	}
+	return nil
+}
 
-	return &checkout, nil
+func GetCheckoutByID(id uuid.UUID) (*Checkout, error) {
+	return scanCheckout(db.DB.QueryRow(`SELECT `+checkoutColumns+` FROM checkouts WHERE id = ?`, id.String()))
+}
+
+func auditCheckoutCreate(ex execer, actor Actor, checkout Checkout) error {
+	return writeAuditEvent(ex, actor, auditEntry{
+		action:     ActionCheckoutCreate,
+		entityType: "checkout",
+		entityID:   checkout.ID.String(),
+		truckID:    &checkout.TruckID,
+		after:      checkout,
+	})
+}
+
+// releasableCheckout selects the checkout ReleaseTruckFromCheckout ends,
+// taking the truck ID and the current time as arguments.
+const releasableCheckout = `SELECT ` + checkoutColumns + ` FROM checkouts
+	WHERE truck_id = ? AND start_date <= ? AND released_at IS NULL AND cancelled_at IS NULL
+	ORDER BY start_date DESC
+	LIMIT 1`
+
+// GetReleasableCheckoutByTruckID returns the checkout ReleaseTruckFromCheckout
+// would end: the latest one of the truck that has started and has been
+// neither released nor cancelled, even if it has run past its end. It
+// returns ErrNoActiveCheckout if there is none.
+func GetReleasableCheckoutByTruckID(truckID uuid.UUID) (*Checkout, error) {
+	checkout, err := scanCheckout(db.DB.QueryRow(releasableCheckout, truckID.String(), time.Now().UTC()))
+	if err == sql.ErrNoRows {
+		return nil, ErrNoActiveCheckout
+	}
+	return checkout, err
 }
 
-func ReleaseTruckFromCheckout(truckID uuid.UUID, releasedBy string) error {
+// ReleaseTruckFromCheckout ends the truck's active checkout on behalf of
+// actor. Reservations that have not started yet are left alone; use
+// CancelCheckout for those.
+func ReleaseTruckFromCheckout(truckID uuid.UUID, actor Actor) error {
 	tx, err := db.DB.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	now := time.Now()
-
-	var currentCheckoutID string
-	err = tx.QueryRow(`
-        SELECT id FROM checkouts 
-        WHERE truck_id = ? AND released_at IS NULL
-        ORDER BY start_date DESC 
-        LIMIT 1
-    `, truckID.String()).Scan(&currentCheckoutID)
+	now := time.Now().UTC()
 
+	before, err := scanCheckout(tx.QueryRow(releasableCheckout, truckID.String(), now))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			// This is not an error, it just means the truck is already available.
-			// We can double-check the truck's status and fix it if it's inconsistent.
-			if err == sql.ErrNoRows {
-				return fmt.Errorf("no active checkout found for this truck")
-			}
+			// This just means the truck is already available.
+			return ErrNoActiveCheckout
 		}
 		return fmt.Errorf("failed to find current checkout: %w", err)
 	}
@@ -118,49 +220,514 @@ func ReleaseTruckFromCheckout(truckID uuid.UUID, releasedBy string) error {
         UPDATE checkouts 
         SET released_at = ?, released_by = ?
         WHERE id = ?
-    `, now, releasedBy, currentCheckoutID)
+    `, now, actor.SlackUserID, before.ID.String())
 	if err != nil {
 		return fmt.Errorf("failed to update current checkout: %w", err)
 	}
 
-	_, err = tx.Exec(`
-        UPDATE trucks SET is_checked_out = false WHERE id = ?
-    `, truckID.String())
+	if err := refreshTruckStatus(tx, truckID, now); err != nil {
+		return err
+	}
+
+	after := *before
+	after.ReleasedBy = &actor.SlackUserID
+	after.ReleasedAt = &now
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionCheckoutRelease,
+		entityType: "checkout",
+		entityID:   before.ID.String(),
+		truckID:    &truckID,
+		before:     before,
+		after:      after,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update truck status: %w", err)
+		return err
 	}
 
 	return tx.Commit()
 }
 
 func GetActiveCheckoutByTruckID(truckID uuid.UUID) (*Checkout, error) {
-	var checkout Checkout
-	now := time.Now()
+	now := time.Now().UTC()
 
 	query := `
-        SELECT id, truck_id, user_id, user_name, team_name, start_date, end_date, purpose
+        SELECT ` + checkoutColumns + `
         FROM checkouts
         WHERE truck_id = ?
           AND start_date <= ?
           AND end_date > ?
-          AND released_at IS NULL
+          AND released_at IS NULL AND cancelled_at IS NULL
         ORDER BY start_date DESC
         LIMIT 1
     `
-	err := db.DB.QueryRow(query, truckID.String(), now, now).Scan(
-		&checkout.ID,
-		&checkout.TruckID,
-		&checkout.UserID,
-		&checkout.UserName,
-		&checkout.TeamName,
-		&checkout.StartDate,
-		&checkout.EndDate,
-		&checkout.Purpose,
-	)
+	return scanCheckout(db.DB.QueryRow(query, truckID.String(), now, now))
+}
 
+// GetLatestCheckoutByTruckID returns the most recent checkout of a truck by
+// userID that has already started, whether or not it has been released. A
+// truck's checkouts never overlap, so this is the one in progress if there is
+// one. An empty userID matches any holder. ErrNoActiveCheckout is returned
+// when there is no such checkout.
+func GetLatestCheckoutByTruckID(truckID uuid.UUID, userID string) (*Checkout, error) {
+	checkout, err := scanCheckout(db.DB.QueryRow(`
+        SELECT `+checkoutColumns+`
+        FROM checkouts
+        WHERE truck_id = ?
+          AND (? = '' OR user_id = ?)
+          AND start_date <= ?
+          AND cancelled_at IS NULL
+        ORDER BY start_date DESC
+        LIMIT 1
+    `, truckID.String(), userID, userID, time.Now().UTC()))
+	if err == sql.ErrNoRows {
+		return nil, ErrNoActiveCheckout
+	}
+	return checkout, err
+}
+
+// TruckMatch explains why a truck was picked for an "any truck" checkout.
+type TruckMatch int
+
+const (
+	MatchTeamTruck TruckMatch = iota
+	MatchUnassignedTruck
+)
+
+// CreateCheckoutForAnyTruck picks a free truck for the checkout's dates and
+// inserts the checkout in the same transaction, so two people cannot be
+// handed the same truck. Trucks whose default team matches the checkout's
+// team are preferred, then unassigned trucks; trucks assigned to another
+// team are never picked, as CheckTeamAccess would refuse them. Trucks
+// named in exclude, such as those the checkout policy rules out, are skipped,
+// as are trucks lacking a feature the checkout's assets need or out of
+// service for maintenance. If check is not
+// nil the checkout must also pass it, in the same transaction.
+func CreateCheckoutForAnyTruck(checkout Checkout, exclude []string, check *PolicyCheck, actor Actor) (*Truck, TruckMatch, error) {
+	if !IsValidTeam(checkout.TeamName) {
+		return nil, 0, fmt.Errorf("%w: %s", ErrInvalidTeam, checkout.TeamName)
+	}
+
+	tx, err := db.DB.Begin()
 	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	args := []any{checkout.TeamName, checkout.TeamName, checkout.EndDate.UTC(), checkout.StartDate.UTC(),
+		checkout.EndDate.UTC(), checkout.StartDate.UTC()}
+	excludeClause := ""
+	if len(exclude) > 0 {
+		excludeClause = "AND t.name NOT IN (?" + strings.Repeat(", ?", len(exclude)-1) + ")"
+		for _, name := range exclude {
+			args = append(args, name)
+		}
+	}
+	if len(checkout.AssetIDs) > 0 {
+		assets, err := loadAssets(tx, checkout.AssetIDs)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, feature := range missingFeatures(assets) {
+			excludeClause += " AND (',' || t.features || ',') LIKE ?"
+			args = append(args, "%,"+feature+",%")
+		}
+	}
+
+	var truck Truck
+	var defaultTeam sql.NullString
+	var features string
+	var match TruckMatch
+	err = tx.QueryRow(`
+		SELECT t.id, t.name, t.default_team, t.google_calendar_id, t.is_checked_out, t.features, t.class,
+		       CASE WHEN t.default_team = ? THEN 0 ELSE 1 END AS preference
+		FROM trucks t
+		WHERE (t.default_team = ? OR t.default_team IS NULL OR t.default_team = '')
+		  AND NOT EXISTS (
+		      SELECT 1 FROM checkouts c
+		      WHERE c.truck_id = t.id
+		        AND c.start_date < ?
+		        AND c.end_date > ?
+		        AND c.released_at IS NULL AND c.cancelled_at IS NULL
+		  )
+		  AND NOT EXISTS (
+		      SELECT 1 FROM maintenance_blocks m
+		      WHERE m.truck_id = t.id AND m.start_date < ? AND m.end_date > ?
+		  )
+		  `+excludeClause+`
+		ORDER BY preference, t.name
+		LIMIT 1
+	`, args...).Scan(
+		&truck.ID, &truck.Name, &defaultTeam, &truck.GoogleCalendarID, &truck.IsCheckedOut, &features, &truck.Class, &match)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, 0, ErrNoTrucksAvailable
+		}
+		return nil, 0, fmt.Errorf("failed to find an available truck: %w", err)
+	}
+	if defaultTeam.Valid {
+		truck.DefaultTeam = &defaultTeam.String
+	}
+	truck.Features = splitList(features)
+
+	checkout.TruckID = truck.ID
+	if err := check.enforce(tx, checkout, actor); err != nil {
+		return nil, 0, err
+	}
+	if err := insertCheckout(tx, checkout); err != nil {
+		return nil, 0, err
+	}
+	if err := attachAssets(tx, checkout, &truck); err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	if err := refreshTruckStatus(tx, truck.ID, now); err != nil {
+		return nil, 0, err
+	}
+
+	if err := auditCheckoutCreate(tx, actor, checkout); err != nil {
+		return nil, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, err
+	}
+
+	truck.IsCheckedOut = !checkout.StartDate.After(now)
+	return &truck, match, nil
+}
+
+// CancelCheckout cancels a reservation that has not started yet on behalf
+// of actor. Unlike a release, a cancelled checkout never happened: it drops
+// out of availability, quotas and usage reports. It returns
+// ErrCheckoutNotFound if the checkout doesn't exist or is already released
+// or cancelled, and ErrCheckoutStarted once it is under way.
+func CancelCheckout(id uuid.UUID, actor Actor) (*Checkout, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	cancelled, err := cancelCheckout(tx, id, actor, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
+	return cancelled, nil
+}
 
-	return &checkout, nil
+func cancelCheckout(tx *sql.Tx, id uuid.UUID, actor Actor, now time.Time) (*Checkout, error) {
+	before, err := scanCheckout(tx.QueryRow(`SELECT `+checkoutColumns+` FROM checkouts WHERE id = ?`, id.String()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCheckoutNotFound
+		}
+		return nil, fmt.Errorf("failed to find checkout: %w", err)
+	}
+	if before.ReleasedAt != nil || before.CancelledAt != nil {
+		return nil, ErrCheckoutNotFound
+	}
+	if !before.StartDate.After(now) {
+		return nil, ErrCheckoutStarted
+	}
+
+	_, err = tx.Exec(`
+		UPDATE checkouts SET cancelled_at = ?, cancelled_by = ? WHERE id = ?
+	`, now, actor.SlackUserID, id.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel checkout: %w", err)
+	}
+
+	if err := refreshTruckStatus(tx, before.TruckID, now); err != nil {
+		return nil, err
+	}
+
+	after := *before
+	after.CancelledBy = &actor.SlackUserID
+	after.CancelledAt = &now
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionCheckoutCancel,
+		entityType: "checkout",
+		entityID:   before.ID.String(),
+		truckID:    &before.TruckID,
+		before:     before,
+		after:      after,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
+
+// checkoutColumns is the full checkouts row in the order scanCheckout reads it.
+const checkoutColumns = `id, truck_id, user_id, user_name, team_name, start_date, end_date, purpose, calendar_event_id,
+	created_at, released_by, released_at, cancelled_by, cancelled_at, series_id, announce_channel, announce_ts`
+
+func scanCheckout(row rowScanner) (*Checkout, error) {
+	var c Checkout
+	var purpose, calendarEventID, releasedBy, cancelledBy, seriesID, announceChannel, announceTS sql.NullString
+	var releasedAt, cancelledAt sql.NullTime
+	err := row.Scan(&c.ID, &c.TruckID, &c.UserID, &c.UserName, &c.TeamName, &c.StartDate, &c.EndDate,
+		&purpose, &calendarEventID, &c.CreatedAt, &releasedBy, &releasedAt, &cancelledBy, &cancelledAt, &seriesID,
+		&announceChannel, &announceTS)
+	if err != nil {
+		return nil, err
+	}
+	c.Purpose = purpose.String
+	c.CalendarEventID = calendarEventID.String
+	c.AnnounceChannel = announceChannel.String
+	c.AnnounceTS = announceTS.String
+	if releasedBy.Valid {
+		c.ReleasedBy = &releasedBy.String
+	}
+	if releasedAt.Valid {
+		c.ReleasedAt = &releasedAt.Time
+	}
+	if cancelledBy.Valid {
+		c.CancelledBy = &cancelledBy.String
+	}
+	if cancelledAt.Valid {
+		c.CancelledAt = &cancelledAt.Time
+	}
+	if seriesID.Valid {
+		id, err := uuid.Parse(seriesID.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing series id: %w", err)
+		}
+		c.SeriesID = &id
+	}
+	return &c, nil
+}
+
+// refreshTruckStatus marks the truck checked out exactly when a checkout of
+// it is in progress at now. Every write of is_checked_out goes through here
+// or ReconcileTruckStatus, so the flag means the same thing everywhere.
+func refreshTruckStatus(ex execer, truckID uuid.UUID, now time.Time) error {
+	_, err := ex.Exec(`
+		UPDATE trucks SET is_checked_out = EXISTS (
+			SELECT 1 FROM checkouts c
+			WHERE c.truck_id = ? AND `+inProgressAt+`
+		)
+		WHERE id = ?
+	`, truckID.String(), now.UTC(), now.UTC(), truckID.String())
+	if err != nil {
+		return fmt.Errorf("failed to update truck status: %w", err)
+	}
+	return nil
+}
+
+// GetUpcomingCheckoutsByUserID returns the user's reservations that start
+// after now and are still open, soonest first.
+func GetUpcomingCheckoutsByUserID(userID string, now time.Time) ([]Checkout, error) {
+	return queryCheckouts(`
+		SELECT `+checkoutColumns+`
+		FROM checkouts
+		WHERE user_id = ?
+		  AND start_date > ?
+		  AND released_at IS NULL AND cancelled_at IS NULL
+		ORDER BY start_date
+	`, userID, now.UTC())
+}
+
+// GetOpenCheckoutsByUserID returns the user's checkouts that are in progress
+// or still to come, soonest first.
+func GetOpenCheckoutsByUserID(userID string, now time.Time) ([]Checkout, error) {
+	return queryCheckouts(`
+		SELECT `+checkoutColumns+`
+		FROM checkouts
+		WHERE user_id = ?
+		  AND end_date > ?
+		  AND released_at IS NULL AND cancelled_at IS NULL
+		ORDER BY start_date
+	`, userID, now.UTC())
+}
+
+// GetCheckoutsBetween returns the checkouts of every truck that held it for
+// part of [from, to), in start order. Released checkouts are included if
+// they were released after from; see HeldUntil.
+func GetCheckoutsBetween(from, to time.Time) ([]Checkout, error) {
+	return queryCheckouts(`
+		SELECT `+checkoutColumns+`
+		FROM checkouts c
+		WHERE `+occupiesBetween+`
+		ORDER BY c.start_date
+	`, to.UTC(), from.UTC(), from.UTC())
+}
+
+func queryCheckouts(query string, args ...any) ([]Checkout, error) {
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying checkouts: %w", err)
+	}
+	defer rows.Close()
+
+	var checkouts []Checkout
+	for rows.Next() {
+		c, err := scanCheckout(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning checkout: %w", err)
+		}
+		checkouts = append(checkouts, *c)
+	}
+	return checkouts, rows.Err()
+}
+
+// SetCheckoutAnnouncement records the announce channel message posted for a
+// checkout.
+func SetCheckoutAnnouncement(id uuid.UUID, channel, ts string) error {
+	_, err := db.DB.Exec(`UPDATE checkouts SET announce_channel = ?, announce_ts = ? WHERE id = ?`, channel, ts, id.String())
+	if err != nil {
+		return fmt.Errorf("failed to save announcement: %w", err)
+	}
+	return nil
+}
+
+// SetCheckoutCalendarEvent records the truck calendar event created for a
+// checkout.
+func SetCheckoutCalendarEvent(id uuid.UUID, eventID string) error {
+	_, err := db.DB.Exec(`UPDATE checkouts SET calendar_event_id = ? WHERE id = ?`, eventID, id.String())
+	if err != nil {
+		return fmt.Errorf("failed to save calendar event: %w", err)
+	}
+	return nil
+}
+
+// ExtendCheckout moves an open checkout's end to newEnd on behalf of actor.
+// The truck and any attached assets must be free for the extra time.
+func ExtendCheckout(id uuid.UUID, newEnd time.Time, actor Actor) (*Checkout, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := scanCheckout(tx.QueryRow(`SELECT `+checkoutColumns+` FROM checkouts WHERE id = ?`, id.String()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCheckoutNotFound
+		}
+		return nil, fmt.Errorf("failed to load checkout: %w", err)
+	}
+	if before.ReleasedAt != nil || before.CancelledAt != nil || !before.EndDate.After(time.Now()) {
+		return nil, ErrCheckoutNotFound
+	}
+	if !newEnd.After(before.EndDate) {
+		return nil, fmt.Errorf("new end %s is not after the current end %s", newEnd, before.EndDate)
+	}
+
+	if err := checkTruckFree(tx, before.TruckID, before.EndDate, newEnd); err != nil {
+		return nil, err
+	}
+	if err := checkAttachedAssetsFree(tx, before.ID, before.EndDate, newEnd); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE checkouts SET end_date = ? WHERE id = ?`, newEnd.UTC(), id.String()); err != nil {
+		return nil, fmt.Errorf("failed to extend checkout: %w", err)
+	}
+
+	after := *before
+	after.EndDate = newEnd
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionCheckoutExtend,
+		entityType: "checkout",
+		entityID:   id.String(),
+		truckID:    &before.TruckID,
+		before:     before,
+		after:      after,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
+
+// HandOffCheckout releases the in-progress checkout id and checks the truck
+// out to next for the rest of that checkout, in a single transaction, so
+// nobody else can grab the truck in between. next.TruckID, StartDate,
+// EndDate and AssetIDs are filled in from the released checkout, whose
+// trailers and equipment go along with the truck. If check is not nil the
+// new checkout must pass it. It returns ErrNoActiveCheckout if the checkout
+// is no longer in progress.
+func HandOffCheckout(id uuid.UUID, next Checkout, check *PolicyCheck, actor Actor) (*Checkout, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	before, err := scanCheckout(tx.QueryRow(`SELECT `+checkoutColumns+` FROM checkouts WHERE id = ?`, id.String()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCheckoutNotFound
+		}
+		return nil, fmt.Errorf("failed to load checkout: %w", err)
+	}
+	if before.ReleasedAt != nil || before.CancelledAt != nil || before.StartDate.After(now) || !before.EndDate.After(now) {
+		return nil, ErrNoActiveCheckout
+	}
+
+	_, err = tx.Exec(`UPDATE checkouts SET released_at = ?, released_by = ? WHERE id = ?`, now, actor.SlackUserID, id.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update current checkout: %w", err)
+	}
+	released := *before
+	released.ReleasedBy = &actor.SlackUserID
+	released.ReleasedAt = &now
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionCheckoutRelease,
+		entityType: "checkout",
+		entityID:   id.String(),
+		truckID:    &before.TruckID,
+		before:     before,
+		after:      released,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	next.TruckID = before.TruckID
+	next.StartDate = now
+	next.EndDate = before.EndDate
+	next.AssetIDs, err = attachedAssetIDs(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTruckFree(tx, next.TruckID, next.StartDate, next.EndDate); err != nil {
+		return nil, err
+	}
+	if err := check.enforce(tx, next, actor); err != nil {
+		return nil, err
+	}
+	if err := insertCheckout(tx, next); err != nil {
+		return nil, err
+	}
+	if len(next.AssetIDs) > 0 {
+		truck, err := scanTruck(tx.QueryRow(`SELECT `+truckColumns+` FROM trucks WHERE id = ?`, next.TruckID.String()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load truck: %w", err)
+		}
+		if err := attachAssets(tx, next, truck); err != nil {
+			return nil, err
+		}
+	}
+	if err := auditCheckoutCreate(tx, actor, next); err != nil {
+		return nil, err
+	}
+	if err := refreshTruckStatus(tx, next.TruckID, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &next, nil
 }
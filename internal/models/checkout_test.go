@@ -2,7 +2,11 @@ package models
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 	db "truck-checkout/internal/database"
@@ -14,7 +18,7 @@ func TestCheckoutDatabaseOperations(t *testing.T) {
 	ResetTestDB(t)
 	// First create a truck
 	team := "forest_restoration"
-	err := InsertTruck("Magnolia", &team, uuid.NewString(), false)
+	err := InsertTruck("Magnolia", &team, uuid.NewString(), false, testActor)
 	if err != nil {
 		t.Fatalf("failed to insert truck: %v", err)
 	}
@@ -36,7 +40,7 @@ func TestCheckoutDatabaseOperations(t *testing.T) {
 		Purpose:   "Testing This truck was checked out digitally",
 	}
 
-	err = CreateCheckout(checkout)
+	err = CreateCheckout(checkout, nil, testActor)
 	if err != nil {
 		t.Fatalf("failed to insert checkout: %v", err)
 	}
@@ -63,7 +67,7 @@ func TestGetActiveCheckoutByTruckID(t *testing.T) {
 
 	// Create a truck
 	team := "forest_restoration"
-	err := InsertTruck("Magnolia", &team, uuid.NewString(), false)
+	err := InsertTruck("Magnolia", &team, uuid.NewString(), false, testActor)
 	if err != nil {
 		t.Fatalf("failed to insert truck: %v", err)
 	}
@@ -91,7 +95,7 @@ func TestGetActiveCheckoutByTruckID(t *testing.T) {
 		Purpose:   "Active checkout test",
 	}
 
-	err = CreateCheckout(activeCheckout)
+	err = CreateCheckout(activeCheckout, nil, testActor)
 	if err != nil {
 		t.Fatalf("failed to insert checkout: %v", err)
 	}
@@ -124,7 +128,7 @@ func TestGetActiveCheckoutByTruckID(t *testing.T) {
 		Purpose:   "Expired checkout test",
 	}
 
-	err = CreateCheckout(expiredCheckout)
+	err = CreateCheckout(expiredCheckout, nil, testActor)
 	if err != nil {
 		t.Fatalf("failed to insert expired checkout: %v", err)
 	}
@@ -144,7 +148,7 @@ func TestReleaseTruckFromCheckout(t *testing.T) {
 	ResetTestDB(t)
 
 	team := "forest_restoration"
-	err := InsertTruck("Andre350", &team, uuid.NewString(), false) // Start as checked out
+	err := InsertTruck("Andre350", &team, uuid.NewString(), false, testActor) // Start as checked out
 	if err != nil {
 		t.Fatalf("failed to insert truck: %v", err)
 	}
@@ -166,14 +170,14 @@ func TestReleaseTruckFromCheckout(t *testing.T) {
 		Purpose:   "Test checkout for release",
 	}
 
-	err = CreateCheckout(checkout)
+	err = CreateCheckout(checkout, nil, testActor)
 	if err != nil {
 		t.Fatalf("failed to insert checkout: %v", err)
 	}
 
 	// Release the truck
 	releasedBy := "admin123"
-	err = ReleaseTruckFromCheckout(truck.ID, releasedBy)
+	err = ReleaseTruckFromCheckout(truck.ID, Actor{SlackUserID: releasedBy, Source: SourceSlashCommand})
 	if err != nil {
 		t.Fatalf("failed to release truck: %v", err)
 	}
@@ -210,7 +214,7 @@ func TestReleaseTruckFromCheckout_InvalidTruckID(t *testing.T) {
 	nonExistentTruckID := uuid.New()
 	releaserID := "admin123"
 
-	err := ReleaseTruckFromCheckout(nonExistentTruckID, releaserID)
+	err := ReleaseTruckFromCheckout(nonExistentTruckID, Actor{SlackUserID: releaserID, Source: SourceSlashCommand})
 
 	if err == nil {
 		t.Logf("Error: %v", err)
@@ -222,3 +226,646 @@ func TestReleaseTruckFromCheckout_InvalidTruckID(t *testing.T) {
 		t.Errorf("expected error containing '%s', got: %v", expectedMsg, err)
 	}
 }
+
+func TestCreateCheckoutForAnyTruck(t *testing.T) {
+	ResetTestDB(t)
+
+	beltline := "beltline"
+	urbanTrees := "urban_trees"
+	if err := InsertTruck("Watson", &urbanTrees, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	if err := InsertTruck("Libby", nil, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	if err := InsertTruck("Tulip", &beltline, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+
+	now := time.Now()
+	newCheckout := func() Checkout {
+		return Checkout{
+			ID:        uuid.New(),
+			UserID:    "user123",
+			UserName:  "John Doe",
+			TeamName:  "beltline",
+			StartDate: now.Add(-1 * time.Hour),
+			EndDate:   now.Add(8 * time.Hour),
+			Purpose:   "Any truck test",
+		}
+	}
+
+	// Excluded trucks are never picked
+	_, _, err := CreateCheckoutForAnyTruck(newCheckout(), []string{"Tulip", "Libby", "Watson"}, nil, testActor)
+	if !errors.Is(err, ErrNoTrucksAvailable) {
+		t.Errorf("expected ErrNoTrucksAvailable with every truck excluded, got %v", err)
+	}
+
+	expected := []struct {
+		truck string
+		match TruckMatch
+	}{
+		{"Tulip", MatchTeamTruck},
+		{"Libby", MatchUnassignedTruck},
+	}
+
+	for _, want := range expected {
+		checkout := newCheckout()
+		truck, match, err := CreateCheckoutForAnyTruck(checkout, nil, nil, testActor)
+		if err != nil {
+			t.Fatalf("failed to check out any truck: %v", err)
+		}
+		if truck.Name != want.truck || match != want.match {
+			t.Errorf("expected %s (match %d), got %s (match %d)", want.truck, want.match, truck.Name, match)
+		}
+
+		stored, err := GetCheckoutByID(checkout.ID)
+		if err != nil {
+			t.Fatalf("failed to get checkout: %v", err)
+		}
+		if stored.TruckID != truck.ID {
+			t.Errorf("expected checkout to reference %s", truck.Name)
+		}
+	}
+
+	// Watson is free but belongs to another team, so nothing is left
+	_, _, err = CreateCheckoutForAnyTruck(newCheckout(), nil, nil, testActor)
+	if !errors.Is(err, ErrNoTrucksAvailable) {
+		t.Errorf("expected ErrNoTrucksAvailable, got %v", err)
+	}
+}
+
+func TestCreateCheckout_Overlap(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	now := time.Now()
+	first := Checkout{
+		ID:        uuid.New(),
+		TruckID:   truck.ID,
+		UserID:    "user123",
+		UserName:  "John Doe",
+		TeamName:  "beltline",
+		StartDate: now,
+		EndDate:   now.Add(8 * time.Hour),
+	}
+	if err := CreateCheckout(first, nil, testActor); err != nil {
+		t.Fatalf("failed to insert checkout: %v", err)
+	}
+
+	overlapping := first
+	overlapping.ID = uuid.New()
+	overlapping.StartDate = now.Add(4 * time.Hour)
+	overlapping.EndDate = now.Add(12 * time.Hour)
+	if err := CreateCheckout(overlapping, nil, testActor); !errors.Is(err, ErrTruckUnavailable) {
+		t.Errorf("expected ErrTruckUnavailable for overlapping checkout, got %v", err)
+	}
+
+	// Back-to-back checkouts do not overlap
+	next := first
+	next.ID = uuid.New()
+	next.StartDate = first.EndDate
+	next.EndDate = first.EndDate.Add(8 * time.Hour)
+	if err := CreateCheckout(next, nil, testActor); err != nil {
+		t.Errorf("expected back-to-back checkout to succeed, got %v", err)
+	}
+}
+
+func TestCancelCheckout(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	now := time.Now()
+	current := Checkout{
+		ID:        uuid.New(),
+		TruckID:   truck.ID,
+		UserID:    "user123",
+		UserName:  "John Doe",
+		TeamName:  "beltline",
+		StartDate: now.Add(-time.Hour),
+		EndDate:   now.Add(time.Hour),
+	}
+	upcoming := current
+	upcoming.ID = uuid.New()
+	upcoming.StartDate = now.Add(24 * time.Hour)
+	upcoming.EndDate = now.Add(32 * time.Hour)
+	for _, c := range []Checkout{current, upcoming} {
+		if err := CreateCheckout(c, nil, testActor); err != nil {
+			t.Fatalf("failed to insert checkout: %v", err)
+		}
+	}
+
+	list, err := GetUpcomingCheckoutsByUserID("user123", now)
+	if err != nil {
+		t.Fatalf("failed to get upcoming checkouts: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != upcoming.ID {
+		t.Fatalf("expected only the upcoming reservation, got %+v", list)
+	}
+
+	if _, err := CancelCheckout(current.ID, testActor); !errors.Is(err, ErrCheckoutStarted) {
+		t.Errorf("expected ErrCheckoutStarted for a checkout under way, got %v", err)
+	}
+
+	cancelled, err := CancelCheckout(upcoming.ID, Actor{SlackUserID: "user123", Source: SourceSlashCommand})
+	if err != nil {
+		t.Fatalf("failed to cancel checkout: %v", err)
+	}
+	if cancelled.CancelledBy == nil || *cancelled.CancelledBy != "user123" || cancelled.CancelledAt == nil {
+		t.Errorf("expected cancellation to be recorded, got %+v", cancelled)
+	}
+	if _, err := CancelCheckout(upcoming.ID, testActor); !errors.Is(err, ErrCheckoutNotFound) {
+		t.Errorf("expected ErrCheckoutNotFound when cancelling twice, got %v", err)
+	}
+
+	// The cancelled slot can be booked again and no longer counts anywhere
+	rebooked := upcoming
+	rebooked.ID = uuid.New()
+	rebooked.UserID = "user456"
+	if err := CreateCheckout(rebooked, nil, testActor); err != nil {
+		t.Errorf("expected cancelled slot to be bookable, got %v", err)
+	}
+	if n, err := CountOpenCheckoutsForUser("user123", upcoming.StartDate, upcoming.EndDate); err != nil || n != 0 {
+		t.Errorf("expected no open checkouts for user123, got %d (%v)", n, err)
+	}
+	if list, err := GetUpcomingCheckoutsByUserID("user123", now); err != nil || len(list) != 0 {
+		t.Errorf("expected no upcoming reservations after cancel, got %+v (%v)", list, err)
+	}
+
+	// Releasing only ends the checkout that is under way
+	if err := ReleaseTruckFromCheckout(truck.ID, testActor); err != nil {
+		t.Fatalf("failed to release truck: %v", err)
+	}
+	var releasedAt sql.NullTime
+	if err := db.DB.QueryRow(`SELECT released_at FROM checkouts WHERE id = ?`, rebooked.ID.String()).Scan(&releasedAt); err != nil {
+		t.Fatalf("failed to read checkout: %v", err)
+	}
+	if releasedAt.Valid {
+		t.Error("expected the future reservation to survive a release")
+	}
+}
+
+func TestCreateCheckout_Concurrent(t *testing.T) {
+	// Use a file database so every goroutine gets its own connection and
+	// really competes for the write lock.
+	fileDB, err := db.Open(filepath.Join(t.TempDir(), "concurrent.db"))
+	if err != nil {
+		t.Fatalf("failed to open file database: %v", err)
+	}
+	defer fileDB.Close()
+	if err := db.CreateTables(fileDB); err != nil {
+		t.Fatalf("failed to create tables: %v", err)
+	}
+
+	originalDB := db.DB
+	db.DB = fileDB
+	defer func() { db.DB = originalDB }()
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	const attempts = 20
+	now := time.Now()
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results <- CreateCheckout(Checkout{
+				ID:        uuid.New(),
+				TruckID:   truck.ID,
+				UserID:    fmt.Sprintf("user%d", i),
+				UserName:  fmt.Sprintf("User %d", i),
+				TeamName:  "beltline",
+				StartDate: now,
+				EndDate:   now.Add(8 * time.Hour),
+			}, nil, testActor)
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrTruckUnavailable):
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("expected exactly 1 checkout to succeed, got %d", succeeded)
+	}
+
+	var stored int
+	if err := db.DB.QueryRow(`SELECT COUNT(*) FROM checkouts WHERE truck_id = ?`, truck.ID.String()).Scan(&stored); err != nil {
+		t.Fatalf("failed to count checkouts: %v", err)
+	}
+	if stored != 1 {
+		t.Errorf("expected 1 stored checkout, got %d", stored)
+	}
+}
+
+func TestCheckoutTimestampsStoredAsUTC(t *testing.T) {
+	ResetTestDB(t)
+
+	eastern, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	checkout := Checkout{
+		ID:        uuid.New(),
+		TruckID:   truck.ID,
+		UserID:    "user123",
+		UserName:  "John Doe",
+		TeamName:  "beltline",
+		StartDate: time.Date(2026, time.October, 20, 7, 0, 0, 0, eastern),
+		EndDate:   time.Date(2026, time.October, 20, 15, 30, 0, 0, eastern),
+	}
+	if err := CreateCheckout(checkout, nil, testActor); err != nil {
+		t.Fatalf("failed to insert checkout: %v", err)
+	}
+
+	var rawStart, rawEnd string
+	err = db.DB.QueryRow(`SELECT start_date, end_date FROM checkouts WHERE id = ?`, checkout.ID.String()).Scan(&rawStart, &rawEnd)
+	if err != nil {
+		t.Fatalf("failed to read raw timestamps: %v", err)
+	}
+	if !strings.HasPrefix(rawStart, "2026-10-20T11:00:00") && !strings.HasPrefix(rawStart, "2026-10-20 11:00:00") {
+		t.Errorf("expected start stored as 11:00 UTC, got %q", rawStart)
+	}
+	if !strings.HasSuffix(rawStart, "Z") && !strings.HasSuffix(rawStart, "+00:00") {
+		t.Errorf("expected start stored in UTC, got %q", rawStart)
+	}
+
+	stored, err := GetCheckoutByID(checkout.ID)
+	if err != nil {
+		t.Fatalf("failed to get checkout: %v", err)
+	}
+	if !stored.StartDate.Equal(checkout.StartDate) || !stored.EndDate.Equal(checkout.EndDate) {
+		t.Errorf("expected round-tripped times to match, got %s - %s", stored.StartDate, stored.EndDate)
+	}
+}
+
+func TestNormalizeTimestamps(t *testing.T) {
+	ResetTestDB(t)
+
+	_, err := db.DB.Exec(`
+		INSERT INTO checkouts (id, truck_id, user_id, user_name, team_name, start_date, end_date)
+		VALUES ('legacy', 'truck', 'user123', 'John Doe', 'beltline', '2026-10-20 07:00:00-04:00', '2026-10-20 15:30:00-04:00')
+	`)
+	if err != nil {
+		t.Fatalf("failed to insert legacy checkout: %v", err)
+	}
+
+	if err := db.NormalizeTimestamps(db.DB); err != nil {
+		t.Fatalf("failed to normalize timestamps: %v", err)
+	}
+
+	var rawStart string
+	if err := db.DB.QueryRow(`SELECT start_date FROM checkouts WHERE id = 'legacy'`).Scan(&rawStart); err != nil {
+		t.Fatalf("failed to read raw timestamp: %v", err)
+	}
+	if rawStart != "2026-10-20T11:00:00Z" {
+		t.Errorf("expected legacy start normalized to UTC, got %q", rawStart)
+	}
+}
+
+func TestGetOpenCheckoutsByUserID(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, _ := GetTruckByName("Tulip")
+
+	now := time.Now()
+	newCheckout := func(start time.Time) Checkout {
+		return Checkout{
+			ID:        uuid.New(),
+			TruckID:   truck.ID,
+			UserID:    "user123",
+			UserName:  "John Doe",
+			TeamName:  "beltline",
+			StartDate: start,
+			EndDate:   start.Add(4 * time.Hour),
+		}
+	}
+	past, current, upcoming := newCheckout(now.Add(-48*time.Hour)), newCheckout(now.Add(-time.Hour)), newCheckout(now.Add(24*time.Hour))
+	for _, c := range []Checkout{past, current, upcoming} {
+		if err := CreateCheckout(c, nil, testActor); err != nil {
+			t.Fatalf("failed to create checkout: %v", err)
+		}
+	}
+
+	open, err := GetOpenCheckoutsByUserID("user123", now)
+	if err != nil {
+		t.Fatalf("failed to get open checkouts: %v", err)
+	}
+	if len(open) != 2 || open[0].ID != current.ID || open[1].ID != upcoming.ID {
+		t.Errorf("expected the current and upcoming checkouts, got %+v", open)
+	}
+}
+
+func TestExtendCheckout(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, _ := GetTruckByName("Tulip")
+
+	now := time.Now()
+	checkout := Checkout{
+		ID:        uuid.New(),
+		TruckID:   truck.ID,
+		UserID:    "user123",
+		UserName:  "John Doe",
+		TeamName:  "beltline",
+		StartDate: now.Add(-time.Hour),
+		EndDate:   now.Add(4 * time.Hour),
+	}
+	if err := CreateCheckout(checkout, nil, testActor); err != nil {
+		t.Fatalf("failed to create checkout: %v", err)
+	}
+	if err := SetCheckoutAnnouncement(checkout.ID, "C123", "1700000000.000100"); err != nil {
+		t.Fatalf("failed to save announcement: %v", err)
+	}
+
+	// Someone else has the truck from tomorrow
+	next := Checkout{
+		ID:        uuid.New(),
+		TruckID:   truck.ID,
+		UserID:    "user456",
+		UserName:  "Jane Roe",
+		TeamName:  "beltline",
+		StartDate: now.Add(24 * time.Hour),
+		EndDate:   now.Add(30 * time.Hour),
+	}
+	if err := CreateCheckout(next, nil, testActor); err != nil {
+		t.Fatalf("failed to create checkout: %v", err)
+	}
+
+	extended, err := ExtendCheckout(checkout.ID, now.Add(8*time.Hour), testActor)
+	if err != nil {
+		t.Fatalf("failed to extend checkout: %v", err)
+	}
+	got, err := GetCheckoutByID(checkout.ID)
+	if err != nil {
+		t.Fatalf("failed to get checkout: %v", err)
+	}
+	if !got.EndDate.Equal(extended.EndDate) || got.AnnounceChannel != "C123" || got.AnnounceTS != "1700000000.000100" {
+		t.Errorf("expected the extended checkout with its announcement, got %+v", got)
+	}
+
+	if _, err := ExtendCheckout(checkout.ID, now.Add(26*time.Hour), testActor); !errors.Is(err, ErrCheckoutOverlap) {
+		t.Errorf("expected ErrCheckoutOverlap extending into the next booking, got %v", err)
+	}
+
+	if err := ReleaseTruckFromCheckout(truck.ID, testActor); err != nil {
+		t.Fatalf("failed to release truck: %v", err)
+	}
+	if _, err := ExtendCheckout(checkout.ID, now.Add(10*time.Hour), testActor); !errors.Is(err, ErrCheckoutNotFound) {
+		t.Errorf("expected ErrCheckoutNotFound for a released checkout, got %v", err)
+	}
+}
+
+func TestHandOffCheckout(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	if err := InsertTruck("Libby", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, _ := GetTruckByName("Tulip")
+	libby, _ := GetTruckByName("Libby")
+	trailer, err := InsertAsset("Trailer", AssetTrailer, nil, nil, testActor)
+	if err != nil {
+		t.Fatalf("failed to insert asset: %v", err)
+	}
+
+	now := time.Now()
+	checkout := Checkout{
+		ID:        uuid.New(),
+		TruckID:   truck.ID,
+		UserID:    "user123",
+		UserName:  "John Doe",
+		TeamName:  "beltline",
+		StartDate: now.Add(-time.Hour),
+		EndDate:   now.Add(4 * time.Hour),
+		AssetIDs:  []uuid.UUID{trailer.ID},
+	}
+	if err := CreateCheckout(checkout, nil, testActor); err != nil {
+		t.Fatalf("failed to create checkout: %v", err)
+	}
+	busy := Checkout{
+		ID:        uuid.New(),
+		TruckID:   libby.ID,
+		UserID:    "user789",
+		UserName:  "Sam Poe",
+		TeamName:  "beltline",
+		StartDate: now.Add(-time.Hour),
+		EndDate:   now.Add(4 * time.Hour),
+	}
+	if err := CreateCheckout(busy, nil, testActor); err != nil {
+		t.Fatalf("failed to create checkout: %v", err)
+	}
+	check := func(userID string) *PolicyCheck {
+		return &PolicyCheck{
+			Policy:  &CheckoutPolicy{OneTruckPerUser: true},
+			Request: CheckoutRequest{TruckName: "Tulip", UserID: userID, TeamName: "beltline", BusinessDays: 1, Now: now},
+		}
+	}
+
+	// Someone who already has a truck can't take over another
+	var policyErr *PolicyError
+	_, err = HandOffCheckout(checkout.ID, Checkout{ID: uuid.New(), UserID: "user789", UserName: "Sam Poe", TeamName: "beltline"}, check("user789"), testActor)
+	if !errors.As(err, &policyErr) || policyErr.Violations[0].Rule != RuleOneTruckPerUser {
+		t.Fatalf("expected a one-truck-per-user violation, got %v", err)
+	}
+	if old, _ := GetCheckoutByID(checkout.ID); old.ReleasedAt != nil {
+		t.Fatal("expected the refused handoff to leave the checkout alone")
+	}
+
+	next, err := HandOffCheckout(checkout.ID, Checkout{
+		ID:       uuid.New(),
+		UserID:   "user456",
+		UserName: "Jane Roe",
+		TeamName: "beltline",
+	}, check("user456"), testActor)
+	if err != nil {
+		t.Fatalf("failed to hand off checkout: %v", err)
+	}
+	if next.TruckID != truck.ID || !next.EndDate.Equal(checkout.EndDate) {
+		t.Errorf("expected the rest of the checkout on Tulip, got %+v", next)
+	}
+	if assets, err := GetCheckoutAssets(next.ID); err != nil || len(assets) != 1 || assets[0].ID != trailer.ID {
+		t.Errorf("expected the trailer to go along with the truck, got %+v (%v)", assets, err)
+	}
+
+	old, _ := GetCheckoutByID(checkout.ID)
+	if old.ReleasedAt == nil {
+		t.Error("expected the original checkout to be released")
+	}
+	active, err := GetActiveCheckoutByTruckID(truck.ID)
+	if err != nil {
+		t.Fatalf("failed to get active checkout: %v", err)
+	}
+	if active.ID != next.ID || active.UserID != "user456" {
+		t.Errorf("expected the handed-off checkout to be active, got %+v", active)
+	}
+	if truck, _ := GetTruckByName("Tulip"); !truck.IsCheckedOut {
+		t.Error("expected Tulip to stay checked out")
+	}
+
+	if _, err := HandOffCheckout(checkout.ID, Checkout{ID: uuid.New(), UserID: "user789", UserName: "Sam Poe", TeamName: "beltline"}, nil, testActor); !errors.Is(err, ErrNoActiveCheckout) {
+		t.Errorf("expected ErrNoActiveCheckout handing off a released checkout, got %v", err)
+	}
+}
+
+func TestCreateCheckout_SameDaySlots(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, _ := GetTruckByName("Tulip")
+
+	day := time.Now().AddDate(0, 0, 1).Truncate(24 * time.Hour)
+	slot := func(user string, startMin, endMin int) Checkout {
+		return Checkout{
+			ID:        uuid.New(),
+			TruckID:   truck.ID,
+			UserID:    user,
+			UserName:  user,
+			TeamName:  "beltline",
+			StartDate: day.Add(time.Duration(startMin) * time.Minute),
+			EndDate:   day.Add(time.Duration(endMin) * time.Minute),
+		}
+	}
+
+	// Morning crew 7:00-11:00, afternoon crew from 12:00.
+	if err := CreateCheckout(slot("user123", 7*60, 11*60), nil, testActor); err != nil {
+		t.Fatalf("failed to create morning checkout: %v", err)
+	}
+	if err := CreateCheckout(slot("user456", 12*60, 15*60+30), nil, testActor); err != nil {
+		t.Fatalf("failed to create afternoon checkout: %v", err)
+	}
+	// Running one minute into the afternoon is turned away, but the gap
+	// between the two fits exactly.
+	if err := CreateCheckout(slot("user789", 11*60, 12*60+1), nil, testActor); !errors.Is(err, ErrCheckoutOverlap) {
+		t.Errorf("expected ErrCheckoutOverlap for a one-minute overlap, got %v", err)
+	}
+	if err := CreateCheckout(slot("user789", 11*60, 12*60), nil, testActor); err != nil {
+		t.Errorf("expected the midday gap to be bookable, got %v", err)
+	}
+}
+
+func TestGetCheckoutsBetween(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	for _, name := range []string{"Tulip", "Watson"} {
+		if err := InsertTruck(name, &team, uuid.NewString(), false, testActor); err != nil {
+			t.Fatalf("failed to insert truck: %v", err)
+		}
+	}
+	tulip, _ := GetTruckByName("Tulip")
+	watson, _ := GetTruckByName("Watson")
+
+	monday := time.Date(2026, time.November, 2, 0, 0, 0, 0, time.UTC)
+	book := func(truckID uuid.UUID, start, end time.Time) Checkout {
+		c := Checkout{
+			ID:        uuid.New(),
+			TruckID:   truckID,
+			UserID:    "user123",
+			UserName:  "John Doe",
+			TeamName:  "beltline",
+			StartDate: start,
+			EndDate:   end,
+		}
+		if err := CreateCheckout(c, nil, testActor); err != nil {
+			t.Fatalf("failed to create checkout: %v", err)
+		}
+		return c
+	}
+	lastWeek := book(tulip.ID, monday.AddDate(0, 0, -4), monday.AddDate(0, 0, -3))
+	spanning := book(tulip.ID, monday.AddDate(0, 0, -1), monday.AddDate(0, 0, 1))
+	tuesday := monday.AddDate(0, 0, 1)
+	released := book(tulip.ID, tuesday.Add(7*time.Hour), tuesday.Add(15*time.Hour))
+	releasedAt := tuesday.Add(10 * time.Hour)
+	if _, err := db.DB.Exec(`UPDATE checkouts SET released_at = ?, released_by = ? WHERE id = ?`, releasedAt, "user123", released.ID.String()); err != nil {
+		t.Fatalf("failed to release checkout: %v", err)
+	}
+	midweek := book(watson.ID, monday.AddDate(0, 0, 2), monday.AddDate(0, 0, 3))
+	cancelled := book(watson.ID, monday.AddDate(0, 0, 4), monday.AddDate(0, 0, 5))
+	if _, err := CancelCheckout(cancelled.ID, testActor); err != nil {
+		t.Fatalf("failed to cancel checkout: %v", err)
+	}
+
+	got, err := GetCheckoutsBetween(monday, monday.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("failed to get checkouts: %v", err)
+	}
+	if len(got) != 3 || got[0].ID != spanning.ID || got[1].ID != released.ID || got[2].ID != midweek.ID {
+		t.Fatalf("expected the spanning, released and midweek checkouts, got %+v", got)
+	}
+	if !got[1].HeldUntil().Equal(releasedAt) || !got[2].HeldUntil().Equal(midweek.EndDate) {
+		t.Errorf("expected the released checkout held until %v, got %v", releasedAt, got[1].HeldUntil())
+	}
+	for _, c := range got {
+		if c.ID == lastWeek.ID {
+			t.Error("expected last week's checkout to be left out")
+		}
+	}
+
+	trucks, err := GetTrucks()
+	if err != nil {
+		t.Fatalf("failed to get trucks: %v", err)
+	}
+	if len(trucks) != 2 || trucks[0].Name != "Tulip" || trucks[1].Name != "Watson" {
+		t.Errorf("expected Tulip and Watson, got %+v", trucks)
+	}
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	db "truck-checkout/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// CustodyItem is a physical item that travels with a truck.
+type CustodyItem string
+
+const (
+	CustodyKeys     CustodyItem = "keys"
+	CustodyFuelCard CustodyItem = "fuel_card"
+)
+
+// CustodyItems lists every item tracked for each truck.
+var CustodyItems = []CustodyItem{CustodyKeys, CustodyFuelCard}
+
+// ParseCustodyItem accepts the names people type, e.g. "keys" or "fuelcard".
+func ParseCustodyItem(s string) (CustodyItem, bool) {
+	switch strings.ToLower(strings.ReplaceAll(s, "-", "_")) {
+	case "keys", "key":
+		return CustodyKeys, true
+	case "fuel_card", "fuelcard", "card":
+		return CustodyFuelCard, true
+	}
+	return "", false
+}
+
+// Label is the item's name for display, e.g. "fuel card".
+func (i CustodyItem) Label() string {
+	return strings.ReplaceAll(string(i), "_", " ")
+}
+
+type CustodyAction string
+
+const (
+	CustodyHandoff CustodyAction = "handoff"
+	CustodyReturn  CustodyAction = "return"
+)
+
+// CustodyEvent records an item changing hands. HolderID is empty when the
+// item was returned to the lockbox.
+type CustodyEvent struct {
+	ID         uuid.UUID     `json:"id"`
+	TruckID    uuid.UUID     `json:"truck_id"`
+	Item       CustodyItem   `json:"item"`
+	Action     CustodyAction `json:"action"`
+	HolderID   string        `json:"holder_slack_id,omitempty"`
+	CheckoutID *uuid.UUID    `json:"checkout_id,omitempty"`
+	RecordedBy string        `json:"recorded_by"`
+	RecordedAt time.Time     `json:"recorded_at"`
+}
+
+// InLockbox reports whether the item was last returned to the lockbox.
+func (e *CustodyEvent) InLockbox() bool {
+	return e.Action == CustodyReturn
+}
+
+// HandOffCustody records that holder took the item, usually at checkout.
+func HandOffCustody(truckID uuid.UUID, checkoutID *uuid.UUID, item CustodyItem, holder string, actor Actor) error {
+	return recordCustody(CustodyEvent{
+		TruckID:    truckID,
+		Item:       item,
+		Action:     CustodyHandoff,
+		HolderID:   holder,
+		CheckoutID: checkoutID,
+		RecordedBy: actor.SlackUserID,
+	})
+}
+
+// ReturnCustody records that the item went back to the lockbox.
+func ReturnCustody(truckID uuid.UUID, checkoutID *uuid.UUID, item CustodyItem, actor Actor) error {
+	return recordCustody(CustodyEvent{
+		TruckID:    truckID,
+		Item:       item,
+		Action:     CustodyReturn,
+		CheckoutID: checkoutID,
+		RecordedBy: actor.SlackUserID,
+	})
+}
+
+// HandOffStartedCheckouts records the keys and fuel card going to the holder
+// of every checkout in progress at now that nothing has been recorded for
+// yet, i.e. bookings that have started since they were made. It returns the
+// handoffs recorded.
+func HandOffStartedCheckouts(now time.Time, actor Actor) ([]CustodyEvent, error) {
+	rows, err := db.DB.Query(`
+		SELECT c.id, c.truck_id, c.user_id FROM checkouts c
+		WHERE `+inProgressAt+`
+			AND NOT EXISTS (SELECT 1 FROM custody_events e WHERE e.checkout_id = c.id)
+		ORDER BY c.start_date
+	`, now.UTC(), now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query started checkouts: %w", err)
+	}
+	var started []CustodyEvent
+	for rows.Next() {
+		var e CustodyEvent
+		var checkoutID uuid.UUID
+		if err := rows.Scan(&checkoutID, &e.TruckID, &e.HolderID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning started checkout: %w", err)
+		}
+		e.CheckoutID = &checkoutID
+		started = append(started, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var handoffs []CustodyEvent
+	for _, e := range started {
+		for _, item := range CustodyItems {
+			if err := HandOffCustody(e.TruckID, e.CheckoutID, item, e.HolderID, actor); err != nil {
+				return handoffs, err
+			}
+			e.Item, e.Action, e.RecordedBy = item, CustodyHandoff, actor.SlackUserID
+			handoffs = append(handoffs, e)
+		}
+	}
+	return handoffs, nil
+}
+
+func recordCustody(e CustodyEvent) error {
+	var holder, checkoutID any
+	if e.HolderID != "" {
+		holder = e.HolderID
+	}
+	if e.CheckoutID != nil {
+		checkoutID = e.CheckoutID.String()
+	}
+	_, err := db.DB.Exec(`
+		INSERT INTO custody_events (id, truck_id, item, action, holder_slack_id, checkout_id, recorded_by, recorded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, uuid.NewString(), e.TruckID.String(), e.Item, e.Action, holder, checkoutID, e.RecordedBy, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record %s %s: %w", e.Item, e.Action, err)
+	}
+	return nil
+}
+
+// GetCustody returns the latest custody event for the truck's item, which
+// says where it is now, or nil if it has never been recorded.
+func GetCustody(truckID uuid.UUID, item CustodyItem) (*CustodyEvent, error) {
+	var e CustodyEvent
+	var holder, checkoutID sql.NullString
+	err := db.DB.QueryRow(`
+		SELECT id, truck_id, item, action, holder_slack_id, checkout_id, recorded_by, recorded_at
+		FROM custody_events
+		WHERE truck_id = ? AND item = ?
+		ORDER BY recorded_at DESC, rowid DESC
+		LIMIT 1
+	`, truckID.String(), item).Scan(&e.ID, &e.TruckID, &e.Item, &e.Action, &holder, &checkoutID, &e.RecordedBy, &e.RecordedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get %s custody: %w", item, err)
+	}
+	e.HolderID = holder.String
+	if checkoutID.Valid {
+		id, err := uuid.Parse(checkoutID.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing custody checkout UUID: %w", err)
+		}
+		e.CheckoutID = &id
+	}
+	return &e, nil
+}
+
+// OutstandingCustody returns the truck's items that are out of the lockbox,
+// with whoever has them.
+func OutstandingCustody(truckID uuid.UUID) ([]CustodyEvent, error) {
+	var out []CustodyEvent
+	for _, item := range CustodyItems {
+		e, err := GetCustody(truckID, item)
+		if err != nil {
+			return nil, err
+		}
+		if e != nil && !e.InLockbox() {
+			out = append(out, *e)
+		}
+	}
+	return out, nil
+}
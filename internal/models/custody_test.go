@@ -0,0 +1,124 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCustody(t *testing.T) {
+	ResetTestDB(t)
+
+	if err := InsertTruck("Tulip", nil, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	if e, err := GetCustody(truck.ID, CustodyKeys); err != nil || e != nil {
+		t.Fatalf("expected no custody before anything is recorded, got %+v (%v)", e, err)
+	}
+
+	checkoutID := uuid.New()
+	for _, item := range CustodyItems {
+		if err := HandOffCustody(truck.ID, &checkoutID, item, "user123", testActor); err != nil {
+			t.Fatalf("failed to hand off %s: %v", item, err)
+		}
+	}
+	if err := ReturnCustody(truck.ID, &checkoutID, CustodyFuelCard, testActor); err != nil {
+		t.Fatalf("failed to return fuel card: %v", err)
+	}
+
+	keys, err := GetCustody(truck.ID, CustodyKeys)
+	if err != nil {
+		t.Fatalf("failed to get custody: %v", err)
+	}
+	if keys == nil || keys.InLockbox() || keys.HolderID != "user123" || keys.CheckoutID == nil || *keys.CheckoutID != checkoutID {
+		t.Errorf("expected user123 to hold the keys, got %+v", keys)
+	}
+
+	out, err := OutstandingCustody(truck.ID)
+	if err != nil {
+		t.Fatalf("failed to get outstanding custody: %v", err)
+	}
+	if len(out) != 1 || out[0].Item != CustodyKeys {
+		t.Errorf("expected only the keys to be out, got %+v", out)
+	}
+
+	if err := ReturnCustody(truck.ID, nil, CustodyKeys, testActor); err != nil {
+		t.Fatalf("failed to return keys: %v", err)
+	}
+	if out, _ := OutstandingCustody(truck.ID); len(out) != 0 {
+		t.Errorf("expected everything back in the lockbox, got %+v", out)
+	}
+
+	for input, want := range map[string]CustodyItem{"keys": CustodyKeys, "Key": CustodyKeys, "fuelcard": CustodyFuelCard, "fuel-card": CustodyFuelCard} {
+		if got, ok := ParseCustodyItem(input); !ok || got != want {
+			t.Errorf("ParseCustodyItem(%q) = %q, %v; want %q", input, got, ok, want)
+		}
+	}
+	if _, ok := ParseCustodyItem("wallet"); ok {
+		t.Error("expected unknown item to be rejected")
+	}
+}
+
+func TestHandOffStartedCheckouts(t *testing.T) {
+	ResetTestDB(t)
+
+	if err := InsertTruck("Tulip", nil, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	// Booked this morning for the afternoon
+	now := time.Now().UTC().Truncate(time.Minute)
+	checkout := Checkout{
+		ID:        uuid.New(),
+		TruckID:   truck.ID,
+		UserID:    "user123",
+		UserName:  "John Doe",
+		TeamName:  "beltline",
+		StartDate: now.Add(2 * time.Hour),
+		EndDate:   now.Add(5 * time.Hour),
+	}
+	if err := CreateCheckout(checkout, nil, testActor); err != nil {
+		t.Fatalf("failed to create checkout: %v", err)
+	}
+
+	if handoffs, err := HandOffStartedCheckouts(now, testActor); err != nil || len(handoffs) != 0 {
+		t.Fatalf("expected nothing handed over before the checkout starts, got %+v (%v)", handoffs, err)
+	}
+	if e, _ := GetCustody(truck.ID, CustodyKeys); e != nil {
+		t.Fatalf("expected no custody recorded yet, got %+v", e)
+	}
+
+	started := now.Add(3 * time.Hour)
+	handoffs, err := HandOffStartedCheckouts(started, testActor)
+	if err != nil {
+		t.Fatalf("failed to hand off custody: %v", err)
+	}
+	if len(handoffs) != len(CustodyItems) {
+		t.Fatalf("expected every item handed over, got %+v", handoffs)
+	}
+	keys, err := GetCustody(truck.ID, CustodyKeys)
+	if err != nil {
+		t.Fatalf("failed to get custody: %v", err)
+	}
+	if keys == nil || keys.HolderID != "user123" || keys.CheckoutID == nil || *keys.CheckoutID != checkout.ID {
+		t.Errorf("expected user123 to hold the keys for the checkout, got %+v", keys)
+	}
+
+	// Once recorded, later runs leave it alone
+	if err := ReturnCustody(truck.ID, &checkout.ID, CustodyKeys, testActor); err != nil {
+		t.Fatalf("failed to return keys: %v", err)
+	}
+	if handoffs, err := HandOffStartedCheckouts(started.Add(time.Minute), testActor); err != nil || len(handoffs) != 0 {
+		t.Errorf("expected no second handoff, got %+v (%v)", handoffs, err)
+	}
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Domain errors returned by this package. Compare against them with
+// errors.Is / errors.As; their text is for logs, not for end users, so each
+// front end (Slack, HTTP, CLI) decides how to present them.
+var (
+	ErrTruckNotFound     = errors.New("truck not found")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrInvalidTruck      = errors.New("invalid truck name")
+	ErrInvalidTeam       = errors.New("invalid team")
+	ErrInvalidPeriod     = errors.New("period must end after it starts")
+	ErrTruckUnavailable  = errors.New("truck is already checked out")
+	ErrInMaintenance     = errors.New("truck is out of service for maintenance")
+	ErrCheckoutOverlap   = errors.New("checkout overlaps an existing booking")
+	ErrNoTrucksAvailable = errors.New("no trucks available for the requested dates")
+	ErrNoActiveCheckout  = errors.New("no active checkout found for this truck")
+	ErrCheckoutNotFound  = errors.New("checkout not found")
+	ErrCheckoutStarted   = errors.New("checkout has already started")
+	ErrCrossTeam         = errors.New("truck belongs to another team")
+	ErrAlreadyWaitlisted = errors.New("already on the waitlist for this day")
+	ErrOfferUnavailable  = errors.New("waitlist offer is no longer available")
+	ErrPermissionDenied  = errors.New("permission denied")
+	ErrPolicyViolation   = errors.New("checkout violates policy")
+	ErrAssetNotFound     = errors.New("asset not found")
+	ErrAssetUnavailable  = errors.New("asset is already booked")
+	ErrAssetIncompatible = errors.New("asset is not compatible with the truck")
+	ErrNotQualified      = errors.New("driver is not qualified")
+	ErrInvalidReading    = errors.New("invalid odometer reading")
+	ErrInvalidFuel       = errors.New("invalid fuel purchase")
+	ErrReadingRecorded   = errors.New("odometer reading already recorded for this checkout")
+	ErrReadingTooLow     = errors.New("odometer reading is lower than the last recorded value")
+)
+
+// CrossTeamError is returned when a user asks for a truck whose default team
+// is not their own.
+type CrossTeamError struct {
+	Truck     string
+	TruckTeam string
+	UserTeam  string
+}
+
+func (e *CrossTeamError) Error() string {
+	return fmt.Sprintf("%s is typically used by %s team, but user is on %s team", e.Truck, e.TruckTeam, e.UserTeam)
+}
+
+func (e *CrossTeamError) Unwrap() error { return ErrCrossTeam }
+
+// PolicyError is returned when a checkout breaks one or more rules of the
+// checkout policy.
+type PolicyError struct {
+	Violations []PolicyViolation
+}
+
+func (e *PolicyError) Error() string {
+	reasons := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		reasons[i] = string(v.Rule)
+	}
+	return fmt.Sprintf("checkout violates policy: %s", strings.Join(reasons, ", "))
+}
+
+func (e *PolicyError) Unwrap() error { return ErrPolicyViolation }
+
+// AssetUnavailableError is returned when an asset on the checkout is already
+// booked for part of the period.
+type AssetUnavailableError struct {
+	Asset string
+}
+
+func (e *AssetUnavailableError) Error() string {
+	return fmt.Sprintf("%s is already booked", e.Asset)
+}
+
+func (e *AssetUnavailableError) Unwrap() error { return ErrAssetUnavailable }
+
+// IncompatibleAssetError is returned when an asset needs a feature, such as
+// a hitch, that the truck and the other assets don't have.
+type IncompatibleAssetError struct {
+	Asset    string
+	Truck    string
+	Requires string
+}
+
+func (e *IncompatibleAssetError) Error() string {
+	return fmt.Sprintf("%s requires %s, which %s does not have", e.Asset, e.Requires, e.Truck)
+}
+
+func (e *IncompatibleAssetError) Unwrap() error { return ErrAssetIncompatible }
+
+// QualificationError is returned when the driver has no valid license on
+// file, or no valid certification for the truck's class. ExpiredAt is set
+// when there is one on file but it runs out before the checkout ends.
+type QualificationError struct {
+	Kind       QualificationKind
+	TruckClass string
+	Truck      string
+	ExpiredAt  *time.Time
+}
+
+func (e *QualificationError) Error() string {
+	what := "license"
+	if e.Kind == QualificationCertification {
+		what = fmt.Sprintf("%s certification for %s", e.TruckClass, e.Truck)
+	}
+	if e.ExpiredAt != nil {
+		return fmt.Sprintf("%s expires %s", what, e.ExpiredAt.Format("2006-01-02"))
+	}
+	return fmt.Sprintf("no %s on file", what)
+}
+
+func (e *QualificationError) Unwrap() error { return ErrNotQualified }
+
+// ReadingTooLowError is returned when an odometer reading is lower than the
+// last one recorded for the same truck.
+type ReadingTooLowError struct {
+	Reading int
+	Last    int
+}
+
+func (e *ReadingTooLowError) Error() string {
+	return fmt.Sprintf("odometer reading %d is lower than the last recorded value %d for this truck", e.Reading, e.Last)
+}
+
+func (e *ReadingTooLowError) Unwrap() error { return ErrReadingTooLow }
+
+// ErrorCode returns a stable, machine-readable code for a domain error so
+// that non-Slack front ends can map it to their own status codes. Unknown
+// errors map to "internal".
+func ErrorCode(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrTruckNotFound), errors.Is(err, ErrUserNotFound), errors.Is(err, ErrNoActiveCheckout), errors.Is(err, ErrCheckoutNotFound),
+		errors.Is(err, ErrAssetNotFound):
+		return "not_found"
+	case errors.Is(err, ErrTruckUnavailable), errors.Is(err, ErrNoTrucksAvailable), errors.Is(err, ErrOfferUnavailable),
+		errors.Is(err, ErrAssetUnavailable), errors.Is(err, ErrInMaintenance):
+		return "unavailable"
+	case errors.Is(err, ErrCheckoutOverlap), errors.Is(err, ErrAlreadyWaitlisted), errors.Is(err, ErrCheckoutStarted),
+		errors.Is(err, ErrReadingRecorded):
+		return "conflict"
+	case errors.Is(err, ErrCrossTeam):
+		return "cross_team"
+	case errors.Is(err, ErrInvalidTeam), errors.Is(err, ErrInvalidTruck), errors.Is(err, ErrAssetIncompatible), errors.Is(err, ErrInvalidPeriod),
+		errors.Is(err, ErrInvalidReading), errors.Is(err, ErrInvalidFuel), errors.Is(err, ErrReadingTooLow):
+		return "invalid_argument"
+	case errors.Is(err, ErrPermissionDenied), errors.Is(err, ErrNotQualified):
+		return "permission_denied"
+	case errors.Is(err, ErrPolicyViolation):
+		return "policy_violation"
+	default:
+		return "internal"
+	}
+}
+
+// CheckTeamAccess returns a *CrossTeamError if the truck is assigned to a
+// team other than the user's.
+func CheckTeamAccess(truck *Truck, userTeam string) error {
+	if truck.DefaultTeam != nil && *truck.DefaultTeam != userTeam {
+		return &CrossTeamError{Truck: truck.Name, TruckTeam: *truck.DefaultTeam, UserTeam: userTeam}
+	}
+	return nil
+}
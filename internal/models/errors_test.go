@@ -0,0 +1,68 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDomainErrors(t *testing.T) {
+	ResetTestDB(t)
+
+	_, err := GetTruckByName("Tulip")
+	if !errors.Is(err, ErrTruckNotFound) {
+		t.Errorf("expected ErrTruckNotFound for missing truck, got %v", err)
+	}
+
+	err = InsertTruck("BananaBoat", nil, uuid.NewString(), false, testActor)
+	if !errors.Is(err, ErrInvalidTruck) {
+		t.Errorf("expected ErrInvalidTruck, got %v", err)
+	}
+
+	team := "not_a_team"
+	err = InsertTruck("Tulip", &team, uuid.NewString(), false, testActor)
+	if !errors.Is(err, ErrInvalidTeam) {
+		t.Errorf("expected ErrInvalidTeam, got %v", err)
+	}
+
+	err = ReleaseTruckFromCheckout(uuid.New(), Actor{SlackUserID: "admin123", Source: SourceSlashCommand})
+	if !errors.Is(err, ErrNoActiveCheckout) {
+		t.Errorf("expected ErrNoActiveCheckout, got %v", err)
+	}
+
+	beltline := "beltline"
+	err = CheckTeamAccess(&Truck{Name: "Tulip", DefaultTeam: &beltline}, "urban_trees")
+	var crossTeam *CrossTeamError
+	if !errors.As(err, &crossTeam) || !errors.Is(err, ErrCrossTeam) {
+		t.Errorf("expected CrossTeamError, got %v", err)
+	}
+	if err := CheckTeamAccess(&Truck{Name: "Libby"}, "urban_trees"); err != nil {
+		t.Errorf("expected unassigned truck to be open to any team, got %v", err)
+	}
+
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{nil, ""},
+		{fmt.Errorf("%w: Tulip", ErrTruckNotFound), "not_found"},
+		{ErrTruckUnavailable, "unavailable"},
+		{ErrCheckoutOverlap, "conflict"},
+		{&CrossTeamError{Truck: "Tulip", TruckTeam: "beltline", UserTeam: "floaters"}, "cross_team"},
+		{fmt.Errorf("%w: nope", ErrInvalidTeam), "invalid_argument"},
+		{ErrPermissionDenied, "permission_denied"},
+		{&PolicyError{Violations: []PolicyViolation{{Rule: RuleBlackout}}}, "policy_violation"},
+		{errors.New("disk on fire"), "internal"},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorCode(tt.err); got != tt.code {
+			t.Errorf("ErrorCode(%v) = %q, want %q", tt.err, got, tt.code)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	db "truck-checkout/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// MaintenanceBlock is a period a truck is out of service, e.g. in the shop.
+type MaintenanceBlock struct {
+	ID        uuid.UUID `json:"id"`
+	TruckID   uuid.UUID `json:"truck_id"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ScheduleMaintenance takes a truck out of service for the block's period.
+// The truck must be free for the whole of it, so bookings in the way have to
+// be cancelled first; the errors are those of CreateCheckout.
+func ScheduleMaintenance(block MaintenanceBlock, actor Actor) (*MaintenanceBlock, error) {
+	if !block.EndDate.After(block.StartDate) {
+		return nil, ErrInvalidPeriod
+	}
+	if block.ID == uuid.Nil {
+		block.ID = uuid.New()
+	}
+	block.CreatedBy = actor.SlackUserID
+	block.CreatedAt = time.Now().UTC()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkTruckFree(tx, block.TruckID, block.StartDate, block.EndDate); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO maintenance_blocks (id, truck_id, start_date, end_date, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, block.ID.String(), block.TruckID.String(), block.StartDate.UTC(), block.EndDate.UTC(),
+		block.CreatedBy, block.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert maintenance block: %w", err)
+	}
+
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionMaintenance,
+		entityType: "maintenance",
+		entityID:   block.ID.String(),
+		truckID:    &block.TruckID,
+		after:      block,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// GetMaintenanceBetween returns every maintenance block that overlaps
+// [from, to), ordered by start.
+func GetMaintenanceBetween(from, to time.Time) ([]MaintenanceBlock, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, truck_id, start_date, end_date, created_by, created_at
+		FROM maintenance_blocks
+		WHERE start_date < ? AND end_date > ?
+		ORDER BY start_date
+	`, to.UTC(), from.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []MaintenanceBlock
+	for rows.Next() {
+		var b MaintenanceBlock
+		if err := rows.Scan(&b.ID, &b.TruckID, &b.StartDate, &b.EndDate, &b.CreatedBy, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, rows.Err()
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestScheduleMaintenance(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	for _, name := range []string{"Tulip", "Watson"} {
+		if err := InsertTruck(name, &team, uuid.NewString(), false, testActor); err != nil {
+			t.Fatalf("failed to insert truck: %v", err)
+		}
+	}
+	tulip, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	booking := Checkout{
+		ID:        uuid.New(),
+		TruckID:   tulip.ID,
+		UserID:    "U111",
+		UserName:  "alice",
+		TeamName:  team,
+		StartDate: start,
+		EndDate:   start.Add(4 * time.Hour),
+	}
+	if err := CreateCheckout(booking, nil, testActor); err != nil {
+		t.Fatalf("failed to create checkout: %v", err)
+	}
+
+	// A booking in the way has to be cancelled first
+	block := MaintenanceBlock{TruckID: tulip.ID, StartDate: start.Add(-time.Hour), EndDate: start.Add(48 * time.Hour)}
+	if _, err := ScheduleMaintenance(block, testActor); !errors.Is(err, ErrCheckoutOverlap) {
+		t.Fatalf("expected ErrCheckoutOverlap, got %v", err)
+	}
+	if _, err := CancelCheckout(booking.ID, testActor); err != nil {
+		t.Fatalf("failed to cancel checkout: %v", err)
+	}
+	if _, err := ScheduleMaintenance(MaintenanceBlock{TruckID: tulip.ID, StartDate: start, EndDate: start}, testActor); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("expected ErrInvalidPeriod for an empty period, got %v", err)
+	}
+	scheduled, err := ScheduleMaintenance(block, testActor)
+	if err != nil {
+		t.Fatalf("failed to schedule maintenance: %v", err)
+	}
+
+	blocks, err := GetMaintenanceBetween(start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to get maintenance: %v", err)
+	}
+	if len(blocks) != 1 || blocks[0].ID != scheduled.ID || blocks[0].CreatedBy != testActor.SlackUserID {
+		t.Errorf("expected the scheduled block, got %+v", blocks)
+	}
+
+	// Nobody can book the truck while it's in the shop, and "any truck"
+	// passes it over.
+	booking.ID = uuid.New()
+	if err := CreateCheckout(booking, nil, testActor); !errors.Is(err, ErrInMaintenance) {
+		t.Errorf("expected ErrInMaintenance, got %v", err)
+	}
+	booking.ID = uuid.New()
+	truck, _, err := CreateCheckoutForAnyTruck(booking, nil, nil, testActor)
+	if err != nil {
+		t.Fatalf("failed to check out any truck: %v", err)
+	}
+	if truck.Name != "Watson" {
+		t.Errorf("expected Watson while Tulip is in the shop, got %s", truck.Name)
+	}
+}
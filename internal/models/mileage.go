@@ -0,0 +1,207 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	db "truck-checkout/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type OdometerKind string
+
+const (
+	OdometerStart OdometerKind = "start"
+	OdometerEnd   OdometerKind = "end"
+)
+
+type OdometerReading struct {
+	ID         uuid.UUID    `json:"id"`
+	CheckoutID uuid.UUID    `json:"checkout_id"`
+	TruckID    uuid.UUID    `json:"truck_id"`
+	Kind       OdometerKind `json:"kind"`
+	Reading    int          `json:"reading"`
+	RecordedBy string       `json:"recorded_by"`
+	RecordedAt time.Time    `json:"recorded_at"`
+}
+
+type FuelPurchase struct {
+	ID          uuid.UUID `json:"id"`
+	CheckoutID  uuid.UUID `json:"checkout_id"`
+	Gallons     float64   `json:"gallons"`
+	CostCents   int64     `json:"cost_cents"`
+	ReceiptNote string    `json:"receipt_note,omitempty"`
+	PurchasedBy string    `json:"purchased_by"`
+	PurchasedAt time.Time `json:"purchased_at"`
+}
+
+// TeamUsage is one row of the monthly fuel charge-back report.
+type TeamUsage struct {
+	TeamName      string  `json:"team_name"`
+	Checkouts     int     `json:"checkouts"`
+	Miles         int     `json:"miles"`
+	FuelGallons   float64 `json:"fuel_gallons"`
+	FuelCostCents int64   `json:"fuel_cost_cents"`
+}
+
+// RecordOdometerReading stores a start or end reading against a checkout.
+// Readings lower than the last value recorded for the same truck are rejected.
+func RecordOdometerReading(checkoutID uuid.UUID, kind OdometerKind, reading int, recordedBy string) error {
+	if kind != OdometerStart && kind != OdometerEnd {
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidReading, kind)
+	}
+	if reading < 0 {
+		return fmt.Errorf("%w: reading cannot be negative", ErrInvalidReading)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var truckID string
+	err = tx.QueryRow(`SELECT truck_id FROM checkouts WHERE id = ?`, checkoutID.String()).Scan(&truckID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrCheckoutNotFound
+		}
+		return fmt.Errorf("failed to find checkout: %w", err)
+	}
+
+	var existing int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM odometer_readings WHERE checkout_id = ? AND kind = ?
+	`, checkoutID.String(), kind).Scan(&existing)
+	if err != nil {
+		return fmt.Errorf("failed to check existing readings: %w", err)
+	}
+	if existing > 0 {
+		return ErrReadingRecorded
+	}
+
+	var last sql.NullInt64
+	err = tx.QueryRow(`SELECT MAX(reading) FROM odometer_readings WHERE truck_id = ?`, truckID).Scan(&last)
+	if err != nil {
+		return fmt.Errorf("failed to find last odometer reading: %w", err)
+	}
+	if last.Valid && int64(reading) < last.Int64 {
+		return &ReadingTooLowError{Reading: reading, Last: int(last.Int64)}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO odometer_readings (id, checkout_id, truck_id, kind, reading, recorded_by, recorded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), checkoutID.String(), truckID, kind, reading, recordedBy, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert odometer reading: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetLastOdometerReading returns the highest reading recorded for a truck.
+// The boolean is false when the truck has no readings yet.
+func GetLastOdometerReading(truckID uuid.UUID) (int, bool, error) {
+	var last sql.NullInt64
+	err := db.DB.QueryRow(`SELECT MAX(reading) FROM odometer_readings WHERE truck_id = ?`, truckID.String()).Scan(&last)
+	if err != nil {
+		return 0, false, err
+	}
+	return int(last.Int64), last.Valid, nil
+}
+
+func GetOdometerReadingsByCheckoutID(checkoutID uuid.UUID) ([]OdometerReading, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, checkout_id, truck_id, kind, reading, recorded_by, recorded_at
+		FROM odometer_readings
+		WHERE checkout_id = ?
+		ORDER BY reading
+	`, checkoutID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var readings []OdometerReading
+	for rows.Next() {
+		var r OdometerReading
+		if err := rows.Scan(&r.ID, &r.CheckoutID, &r.TruckID, &r.Kind, &r.Reading, &r.RecordedBy, &r.RecordedAt); err != nil {
+			return nil, err
+		}
+		readings = append(readings, r)
+	}
+	return readings, rows.Err()
+}
+
+func RecordFuelPurchase(purchase FuelPurchase) error {
+	if purchase.Gallons <= 0 {
+		return fmt.Errorf("%w: gallons must be greater than zero", ErrInvalidFuel)
+	}
+	if purchase.CostCents < 0 {
+		return fmt.Errorf("%w: cost cannot be negative", ErrInvalidFuel)
+	}
+	if purchase.ID == uuid.Nil {
+		purchase.ID = uuid.New()
+	}
+	if purchase.PurchasedAt.IsZero() {
+		purchase.PurchasedAt = time.Now()
+	}
+
+	_, err := db.DB.Exec(`
+		INSERT INTO fuel_purchases (id, checkout_id, gallons, cost_cents, receipt_note, purchased_by, purchased_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, purchase.ID.String(), purchase.CheckoutID.String(), purchase.Gallons, purchase.CostCents,
+		purchase.ReceiptNote, purchase.PurchasedBy, purchase.PurchasedAt.UTC())
+	return err
+}
+
+// GetTeamUsageReport totals miles driven and fuel bought per team for
+// checkouts that started in the given month. Miles only count for checkouts
+// that have both a start and an end reading.
+func GetTeamUsageReport(year int, month time.Month, loc *time.Location) ([]TeamUsage, error) {
+	from := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 1, 0)
+
+	rows, err := db.DB.Query(`
+		SELECT c.team_name,
+		       COUNT(c.id),
+		       COALESCE(SUM(m.miles), 0),
+		       COALESCE(SUM(f.gallons), 0),
+		       COALESCE(SUM(f.cost_cents), 0)
+		FROM checkouts c
+		LEFT JOIN (
+			SELECT s.checkout_id, e.reading - s.reading AS miles
+			FROM odometer_readings s
+			JOIN odometer_readings e ON e.checkout_id = s.checkout_id AND e.kind = 'end'
+			WHERE s.kind = 'start'
+		) m ON m.checkout_id = c.id
+		LEFT JOIN (
+			SELECT checkout_id, SUM(gallons) AS gallons, SUM(cost_cents) AS cost_cents
+			FROM fuel_purchases
+			GROUP BY checkout_id
+		) f ON f.checkout_id = c.id
+		WHERE c.start_date >= ? AND c.start_date < ? AND c.cancelled_at IS NULL
+		GROUP BY c.team_name
+		ORDER BY c.team_name
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("querying team usage: %w", err)
+	}
+	defer rows.Close()
+
+	var report []TeamUsage
+	for rows.Next() {
+		var u TeamUsage
+		if err := rows.Scan(&u.TeamName, &u.Checkouts, &u.Miles, &u.FuelGallons, &u.FuelCostCents); err != nil {
+			return nil, fmt.Errorf("scanning team usage row: %w", err)
+		}
+		report = append(report, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("after row iteration: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func createMileageCheckout(t *testing.T, truck *Truck, team string, start time.Time) Checkout {
+	t.Helper()
+	checkout := Checkout{
+		ID:        uuid.New(),
+		TruckID:   truck.ID,
+		UserID:    "user123",
+		UserName:  "John Doe",
+		TeamName:  team,
+		StartDate: start,
+		EndDate:   start.Add(8 * time.Hour),
+		Purpose:   "Mileage test",
+	}
+	if err := CreateCheckout(checkout, nil, testActor); err != nil {
+		t.Fatalf("failed to insert checkout: %v", err)
+	}
+	return checkout
+}
+
+func TestRecordOdometerReading(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	checkout := createMileageCheckout(t, truck, "beltline", time.Now().Add(-1*time.Hour))
+
+	if err := RecordOdometerReading(checkout.ID, OdometerStart, 48210, "user123"); err != nil {
+		t.Fatalf("failed to record start reading: %v", err)
+	}
+
+	// A second start reading for the same checkout is rejected
+	if err := RecordOdometerReading(checkout.ID, OdometerStart, 48220, "user123"); !errors.Is(err, ErrReadingRecorded) {
+		t.Errorf("expected ErrReadingRecorded for a duplicate start reading, got %v", err)
+	}
+
+	// An end reading below the last recorded value is rejected
+	err = RecordOdometerReading(checkout.ID, OdometerEnd, 48100, "user123")
+	if err == nil {
+		t.Fatal("expected error for odometer reading lower than last recorded value")
+	}
+	var tooLow *ReadingTooLowError
+	if !errors.As(err, &tooLow) || tooLow.Last != 48210 {
+		t.Errorf("expected ReadingTooLowError against 48210, got %v", err)
+	}
+	if code := ErrorCode(err); code != "invalid_argument" {
+		t.Errorf("expected invalid_argument code, got %q", code)
+	}
+
+	if err := RecordOdometerReading(checkout.ID, OdometerEnd, 48262, "user123"); err != nil {
+		t.Fatalf("failed to record end reading: %v", err)
+	}
+
+	last, ok, err := GetLastOdometerReading(truck.ID)
+	if err != nil {
+		t.Fatalf("failed to get last reading: %v", err)
+	}
+	if !ok || last != 48262 {
+		t.Errorf("expected last reading 48262, got %d (ok=%v)", last, ok)
+	}
+
+	readings, err := GetOdometerReadingsByCheckoutID(checkout.ID)
+	if err != nil {
+		t.Fatalf("failed to get readings: %v", err)
+	}
+	if len(readings) != 2 {
+		t.Errorf("expected 2 readings, got %d", len(readings))
+	}
+
+	// The next checkout of the same truck cannot start below the previous end
+	next := createMileageCheckout(t, truck, "floaters", checkout.EndDate)
+	if err := RecordOdometerReading(next.ID, OdometerStart, 48000, "user456"); err == nil {
+		t.Error("expected error for start reading below previous checkout's end reading")
+	}
+}
+
+func TestRecordOdometerReading_UnknownCheckout(t *testing.T) {
+	ResetTestDB(t)
+
+	if err := RecordOdometerReading(uuid.New(), OdometerStart, 100, "user123"); !errors.Is(err, ErrCheckoutNotFound) {
+		t.Errorf("expected ErrCheckoutNotFound for a non-existent checkout, got %v", err)
+	}
+}
+
+func TestRecordFuelPurchase_Invalid(t *testing.T) {
+	ResetTestDB(t)
+
+	if err := RecordFuelPurchase(FuelPurchase{CheckoutID: uuid.New(), Gallons: 0, CostCents: 100}); !errors.Is(err, ErrInvalidFuel) {
+		t.Error("expected error for zero gallons")
+	}
+	if err := RecordFuelPurchase(FuelPurchase{CheckoutID: uuid.New(), Gallons: 3, CostCents: -1}); !errors.Is(err, ErrInvalidFuel) {
+		t.Error("expected error for negative cost")
+	}
+}
+
+func TestGetTeamUsageReport(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Watson", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Watson")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	october := time.Date(2026, time.October, 6, 7, 0, 0, 0, time.Local)
+	first := createMileageCheckout(t, truck, "beltline", october)
+	second := createMileageCheckout(t, truck, "beltline", october.AddDate(0, 0, 1))
+	third := createMileageCheckout(t, truck, "urban_trees", october.AddDate(0, 0, 2))
+	// Outside the reporting month
+	november := createMileageCheckout(t, truck, "urban_trees", october.AddDate(0, 1, 0))
+
+	readings := []struct {
+		checkout Checkout
+		start    int
+		end      int
+	}{
+		{first, 1000, 1040},
+		{second, 1040, 1100},
+		{third, 1100, 1125},
+		{november, 1125, 1500},
+	}
+	for _, r := range readings {
+		if err := RecordOdometerReading(r.checkout.ID, OdometerStart, r.start, "user123"); err != nil {
+			t.Fatalf("failed to record start reading: %v", err)
+		}
+		if err := RecordOdometerReading(r.checkout.ID, OdometerEnd, r.end, "user123"); err != nil {
+			t.Fatalf("failed to record end reading: %v", err)
+		}
+	}
+
+	purchases := []FuelPurchase{
+		{CheckoutID: first.ID, Gallons: 10, CostCents: 3500, PurchasedBy: "user123"},
+		{CheckoutID: first.ID, Gallons: 2.5, CostCents: 900, PurchasedBy: "user123"},
+		{CheckoutID: third.ID, Gallons: 5, CostCents: 1750, ReceiptNote: "Shell on Ponce", PurchasedBy: "user456"},
+		{CheckoutID: november.ID, Gallons: 20, CostCents: 7000, PurchasedBy: "user456"},
+	}
+	for _, p := range purchases {
+		if err := RecordFuelPurchase(p); err != nil {
+			t.Fatalf("failed to record fuel purchase: %v", err)
+		}
+	}
+
+	report, err := GetTeamUsageReport(2026, time.October, time.Local)
+	if err != nil {
+		t.Fatalf("failed to build report: %v", err)
+	}
+
+	expected := []TeamUsage{
+		{TeamName: "beltline", Checkouts: 2, Miles: 100, FuelGallons: 12.5, FuelCostCents: 4400},
+		{TeamName: "urban_trees", Checkouts: 1, Miles: 25, FuelGallons: 5, FuelCostCents: 1750},
+	}
+	if len(report) != len(expected) {
+		t.Fatalf("expected %d report rows, got %d: %+v", len(expected), len(report), report)
+	}
+	for i, want := range expected {
+		if report[i] != want {
+			t.Errorf("row %d: expected %+v, got %+v", i, want, report[i])
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import "fmt"
+
+// IsFleetAdmin reports whether the user can manage the fleet and override
+// any checkout.
+func (u *User) IsFleetAdmin() bool {
+	return u != nil && u.Role == RoleFleetAdmin
+}
+
+// LeadsTeam reports whether the user is the team lead of team.
+func (u *User) LeadsTeam(team string) bool {
+	return u != nil && u.Role == RoleTeamLead && u.Team == team
+}
+
+// AuthorizeCheckoutChange decides whether actor may release, extend or cancel
+// the checkout. Only the holder, the lead of the checkout's team or a fleet
+// admin may; anyone else gets ErrPermissionDenied. override is true when the
+// actor is not the holder, so callers can record that they stepped in.
+func AuthorizeCheckoutChange(actor *User, checkout *Checkout) (override bool, err error) {
+	if actor != nil && actor.SlackUserID == checkout.UserID {
+		return false, nil
+	}
+	if actor.IsFleetAdmin() || actor.LeadsTeam(checkout.TeamName) {
+		return true, nil
+	}
+	return false, fmt.Errorf("%w: checkout belongs to %s", ErrPermissionDenied, checkout.UserName)
+}
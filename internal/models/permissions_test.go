@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAuthorizeCheckoutChange(t *testing.T) {
+	checkout := &Checkout{UserID: "U_HOLDER", UserName: "Holder", TeamName: "beltline"}
+
+	tests := []struct {
+		name         string
+		actor        *User
+		wantOverride bool
+		wantErr      bool
+	}{
+		{"holder", &User{SlackUserID: "U_HOLDER", Team: "beltline", Role: RoleMember}, false, false},
+		{"teammate", &User{SlackUserID: "U_MATE", Team: "beltline", Role: RoleMember}, false, true},
+		{"team lead", &User{SlackUserID: "U_LEAD", Team: "beltline", Role: RoleTeamLead}, true, false},
+		{"other team's lead", &User{SlackUserID: "U_LEAD2", Team: "education", Role: RoleTeamLead}, false, true},
+		{"fleet admin", &User{SlackUserID: "U_ADMIN", Team: "admin", Role: RoleFleetAdmin}, true, false},
+		{"no profile", nil, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			override, err := AuthorizeCheckoutChange(tt.actor, checkout)
+			if tt.wantErr {
+				if !errors.Is(err, ErrPermissionDenied) {
+					t.Errorf("expected ErrPermissionDenied, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected to be allowed, got %v", err)
+			}
+			if override != tt.wantOverride {
+				t.Errorf("override = %v, want %v", override, tt.wantOverride)
+			}
+		})
+	}
+}
+
+func TestSetUserRole(t *testing.T) {
+	ResetTestDB(t)
+
+	if _, err := CreateUser("U123456", "testuser", "beltline", testActor); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	user, err := GetUserBySlackID("U123456")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if user.Role != RoleMember {
+		t.Errorf("expected new users to be members, got %q", user.Role)
+	}
+
+	role, ok := ParseRole("lead")
+	if !ok {
+		t.Fatal("expected lead to parse")
+	}
+	if err := SetUserRole("U123456", role, testActor); err != nil {
+		t.Fatalf("failed to set role: %v", err)
+	}
+	user, err = GetUserBySlackID("U123456")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if !user.LeadsTeam("beltline") || user.IsFleetAdmin() {
+		t.Errorf("expected beltline team lead, got %+v", user)
+	}
+
+	if err := SetUserRole("U_NOBODY", RoleFleetAdmin, testActor); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
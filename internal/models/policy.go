@@ -0,0 +1,377 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	db "truck-checkout/internal/database"
+)
+
+// PolicyRule names one rule of the checkout policy.
+type PolicyRule string
+
+const (
+	RuleMaxDuration     PolicyRule = "max_duration"
+	RuleTeamQuota       PolicyRule = "team_weekly_quota"
+	RuleBlackout        PolicyRule = "blackout_date"
+	RuleOneTruckPerUser PolicyRule = "one_truck_per_user"
+	RuleAdvanceNotice   PolicyRule = "advance_notice"
+)
+
+var policyRules = []PolicyRule{RuleMaxDuration, RuleTeamQuota, RuleBlackout, RuleOneTruckPerUser, RuleAdvanceNotice}
+
+// TruckPolicy holds rules for a single truck.
+type TruckPolicy struct {
+	// MaxDays overrides the fleet-wide maximum checkout length when set.
+	MaxDays int `yaml:"max_days" toml:"max_days"`
+	// MinNoticeHours is how far ahead the checkout must be booked.
+	MinNoticeHours int `yaml:"min_notice_hours" toml:"min_notice_hours"`
+}
+
+// CheckoutPolicy is the declarative set of rules every checkout is evaluated
+// against, e.g. in YAML:
+//
+//	policy:
+//	  one_truck_per_user: true
+//	  team_weekly_quota: {beltline: 5}
+//	  blackout_dates: ["2026-12-24"]
+//	  trucks:
+//	    Tulip: {max_days: 3, min_notice_hours: 24}
+//	  overridable: [max_duration, team_weekly_quota]
+type CheckoutPolicy struct {
+	// MaxDays is the fleet-wide maximum checkout length in business days.
+	// It comes from the top-level max_checkout_days setting.
+	MaxDays int `yaml:"-" toml:"-"`
+
+	Trucks          map[string]TruckPolicy `yaml:"trucks" toml:"trucks"`
+	TeamWeeklyQuota map[string]int         `yaml:"team_weekly_quota" toml:"team_weekly_quota"`
+	BlackoutDates   []string               `yaml:"blackout_dates" toml:"blackout_dates"`
+	OneTruckPerUser bool                   `yaml:"one_truck_per_user" toml:"one_truck_per_user"`
+	// Overridable lists the rules an admin may override.
+	Overridable []PolicyRule `yaml:"overridable" toml:"overridable"`
+}
+
+// CheckoutRequest describes a checkout for policy evaluation. TruckName is
+// empty when the user asked for any truck. Start and End should be in the
+// organization time zone, which is used for blackout dates and weeks.
+type CheckoutRequest struct {
+	TruckName    string
+	UserID       string
+	TeamName     string
+	Start        time.Time
+	End          time.Time
+	BusinessDays int
+	Now          time.Time
+	// Override asks to skip the overridable rules. Callers must only set it
+	// for admins.
+	Override bool
+}
+
+// PolicyViolation is one reason a checkout was refused, in a form a front
+// end can display as-is.
+type PolicyViolation struct {
+	Rule        PolicyRule
+	Message     string
+	Overridable bool
+}
+
+// Validate checks the policy for malformed dates, negative limits and
+// unknown rule names.
+func (p *CheckoutPolicy) Validate() error {
+	for _, d := range p.BlackoutDates {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return fmt.Errorf("invalid blackout date %q, expected YYYY-MM-DD", d)
+		}
+	}
+	for team, quota := range p.TeamWeeklyQuota {
+		if quota < 0 {
+			return fmt.Errorf("weekly quota for %s must not be negative", team)
+		}
+	}
+	for name, t := range p.Trucks {
+		if t.MaxDays < 0 || t.MinNoticeHours < 0 {
+			return fmt.Errorf("rules for truck %s must not be negative", name)
+		}
+	}
+	for _, rule := range p.Overridable {
+		if !isPolicyRule(rule) {
+			return fmt.Errorf("unknown overridable rule %q", rule)
+		}
+	}
+	return nil
+}
+
+func isPolicyRule(rule PolicyRule) bool {
+	for _, r := range policyRules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CheckoutPolicy) overridable(rule PolicyRule) bool {
+	for _, r := range p.Overridable {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CheckoutPolicy) truckPolicy(name string) (TruckPolicy, bool) {
+	for truck, rules := range p.Trucks {
+		if strings.EqualFold(truck, name) {
+			return rules, true
+		}
+	}
+	return TruckPolicy{}, false
+}
+
+// Check evaluates the request and returns a *PolicyError listing every
+// violation, or nil if the checkout is allowed. With req.Override set,
+// overridable violations are ignored.
+func (p *CheckoutPolicy) Check(req CheckoutRequest) error {
+	violations, err := p.Evaluate(req)
+	if err != nil {
+		return err
+	}
+	if blocking, _ := splitOverridden(violations, req.Override); len(blocking) > 0 {
+		return &PolicyError{Violations: blocking}
+	}
+	return nil
+}
+
+// splitOverridden separates the violations that block a request from those
+// let through because override is set.
+func splitOverridden(violations []PolicyViolation, override bool) (blocking, overridden []PolicyViolation) {
+	for _, v := range violations {
+		if override && v.Overridable {
+			overridden = append(overridden, v)
+			continue
+		}
+		blocking = append(blocking, v)
+	}
+	return blocking, overridden
+}
+
+// PolicyCheck is the checkout policy to enforce on a new checkout and the
+// request to evaluate it for. The create functions evaluate it inside their
+// transaction, so two checkouts made at once can't both slip under a quota
+// or the one-truck-per-user rule.
+type PolicyCheck struct {
+	Policy  *CheckoutPolicy
+	Request CheckoutRequest
+	// Overridden lists the violations Request.Override let through. The
+	// create functions fill it in; each one is audited as an override of
+	// the checkout it was let through for.
+	Overridden []PolicyViolation
+}
+
+// enforce evaluates the check for checkout inside tx, with the request's
+// dates taken from the checkout. It returns a *PolicyError for blocking
+// violations.
+func (c *PolicyCheck) enforce(tx *sql.Tx, checkout Checkout, actor Actor) error {
+	if c == nil || c.Policy == nil {
+		return nil
+	}
+	req := c.Request
+	req.Start, req.End = checkout.StartDate, checkout.EndDate
+
+	violations, err := c.Policy.evaluate(tx, req)
+	if err != nil {
+		return err
+	}
+	blocking, overridden := splitOverridden(violations, req.Override)
+	if len(blocking) > 0 {
+		return &PolicyError{Violations: blocking}
+	}
+	if len(overridden) == 0 {
+		return nil
+	}
+	c.Overridden = append(c.Overridden, overridden...)
+	return writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionOverride,
+		entityType: "checkout",
+		entityID:   checkout.ID.String(),
+		truckID:    &checkout.TruckID,
+		after:      overridden,
+	})
+}
+
+// CheckExtension checks a checkout being extended to req.End. Only the
+// maximum length and blackout dates apply; quotas, advance notice and one
+// truck per user were settled when it was booked.
+func (p *CheckoutPolicy) CheckExtension(req CheckoutRequest) error {
+	violations, err := p.Evaluate(req)
+	if err != nil {
+		return err
+	}
+
+	var blocking []PolicyViolation
+	for _, v := range violations {
+		if v.Rule != RuleMaxDuration && v.Rule != RuleBlackout {
+			continue
+		}
+		if req.Override && v.Overridable {
+			continue
+		}
+		blocking = append(blocking, v)
+	}
+	if len(blocking) > 0 {
+		return &PolicyError{Violations: blocking}
+	}
+	return nil
+}
+
+// Evaluate returns every rule the request breaks. For any-truck requests
+// the per-truck rules are skipped; use ExcludedTrucks to keep those trucks
+// out of the selection instead.
+func (p *CheckoutPolicy) Evaluate(req CheckoutRequest) ([]PolicyViolation, error) {
+	return p.evaluate(db.DB, req)
+}
+
+// queryer is a database or transaction to count existing checkouts in.
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func (p *CheckoutPolicy) evaluate(q queryer, req CheckoutRequest) ([]PolicyViolation, error) {
+	var violations []PolicyViolation
+	add := func(rule PolicyRule, format string, args ...any) {
+		violations = append(violations, PolicyViolation{
+			Rule:        rule,
+			Message:     fmt.Sprintf(format, args...),
+			Overridable: p.overridable(rule),
+		})
+	}
+
+	maxDays := p.MaxDays
+	if req.TruckName != "" {
+		if rules, ok := p.truckPolicy(req.TruckName); ok {
+			if rules.MaxDays > 0 {
+				maxDays = rules.MaxDays
+			}
+			if v, ok := p.noticeViolation(rules, req); ok {
+				add(RuleAdvanceNotice, "%s", v)
+			}
+		}
+	}
+	if maxDays > 0 && req.BusinessDays > maxDays {
+		add(RuleMaxDuration, "Maximum checkout period is %d business days.", maxDays)
+	}
+
+	for d := dateOnly(req.Start); d.Before(req.End); d = d.AddDate(0, 0, 1) {
+		day := d.Format("2006-01-02")
+		if containsString(p.BlackoutDates, day) {
+			add(RuleBlackout, "%s is a blackout date.", d.Format("Mon Jan 2"))
+		}
+	}
+
+	if quota, ok := p.TeamWeeklyQuota[req.TeamName]; ok {
+		weekStart := startOfWeek(req.Start)
+		used, err := countTeamCheckoutsBetween(q, req.TeamName, weekStart, weekStart.AddDate(0, 0, 7))
+		if err != nil {
+			return nil, err
+		}
+		if used >= quota {
+			add(RuleTeamQuota, "The %s team has used all %d checkouts for the week of %s.", req.TeamName, quota, weekStart.Format("Jan 2"))
+		}
+	}
+
+	if p.OneTruckPerUser {
+		open, err := countOpenCheckoutsForUser(q, req.UserID, req.Start, req.End)
+		if err != nil {
+			return nil, err
+		}
+		if open > 0 {
+			add(RuleOneTruckPerUser, "You already have a truck checked out for that period.")
+		}
+	}
+
+	return violations, nil
+}
+
+// ExcludedTrucks returns the names of trucks whose own rules reject the
+// request, so an any-truck checkout can skip them. Overridable rules are
+// ignored when req.Override is set.
+func (p *CheckoutPolicy) ExcludedTrucks(req CheckoutRequest) []string {
+	var excluded []string
+	for name, rules := range p.Trucks {
+		tooLong := rules.MaxDays > 0 && req.BusinessDays > rules.MaxDays &&
+			!(req.Override && p.overridable(RuleMaxDuration))
+		_, tooSoon := p.noticeViolation(rules, req)
+		tooSoon = tooSoon && !(req.Override && p.overridable(RuleAdvanceNotice))
+		if tooLong || tooSoon {
+			excluded = append(excluded, name)
+		}
+	}
+	return excluded
+}
+
+func (p *CheckoutPolicy) noticeViolation(rules TruckPolicy, req CheckoutRequest) (string, bool) {
+	if rules.MinNoticeHours == 0 {
+		return "", false
+	}
+	notice := time.Duration(rules.MinNoticeHours) * time.Hour
+	if req.Start.Sub(req.Now) >= notice {
+		return "", false
+	}
+	return fmt.Sprintf("Truck %s must be booked at least %d hours in advance.", req.TruckName, rules.MinNoticeHours), true
+}
+
+// CountTeamCheckoutsBetween counts the team's checkouts starting in [from, to).
+func CountTeamCheckoutsBetween(teamName string, from, to time.Time) (int, error) {
+	return countTeamCheckoutsBetween(db.DB, teamName, from, to)
+}
+
+func countTeamCheckoutsBetween(q queryer, teamName string, from, to time.Time) (int, error) {
+	var count int
+	err := q.QueryRow(`
+		SELECT COUNT(*) FROM checkouts
+		WHERE team_name = ? AND start_date >= ? AND start_date < ? AND cancelled_at IS NULL
+	`, teamName, from.UTC(), to.UTC()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count team checkouts: %w", err)
+	}
+	return count, nil
+}
+
+// CountOpenCheckoutsForUser counts the user's unreleased checkouts that
+// overlap [start, end).
+func CountOpenCheckoutsForUser(userID string, start, end time.Time) (int, error) {
+	return countOpenCheckoutsForUser(db.DB, userID, start, end)
+}
+
+func countOpenCheckoutsForUser(q queryer, userID string, start, end time.Time) (int, error) {
+	var count int
+	err := q.QueryRow(`
+		SELECT COUNT(*) FROM checkouts
+		WHERE user_id = ? AND start_date < ? AND end_date > ? AND released_at IS NULL AND cancelled_at IS NULL
+	`, userID, end.UTC(), start.UTC()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count user checkouts: %w", err)
+	}
+	return count, nil
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns midnight on the Monday of t's week.
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return dateOnly(t).AddDate(0, 0, -offset)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckoutPolicy_Evaluate(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	if err := InsertTruck("Libby", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	libby, err := GetTruckByName("Libby")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	// One existing beltline checkout on Monday Oct 19, held by user123
+	existing := Checkout{
+		ID:        uuid.New(),
+		TruckID:   libby.ID,
+		UserID:    "user123",
+		UserName:  "John Doe",
+		TeamName:  "beltline",
+		StartDate: time.Date(2026, time.October, 19, 7, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2026, time.October, 21, 15, 30, 0, 0, time.UTC),
+	}
+	if err := CreateCheckout(existing, nil, testActor); err != nil {
+		t.Fatalf("failed to insert checkout: %v", err)
+	}
+
+	policy := &CheckoutPolicy{
+		MaxDays:         6,
+		Trucks:          map[string]TruckPolicy{"tulip": {MaxDays: 2, MinNoticeHours: 24}},
+		TeamWeeklyQuota: map[string]int{"beltline": 1},
+		BlackoutDates:   []string{"2026-10-21"},
+		OneTruckPerUser: true,
+		Overridable:     []PolicyRule{RuleMaxDuration, RuleTeamQuota},
+	}
+
+	tuesday := time.Date(2026, time.October, 20, 7, 0, 0, 0, time.UTC)
+	request := func(truck, user string, days int, now time.Time) CheckoutRequest {
+		return CheckoutRequest{
+			TruckName:    truck,
+			UserID:       user,
+			TeamName:     "beltline",
+			Start:        tuesday,
+			End:          tuesday.AddDate(0, 0, days-1).Add(8*time.Hour + 30*time.Minute),
+			BusinessDays: days,
+			Now:          now,
+		}
+	}
+
+	tests := []struct {
+		name string
+		req  CheckoutRequest
+		want []PolicyRule
+	}{
+		{
+			name: "every rule broken",
+			req:  request("Tulip", "user123", 3, tuesday.Add(-time.Hour)),
+			want: []PolicyRule{RuleAdvanceNotice, RuleMaxDuration, RuleBlackout, RuleTeamQuota, RuleOneTruckPerUser},
+		},
+		{
+			name: "fleet-wide limit for trucks without their own",
+			req:  request("Libby", "user456", 7, tuesday.Add(-48*time.Hour)),
+			want: []PolicyRule{RuleMaxDuration, RuleBlackout, RuleTeamQuota},
+		},
+		{
+			name: "any truck skips per-truck rules",
+			req:  request("", "user456", 1, tuesday.Add(-time.Hour)),
+			want: []PolicyRule{RuleTeamQuota},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := policy.Evaluate(tt.req)
+			if err != nil {
+				t.Fatalf("failed to evaluate policy: %v", err)
+			}
+			if len(violations) != len(tt.want) {
+				t.Fatalf("expected %d violations, got %+v", len(tt.want), violations)
+			}
+			for i, rule := range tt.want {
+				if violations[i].Rule != rule {
+					t.Errorf("violation %d = %s, want %s", i, violations[i].Rule, rule)
+				}
+				if violations[i].Message == "" {
+					t.Errorf("violation %d has no message", i)
+				}
+			}
+		})
+	}
+
+	// An override drops only the overridable rules
+	req := request("", "user456", 1, tuesday.Add(-time.Hour))
+	err = policy.Check(req)
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) || !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("expected PolicyError, got %v", err)
+	}
+	if !policyErr.Violations[0].Overridable {
+		t.Error("expected the team quota to be overridable")
+	}
+	req.Override = true
+	if err := policy.Check(req); err != nil {
+		t.Errorf("expected override to allow checkout, got %v", err)
+	}
+
+	if excluded := policy.ExcludedTrucks(request("", "user456", 1, tuesday.Add(-time.Hour))); len(excluded) != 1 || excluded[0] != "tulip" {
+		t.Errorf("expected Tulip to be excluded for short notice, got %v", excluded)
+	}
+
+	// Extending user123's own checkout only trips the length and blackout
+	// rules, not the quota or one-truck rule it already counts towards
+	err = policy.CheckExtension(request("Libby", "user123", 7, tuesday.Add(-48*time.Hour)))
+	if !errors.As(err, &policyErr) || len(policyErr.Violations) != 2 ||
+		policyErr.Violations[0].Rule != RuleMaxDuration || policyErr.Violations[1].Rule != RuleBlackout {
+		t.Errorf("expected max duration and blackout violations, got %v", err)
+	}
+}
+
+func TestCreateCheckout_EnforcesPolicy(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	for _, name := range []string{"Tulip", "Libby"} {
+		if err := InsertTruck(name, &team, uuid.NewString(), false, testActor); err != nil {
+			t.Fatalf("failed to insert truck: %v", err)
+		}
+	}
+	tulip, _ := GetTruckByName("Tulip")
+	libby, _ := GetTruckByName("Libby")
+
+	monday := time.Date(2026, time.October, 19, 7, 0, 0, 0, time.UTC)
+	checkout := func(truckID uuid.UUID) Checkout {
+		return Checkout{
+			ID:        uuid.New(),
+			TruckID:   truckID,
+			UserID:    "user123",
+			UserName:  "John Doe",
+			TeamName:  "beltline",
+			StartDate: monday,
+			EndDate:   monday.Add(8*time.Hour + 30*time.Minute),
+		}
+	}
+	check := func(override bool) *PolicyCheck {
+		return &PolicyCheck{
+			Policy: &CheckoutPolicy{
+				OneTruckPerUser: true,
+				Overridable:     []PolicyRule{RuleOneTruckPerUser},
+			},
+			Request: CheckoutRequest{UserID: "user123", TeamName: "beltline", BusinessDays: 1, Now: monday, Override: override},
+		}
+	}
+
+	if err := CreateCheckout(checkout(tulip.ID), check(false), testActor); err != nil {
+		t.Fatalf("failed to create first checkout: %v", err)
+	}
+
+	// The second truck counts the first checkout, made in its own transaction
+	var policyErr *PolicyError
+	if err := CreateCheckout(checkout(libby.ID), check(false), testActor); !errors.As(err, &policyErr) || policyErr.Violations[0].Rule != RuleOneTruckPerUser {
+		t.Fatalf("expected a one-truck-per-user violation, got %v", err)
+	}
+	if open, err := CountOpenCheckoutsForUser("user123", monday, monday.Add(time.Hour)); err != nil || open != 1 {
+		t.Fatalf("expected the rejected checkout not to be inserted, got %d (%v)", open, err)
+	}
+
+	// An override lets it through and is audited against the new checkout
+	overridden := checkout(libby.ID)
+	c := check(true)
+	if err := CreateCheckout(overridden, c, testActor); err != nil {
+		t.Fatalf("expected the override to allow the checkout, got %v", err)
+	}
+	if len(c.Overridden) != 1 || c.Overridden[0].Rule != RuleOneTruckPerUser {
+		t.Errorf("expected the one-truck rule to be overridden, got %v", c.Overridden)
+	}
+	events, err := GetAuditEventsByTruckID(libby.ID, 10)
+	if err != nil {
+		t.Fatalf("failed to load audit events: %v", err)
+	}
+	var audited bool
+	for _, e := range events {
+		if e.Action == ActionOverride && e.EntityID == overridden.ID.String() {
+			audited = true
+		}
+	}
+	if !audited {
+		t.Errorf("expected an override audit event for %s, got %+v", overridden.ID, events)
+	}
+}
+
+func TestCheckoutPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy CheckoutPolicy
+	}{
+		{"bad blackout date", CheckoutPolicy{BlackoutDates: []string{"Dec 24"}}},
+		{"negative quota", CheckoutPolicy{TeamWeeklyQuota: map[string]int{"beltline": -1}}},
+		{"unknown rule", CheckoutPolicy{Overridable: []PolicyRule{"be_nice"}}},
+	}
+	for _, tt := range tests {
+		if err := tt.policy.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", tt.name)
+		}
+	}
+
+	valid := CheckoutPolicy{BlackoutDates: []string{"2026-12-24"}, Overridable: []PolicyRule{RuleBlackout}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid policy, got %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	db "truck-checkout/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// QualificationKind is what a driver qualification record covers.
+type QualificationKind string
+
+const (
+	// QualificationLicense is the driver's license our insurer needs on
+	// file for every checkout.
+	QualificationLicense QualificationKind = "license"
+	// QualificationCertification allows driving one class of truck, such as
+	// Andre350 with the dump bed.
+	QualificationCertification QualificationKind = "certification"
+)
+
+// Qualification is a driver's license or certification and when it expires.
+// TruckClass is empty for a license.
+type Qualification struct {
+	ID          uuid.UUID         `json:"id"`
+	SlackUserID string            `json:"slack_user_id"`
+	Kind        QualificationKind `json:"kind"`
+	TruckClass  string            `json:"truck_class,omitempty"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+	RecordedBy  string            `json:"recorded_by"`
+	RecordedAt  time.Time         `json:"recorded_at"`
+	RemindedAt  *time.Time        `json:"reminded_at,omitempty"`
+}
+
+// Label is the qualification's name for display, e.g. "dump certification".
+func (q *Qualification) Label() string {
+	if q.Kind == QualificationCertification {
+		return q.TruckClass + " certification"
+	}
+	return "driver's license"
+}
+
+const qualificationColumns = `id, slack_user_id, kind, truck_class, expires_at, recorded_by, recorded_at, reminded_at`
+
+func scanQualification(row rowScanner) (*Qualification, error) {
+	var q Qualification
+	var remindedAt sql.NullTime
+	if err := row.Scan(&q.ID, &q.SlackUserID, &q.Kind, &q.TruckClass, &q.ExpiresAt, &q.RecordedBy, &q.RecordedAt, &remindedAt); err != nil {
+		return nil, err
+	}
+	if remindedAt.Valid {
+		q.RemindedAt = &remindedAt.Time
+	}
+	return &q, nil
+}
+
+// RecordQualification saves a driver's license (truckClass "") or
+// certification on behalf of actor, replacing any earlier record of the same
+// kind and class. The expiry reminder is re-armed for the new date.
+func RecordQualification(slackUserID string, kind QualificationKind, truckClass string, expiresAt time.Time, actor Actor) (*Qualification, error) {
+	truckClass = strings.ToLower(strings.TrimSpace(truckClass))
+	if kind == QualificationLicense {
+		truckClass = ""
+	} else if truckClass == "" {
+		return nil, fmt.Errorf("a certification needs a truck class")
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := scanQualification(tx.QueryRow(`
+		SELECT `+qualificationColumns+` FROM driver_qualifications
+		WHERE slack_user_id = ? AND kind = ? AND truck_class = ?
+	`, slackUserID, kind, truckClass))
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to load qualification: %w", err)
+	}
+
+	q := Qualification{
+		ID:          uuid.New(),
+		SlackUserID: slackUserID,
+		Kind:        kind,
+		TruckClass:  truckClass,
+		ExpiresAt:   expiresAt.UTC(),
+		RecordedBy:  actor.SlackUserID,
+		RecordedAt:  time.Now().UTC(),
+	}
+	if before != nil {
+		q.ID = before.ID
+	}
+	_, err = tx.Exec(`
+		INSERT INTO driver_qualifications (id, slack_user_id, kind, truck_class, expires_at, recorded_by, recorded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(slack_user_id, kind, truck_class) DO UPDATE SET
+			expires_at = excluded.expires_at,
+			recorded_by = excluded.recorded_by,
+			recorded_at = excluded.recorded_at,
+			reminded_at = NULL
+	`, q.ID.String(), q.SlackUserID, q.Kind, q.TruckClass, q.ExpiresAt, q.RecordedBy, q.RecordedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record qualification: %w", err)
+	}
+
+	entry := auditEntry{
+		action:     ActionQualification,
+		entityType: "qualification",
+		entityID:   q.ID.String(),
+		after:      q,
+	}
+	if before != nil {
+		entry.before = before
+	}
+	if err := writeAuditEvent(tx, actor, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// GetQualifications returns the user's license and certifications, license
+// first.
+func GetQualifications(slackUserID string) ([]Qualification, error) {
+	rows, err := db.DB.Query(`
+		SELECT `+qualificationColumns+` FROM driver_qualifications
+		WHERE slack_user_id = ?
+		ORDER BY kind DESC, truck_class
+	`, slackUserID)
+	if err != nil {
+		return nil, fmt.Errorf("querying qualifications: %w", err)
+	}
+	defer rows.Close()
+
+	var quals []Qualification
+	for rows.Next() {
+		q, err := scanQualification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning qualification: %w", err)
+		}
+		quals = append(quals, *q)
+	}
+	return quals, rows.Err()
+}
+
+// CheckDriverQualified returns a *QualificationError unless the user has a
+// license, and a certification for the truck's class if it has one, that
+// stay valid until the checkout ends at until. truck may be nil when the
+// truck isn't known yet, in which case only the license is checked.
+func CheckDriverQualified(slackUserID string, truck *Truck, until time.Time) error {
+	quals, err := GetQualifications(slackUserID)
+	if err != nil {
+		return err
+	}
+
+	need := []*QualificationError{{Kind: QualificationLicense}}
+	if truck != nil && truck.Class != "" {
+		need = append(need, &QualificationError{Kind: QualificationCertification, TruckClass: truck.Class, Truck: truck.Name})
+	}
+	for _, n := range need {
+		var found *Qualification
+		for i := range quals {
+			if quals[i].Kind == n.Kind && quals[i].TruckClass == n.TruckClass {
+				found = &quals[i]
+			}
+		}
+		if found == nil {
+			return n
+		}
+		if !found.ExpiresAt.After(until) {
+			n.ExpiredAt = &found.ExpiresAt
+			return n
+		}
+	}
+	return nil
+}
+
+// UncertifiedTrucks returns the names of trucks whose class the user holds
+// no certification for that is valid until the given time, so an any-truck
+// checkout can skip them.
+func UncertifiedTrucks(slackUserID string, until time.Time) ([]string, error) {
+	rows, err := db.DB.Query(`
+		SELECT name FROM trucks
+		WHERE class != ''
+		  AND class NOT IN (
+			SELECT truck_class FROM driver_qualifications
+			WHERE slack_user_id = ? AND kind = ? AND expires_at > ?
+		  )
+		ORDER BY name
+	`, slackUserID, QualificationCertification, until.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("querying uncertified trucks: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning truck name: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// DueQualificationReminders returns qualifications that expire within the
+// given lead time and haven't been reminded about yet.
+func DueQualificationReminders(now time.Time, lead time.Duration) ([]Qualification, error) {
+	rows, err := db.DB.Query(`
+		SELECT `+qualificationColumns+` FROM driver_qualifications
+		WHERE reminded_at IS NULL AND expires_at > ? AND expires_at <= ?
+		ORDER BY expires_at
+	`, now.UTC(), now.Add(lead).UTC())
+	if err != nil {
+		return nil, fmt.Errorf("querying due reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var quals []Qualification
+	for rows.Next() {
+		q, err := scanQualification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning qualification: %w", err)
+		}
+		quals = append(quals, *q)
+	}
+	return quals, rows.Err()
+}
+
+// MarkQualificationReminded records that the user was told the
+// qualification is about to expire, so they are only told once.
+func MarkQualificationReminded(id uuid.UUID, now time.Time) error {
+	_, err := db.DB.Exec(`UPDATE driver_qualifications SET reminded_at = ? WHERE id = ?`, now.UTC(), id.String())
+	if err != nil {
+		return fmt.Errorf("failed to mark reminder sent: %w", err)
+	}
+	return nil
+}
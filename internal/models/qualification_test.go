@@ -0,0 +1,119 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckDriverQualified(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	for _, name := range []string{"Andre350", "Tulip"} {
+		if err := InsertTruck(name, &team, uuid.NewString(), false, testActor); err != nil {
+			t.Fatalf("failed to insert truck: %v", err)
+		}
+	}
+	andre, _ := GetTruckByName("Andre350")
+	andre.Class = "Dump"
+	if err := UpdateTruck(*andre, testActor); err != nil {
+		t.Fatalf("failed to update truck: %v", err)
+	}
+	andre, _ = GetTruckByName("Andre350")
+	if andre.Class != "dump" {
+		t.Fatalf("expected class dump, got %q", andre.Class)
+	}
+	tulip, _ := GetTruckByName("Tulip")
+
+	now := time.Now()
+	until := now.Add(8 * time.Hour)
+
+	var qualErr *QualificationError
+	if err := CheckDriverQualified("user123", tulip, until); !errors.As(err, &qualErr) || qualErr.Kind != QualificationLicense || qualErr.ExpiredAt != nil {
+		t.Fatalf("expected missing license, got %v", err)
+	}
+
+	// A license that runs out before the checkout ends isn't good enough.
+	if _, err := RecordQualification("user123", QualificationLicense, "", now.Add(4*time.Hour), testActor); err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+	if err := CheckDriverQualified("user123", tulip, until); !errors.As(err, &qualErr) || qualErr.ExpiredAt == nil {
+		t.Fatalf("expected expired license, got %v", err)
+	}
+
+	// Recording it again replaces the old expiry.
+	license, err := RecordQualification("user123", QualificationLicense, "", now.AddDate(1, 0, 0), testActor)
+	if err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+	if err := CheckDriverQualified("user123", tulip, until); err != nil {
+		t.Errorf("expected a licensed driver to be able to take Tulip, got %v", err)
+	}
+	if err := CheckDriverQualified("user123", andre, until); !errors.As(err, &qualErr) || qualErr.Kind != QualificationCertification || qualErr.TruckClass != "dump" {
+		t.Errorf("expected missing dump certification, got %v", err)
+	}
+	if names, err := UncertifiedTrucks("user123", until); err != nil || len(names) != 1 || names[0] != "Andre350" {
+		t.Errorf("expected Andre350 to be off limits, got %v (%v)", names, err)
+	}
+
+	if _, err := RecordQualification("user123", QualificationCertification, "dump", now.AddDate(0, 6, 0), testActor); err != nil {
+		t.Fatalf("failed to record certification: %v", err)
+	}
+	if err := CheckDriverQualified("user123", andre, until); err != nil {
+		t.Errorf("expected a certified driver to be able to take Andre350, got %v", err)
+	}
+	if names, _ := UncertifiedTrucks("user123", until); len(names) != 0 {
+		t.Errorf("expected no trucks off limits, got %v", names)
+	}
+
+	quals, err := GetQualifications("user123")
+	if err != nil {
+		t.Fatalf("failed to get qualifications: %v", err)
+	}
+	if len(quals) != 2 || quals[0].ID != license.ID || quals[1].Label() != "dump certification" {
+		t.Errorf("expected the license then the dump certification, got %+v", quals)
+	}
+}
+
+func TestQualificationReminders(t *testing.T) {
+	ResetTestDB(t)
+
+	now := time.Now()
+	lead := 30 * 24 * time.Hour
+	soon, err := RecordQualification("user123", QualificationLicense, "", now.AddDate(0, 0, 20), testActor)
+	if err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+	if _, err := RecordQualification("user456", QualificationLicense, "", now.AddDate(0, 0, 60), testActor); err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+	if _, err := RecordQualification("user789", QualificationLicense, "", now.AddDate(0, 0, -1), testActor); err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+
+	due, err := DueQualificationReminders(now, lead)
+	if err != nil {
+		t.Fatalf("failed to get due reminders: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != soon.ID {
+		t.Fatalf("expected only user123's license to be due, got %+v", due)
+	}
+
+	if err := MarkQualificationReminded(soon.ID, now); err != nil {
+		t.Fatalf("failed to mark reminded: %v", err)
+	}
+	if due, _ := DueQualificationReminders(now, lead); len(due) != 0 {
+		t.Errorf("expected no reminders after sending one, got %+v", due)
+	}
+
+	// Renewing re-arms the reminder for the new expiry.
+	if _, err := RecordQualification("user123", QualificationLicense, "", now.AddDate(0, 0, 25), testActor); err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+	if due, _ := DueQualificationReminders(now, lead); len(due) != 1 || due[0].ID != soon.ID {
+		t.Errorf("expected the renewed license to be due again, got %+v", due)
+	}
+}
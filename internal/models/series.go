@@ -0,0 +1,203 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	db "truck-checkout/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// CheckoutSeries is a recurring reservation, e.g. Watson every Tuesday and
+// Thursday for the planting season. Each occurrence is an ordinary checkout
+// carrying the series ID, so availability and reports need no special cases.
+type CheckoutSeries struct {
+	ID       uuid.UUID `json:"id"`
+	TruckID  uuid.UUID `json:"truck_id"`
+	UserID   string    `json:"user_id"`
+	UserName string    `json:"user_name"`
+	TeamName string    `json:"team_name"`
+	// Weekdays lists the days it recurs on, e.g. "tue,thu".
+	Weekdays     string    `json:"weekdays"`
+	FirstDate    time.Time `json:"first_date"`
+	LastDate     time.Time `json:"last_date"`
+	BusinessDays int       `json:"business_days"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Occurrence is the start and end of one checkout in a series.
+type Occurrence struct {
+	Start time.Time
+	End   time.Time
+}
+
+// SeriesConflict is an occurrence that could not be booked, and why.
+type SeriesConflict struct {
+	Occurrence
+	Err error
+}
+
+// CreateCheckoutSeries saves the series and books every occurrence that is
+// free in one transaction. Occurrences that collide with another booking or
+// with maintenance, or that fail check once the earlier occurrences are
+// counted, are skipped and reported as conflicts; the rest of the series
+// still goes ahead. If none can be booked nothing is saved, and the
+// conflicts are returned with ErrCheckoutOverlap if any occurrence collided
+// with another booking, or otherwise the first occurrence's refusal.
+func CreateCheckoutSeries(series CheckoutSeries, occurrences []Occurrence, purpose string, check *PolicyCheck, actor Actor) ([]Checkout, []SeriesConflict, error) {
+	if !IsValidTeam(series.TeamName) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidTeam, series.TeamName)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO checkout_series (id, truck_id, user_id, user_name, team_name, weekdays, first_date, last_date, business_days)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, series.ID.String(), series.TruckID.String(), series.UserID, series.UserName, series.TeamName,
+		series.Weekdays, series.FirstDate.UTC(), series.LastDate.UTC(), series.BusinessDays)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to insert checkout series: %w", err)
+	}
+
+	now := time.Now().UTC()
+	var booked []Checkout
+	var conflicts []SeriesConflict
+	for _, o := range occurrences {
+		err := checkTruckFree(tx, series.TruckID, o.Start, o.End)
+		if errors.Is(err, ErrTruckUnavailable) || errors.Is(err, ErrCheckoutOverlap) || errors.Is(err, ErrInMaintenance) {
+			conflicts = append(conflicts, SeriesConflict{Occurrence: o, Err: err})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		checkout := Checkout{
+			ID:        uuid.New(),
+			TruckID:   series.TruckID,
+			UserID:    series.UserID,
+			UserName:  series.UserName,
+			TeamName:  series.TeamName,
+			StartDate: o.Start,
+			EndDate:   o.End,
+			Purpose:   purpose,
+			SeriesID:  &series.ID,
+		}
+		err = check.enforce(tx, checkout, actor)
+		if errors.Is(err, ErrPolicyViolation) {
+			conflicts = append(conflicts, SeriesConflict{Occurrence: o, Err: err})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := insertCheckout(tx, checkout); err != nil {
+			return nil, nil, err
+		}
+		if err := auditCheckoutCreate(tx, actor, checkout); err != nil {
+			return nil, nil, err
+		}
+		booked = append(booked, checkout)
+	}
+	if len(booked) == 0 {
+		return nil, conflicts, seriesRefusal(conflicts)
+	}
+
+	// Only an occurrence under way right now takes the truck out.
+	if err := refreshTruckStatus(tx, series.TruckID, now); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return booked, conflicts, nil
+}
+
+// seriesRefusal is the error for a series none of whose occurrences could
+// be booked: ErrCheckoutOverlap if any collided with another booking, or
+// else why the first was refused, such as a PolicyError.
+func seriesRefusal(conflicts []SeriesConflict) error {
+	for _, c := range conflicts {
+		if errors.Is(c.Err, ErrTruckUnavailable) || errors.Is(c.Err, ErrCheckoutOverlap) {
+			return ErrCheckoutOverlap
+		}
+	}
+	if len(conflicts) == 0 {
+		return ErrCheckoutOverlap
+	}
+	return conflicts[0].Err
+}
+
+// GetCheckoutSeriesByID returns the series, or ErrCheckoutNotFound.
+func GetCheckoutSeriesByID(id uuid.UUID) (*CheckoutSeries, error) {
+	var s CheckoutSeries
+	err := db.DB.QueryRow(`
+		SELECT id, truck_id, user_id, user_name, team_name, weekdays, first_date, last_date, business_days, created_at
+		FROM checkout_series WHERE id = ?
+	`, id.String()).Scan(&s.ID, &s.TruckID, &s.UserID, &s.UserName, &s.TeamName,
+		&s.Weekdays, &s.FirstDate, &s.LastDate, &s.BusinessDays, &s.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCheckoutNotFound
+		}
+		return nil, fmt.Errorf("failed to get checkout series: %w", err)
+	}
+	return &s, nil
+}
+
+// CancelSeries cancels every open occurrence of the series that starts at
+// or after from and hasn't started yet, in one transaction, and returns the
+// cancelled checkouts. Occurrences already under way are left for a release.
+func CancelSeries(seriesID uuid.UUID, from time.Time, actor Actor) ([]Checkout, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	rows, err := tx.Query(`
+		SELECT id FROM checkouts
+		WHERE series_id = ? AND start_date >= ? AND start_date > ? AND released_at IS NULL AND cancelled_at IS NULL
+		ORDER BY start_date
+	`, seriesID.String(), from.UTC(), now)
+	if err != nil {
+		return nil, fmt.Errorf("querying series occurrences: %w", err)
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning series occurrence: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var cancelled []Checkout
+	for _, id := range ids {
+		c, err := cancelCheckout(tx, id, actor, now)
+		if err != nil {
+			return nil, err
+		}
+		cancelled = append(cancelled, *c)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return cancelled, nil
+}
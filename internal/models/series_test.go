@@ -0,0 +1,170 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckoutSeries(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Watson", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Watson")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	// Four weekly occurrences starting next week
+	first := time.Now().Add(7 * 24 * time.Hour).Truncate(time.Hour)
+	var occurrences []Occurrence
+	for i := 0; i < 4; i++ {
+		start := first.AddDate(0, 0, 7*i)
+		occurrences = append(occurrences, Occurrence{Start: start, End: start.Add(8 * time.Hour)})
+	}
+
+	// Someone else already has the second week
+	other := Checkout{
+		ID:        uuid.New(),
+		TruckID:   truck.ID,
+		UserID:    "user456",
+		UserName:  "Jane Roe",
+		TeamName:  "beltline",
+		StartDate: occurrences[1].Start.Add(2 * time.Hour),
+		EndDate:   occurrences[1].End,
+	}
+	if err := CreateCheckout(other, nil, testActor); err != nil {
+		t.Fatalf("failed to insert checkout: %v", err)
+	}
+
+	series := CheckoutSeries{
+		ID:           uuid.New(),
+		TruckID:      truck.ID,
+		UserID:       "user123",
+		UserName:     "John Doe",
+		TeamName:     "beltline",
+		Weekdays:     "tue",
+		FirstDate:    occurrences[0].Start,
+		LastDate:     occurrences[3].Start,
+		BusinessDays: 1,
+	}
+	booked, conflicts, err := CreateCheckoutSeries(series, occurrences, "Planting", nil, testActor)
+	if err != nil {
+		t.Fatalf("failed to create series: %v", err)
+	}
+	if len(booked) != 3 || len(conflicts) != 1 {
+		t.Fatalf("expected 3 booked and 1 conflict, got %d and %+v", len(booked), conflicts)
+	}
+	if !conflicts[0].Start.Equal(occurrences[1].Start) || !errors.Is(conflicts[0].Err, ErrCheckoutOverlap) {
+		t.Errorf("expected the second week to conflict, got %+v", conflicts[0])
+	}
+
+	upcoming, err := GetUpcomingCheckoutsByUserID("user123", time.Now())
+	if err != nil {
+		t.Fatalf("failed to get upcoming checkouts: %v", err)
+	}
+	if len(upcoming) != 3 || upcoming[0].SeriesID == nil || *upcoming[0].SeriesID != series.ID {
+		t.Fatalf("expected three occurrences linked to the series, got %+v", upcoming)
+	}
+
+	// Booking the same weeks again conflicts everywhere and saves nothing
+	again := series
+	again.ID = uuid.New()
+	if _, conflicts, err := CreateCheckoutSeries(again, occurrences, "Planting", nil, testActor); !errors.Is(err, ErrCheckoutOverlap) || len(conflicts) != 4 {
+		t.Errorf("expected every occurrence to conflict, got %v with %d conflicts", err, len(conflicts))
+	}
+	if _, err := GetCheckoutSeriesByID(again.ID); !errors.Is(err, ErrCheckoutNotFound) {
+		t.Errorf("expected the failed series not to be saved, got %v", err)
+	}
+
+	// Cancel just the first occurrence, then the rest of the series
+	if _, err := CancelCheckout(upcoming[0].ID, testActor); err != nil {
+		t.Fatalf("failed to cancel occurrence: %v", err)
+	}
+	cancelled, err := CancelSeries(series.ID, occurrences[2].Start, testActor)
+	if err != nil {
+		t.Fatalf("failed to cancel series: %v", err)
+	}
+	if len(cancelled) != 2 {
+		t.Errorf("expected the last two occurrences to be cancelled, got %d", len(cancelled))
+	}
+	if upcoming, _ := GetUpcomingCheckoutsByUserID("user123", time.Now()); len(upcoming) != 0 {
+		t.Errorf("expected nothing left in the series, got %+v", upcoming)
+	}
+
+	got, err := GetCheckoutSeriesByID(series.ID)
+	if err != nil {
+		t.Fatalf("failed to get series: %v", err)
+	}
+	if got.Weekdays != "tue" || got.BusinessDays != 1 || !got.FirstDate.Equal(series.FirstDate) {
+		t.Errorf("unexpected series %+v", got)
+	}
+}
+
+func TestCheckoutSeries_CountsItsOwnOccurrences(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Watson", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Watson")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	// Tuesdays and Thursdays for two weeks, with a quota of one a week
+	var occurrences []Occurrence
+	for _, day := range []int{5, 7, 12, 14} {
+		start := time.Date(2027, time.January, day, 7, 0, 0, 0, time.UTC)
+		occurrences = append(occurrences, Occurrence{Start: start, End: start.Add(8 * time.Hour)})
+	}
+	series := CheckoutSeries{
+		ID:           uuid.New(),
+		TruckID:      truck.ID,
+		UserID:       "user123",
+		UserName:     "John Doe",
+		TeamName:     "beltline",
+		Weekdays:     "tue,thu",
+		FirstDate:    occurrences[0].Start,
+		LastDate:     occurrences[3].Start,
+		BusinessDays: 1,
+	}
+	check := &PolicyCheck{
+		Policy:  &CheckoutPolicy{TeamWeeklyQuota: map[string]int{"beltline": 1}},
+		Request: CheckoutRequest{TruckName: "Watson", UserID: "user123", TeamName: "beltline", BusinessDays: 1, Now: time.Now()},
+	}
+	booked, conflicts, err := CreateCheckoutSeries(series, occurrences, "Planting", check, testActor)
+	if err != nil {
+		t.Fatalf("failed to create series: %v", err)
+	}
+	if len(booked) != 2 || len(conflicts) != 2 {
+		t.Fatalf("expected 2 booked and 2 conflicts, got %d and %+v", len(booked), conflicts)
+	}
+	for i, c := range conflicts {
+		if !c.Start.Equal(occurrences[2*i+1].Start) || !errors.Is(c.Err, ErrPolicyViolation) {
+			t.Errorf("expected each Thursday to break the quota, got %+v", c)
+		}
+	}
+
+	// With the quota used up, a series on another truck collides with
+	// nothing, so the policy is what stops it.
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	tulip, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+	series.ID, series.TruckID = uuid.New(), tulip.ID
+	check.Request.TruckName = "Tulip"
+	var policy *PolicyError
+	if _, conflicts, err := CreateCheckoutSeries(series, occurrences, "Planting", check, testActor); !errors.As(err, &policy) || len(conflicts) != 4 {
+		t.Errorf("expected a PolicyError and 4 conflicts, got %v and %+v", err, conflicts)
+	}
+}
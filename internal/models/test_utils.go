@@ -5,15 +5,19 @@ import (
 	db "truck-checkout/internal/database"
 )
 
-// ResetTestDB clears all data from the 'checkouts' and 'trucks' tables in the test database.
+// ResetTestDB clears all data from the tables in the test database.
 // It is intended to be used in test setup or teardown to ensure a clean database state.
 // If the operation fails, the test is immediately failed with a fatal error.
 func ResetTestDB(t *testing.T) {
 	if db.DB == nil {
 		t.Fatal("db.DB is nil in ResetTestDB")
 	}
-	_, err := db.DB.Exec(`DELETE FROM checkouts; DELETE FROM trucks; DELETE FROM users;`)
+	// audit_events rejects deletes, so it is dropped and recreated instead.
+	_, err := db.DB.Exec(`DROP TABLE IF EXISTS audit_events; DELETE FROM maintenance_blocks; DELETE FROM waitlist_entries; DELETE FROM custody_events; DELETE FROM driver_qualifications; DELETE FROM fuel_purchases; DELETE FROM odometer_readings; DELETE FROM checkout_assets; DELETE FROM checkouts; DELETE FROM checkout_series; DELETE FROM assets; DELETE FROM trucks; DELETE FROM users;`)
 	if err != nil {
 		t.Fatalf("failed to reset database: %v", err)
 	}
+	if err := db.CreateTables(db.DB); err != nil {
+		t.Fatalf("failed to recreate tables: %v", err)
+	}
 }
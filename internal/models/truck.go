@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	db "truck-checkout/internal/database"
@@ -11,121 +12,260 @@ import (
 )
 
 type Truck struct {
-	ID           uuid.UUID
-	Name         string
-	DefaultTeam  *string
-	GoogleCalendarID   string
-	IsCheckedOut bool
+	ID               uuid.UUID `json:"id"`
+	Name             string    `json:"name"`
+	DefaultTeam      *string   `json:"default_team,omitempty"`
+	GoogleCalendarID string    `json:"google_calendar_id,omitempty"`
+	IsCheckedOut     bool      `json:"is_checked_out"`
+	// Features are what the truck offers attached assets, e.g. "hitch".
+	Features []string `json:"features,omitempty"`
+	// Class is the certification a driver needs, e.g. "dump"; empty means
+	// a license is enough.
+	Class string `json:"class,omitempty"`
 }
 
-func InsertTruck(name string, team *string, calendarID string, isCheckedOut bool) error {
+// InsertTruck adds a truck to the fleet on behalf of actor.
+func InsertTruck(name string, team *string, calendarID string, isCheckedOut bool, actor Actor) error {
 	// anyway for the enum to be auto validated?
 	if !IsValidTruck(name) {
-		return fmt.Errorf("invalid truck name: %s", name)
+		return fmt.Errorf("%w: %s", ErrInvalidTruck, name)
 	}
 	if team != nil && !IsValidTeam(*team) {
-		return fmt.Errorf("invalid default team: %s", *team)
+		return fmt.Errorf("%w: %s", ErrInvalidTeam, *team)
 	}
 
-	id := uuid.New()
-	_, err := db.DB.Exec(`
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	truck := Truck{ID: uuid.New(), Name: name, DefaultTeam: team, GoogleCalendarID: calendarID, IsCheckedOut: isCheckedOut}
+	_, err = tx.Exec(`
 		INSERT INTO trucks (id, name, default_team, google_calendar_id, is_checked_out)
 		VALUES (?, ?, ?, ?, ?);
-	`, id, name, team, calendarID, isCheckedOut)
-	return err
+	`, truck.ID, name, team, calendarID, isCheckedOut)
+	if err != nil {
+		return err
+	}
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionTruckCreate,
+		entityType: "truck",
+		entityID:   truck.ID.String(),
+		truckID:    &truck.ID,
+		after:      truck,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func GetTruckByName(name string) (*Truck, error) {
+// truckColumns is the trucks row in the order scanTruck reads it.
+const truckColumns = `id, name, default_team, google_calendar_id, is_checked_out, features, class`
+
+func scanTruck(row rowScanner) (*Truck, error) {
 	var truck Truck
 	var defaultTeam sql.NullString
+	var features string
+	if err := row.Scan(&truck.ID, &truck.Name, &defaultTeam, &truck.GoogleCalendarID, &truck.IsCheckedOut, &features, &truck.Class); err != nil {
+		return nil, err
+	}
+	if defaultTeam.Valid {
+		truck.DefaultTeam = &defaultTeam.String
+	}
+	truck.Features = splitList(features)
+	return &truck, nil
+}
 
-	row := db.DB.QueryRow("SELECT id, name, default_team, google_calendar_id, is_checked_out FROM trucks WHERE name = ?", name)
-	err := row.Scan(&truck.ID, &truck.Name, &defaultTeam, &truck.GoogleCalendarID, &truck.IsCheckedOut)
+func GetTruckByName(name string) (*Truck, error) {
+	row := db.DB.QueryRow("SELECT "+truckColumns+" FROM trucks WHERE name = ?", name)
+	truck, err := scanTruck(row)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrTruckNotFound, name)
+		}
 		return nil, err
 	}
+	return truck, nil
+}
 
-	if defaultTeam.Valid {
-		truck.DefaultTeam = &defaultTeam.String
+func GetTruckByID(id uuid.UUID) (*Truck, error) {
+	row := db.DB.QueryRow("SELECT "+truckColumns+" FROM trucks WHERE id = ?", id.String())
+	truck, err := scanTruck(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrTruckNotFound, id)
+		}
+		return nil, err
 	}
+	return truck, nil
+}
 
-	return &truck, nil
+// GetTrucks returns every truck in name order.
+func GetTrucks() ([]Truck, error) {
+	rows, err := db.DB.Query("SELECT " + truckColumns + " FROM trucks ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("querying trucks: %w", err)
+	}
+	defer rows.Close()
+
+	var trucks []Truck
+	for rows.Next() {
+		truck, err := scanTruck(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning truck: %w", err)
+		}
+		trucks = append(trucks, *truck)
+	}
+	return trucks, rows.Err()
 }
 
-func UpdateTruck(truck Truck) error {
+// UpdateTruck saves the truck's details on behalf of actor. IsCheckedOut is
+// ignored: only the checkouts decide it.
+func UpdateTruck(truck Truck, actor Actor) error {
 	if !IsValidTruck(truck.Name) {
-		return fmt.Errorf("invalid truck name: %s", truck.Name)
+		return fmt.Errorf("%w: %s", ErrInvalidTruck, truck.Name)
 	}
 	if truck.DefaultTeam != nil && !IsValidTeam(*truck.DefaultTeam) {
-		return fmt.Errorf("invalid default team: %s", *truck.DefaultTeam)
+		return fmt.Errorf("%w: %s", ErrInvalidTeam, *truck.DefaultTeam)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := scanTruck(tx.QueryRow(`SELECT `+truckColumns+` FROM trucks WHERE id = ?`, truck.ID.String()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrTruckNotFound, truck.ID)
+		}
+		return fmt.Errorf("failed to load truck: %w", err)
 	}
+	truck.IsCheckedOut = before.IsCheckedOut
 
-	_, err := db.DB.Exec(`
+	_, err = tx.Exec(`
 		UPDATE trucks
-		SET name = ?, default_team = ?, google_calendar_id = ?, is_checked_out = ?
+		SET name = ?, default_team = ?, google_calendar_id = ?, features = ?, class = ?
 		WHERE id = ?;
-	`, truck.Name, truck.DefaultTeam, truck.GoogleCalendarID, truck.IsCheckedOut, truck.ID)
-	return err
+	`, truck.Name, truck.DefaultTeam, truck.GoogleCalendarID, joinList(truck.Features), strings.ToLower(truck.Class), truck.ID)
+	if err != nil {
+		return err
+	}
+
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionTruckUpdate,
+		entityType: "truck",
+		entityID:   truck.ID.String(),
+		truckID:    &truck.ID,
+		before:     before,
+		after:      truck,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetTrucksByCheckoutStatus returns the trucks that are checked out at the
+// moment at, or free then. A booking later the same day doesn't make a truck
+// unavailable now; use GetTrucksByCheckoutStatusBetween for whole days.
+func GetTrucksByCheckoutStatus(at time.Time, isCheckedOut bool) ([]Truck, error) {
+	return queryTrucksByCheckouts(isCheckedOut, occupiesAt, at.UTC(), at.UTC(), at.UTC())
+}
+
+// GetTrucksByCheckoutStatusBetween returns the trucks booked for any part of
+// [from, to), or with isCheckedOut false, those free for all of it.
+func GetTrucksByCheckoutStatusBetween(from, to time.Time, isCheckedOut bool) ([]Truck, error) {
+	return queryTrucksByCheckouts(isCheckedOut, occupiesBetween, to.UTC(), from.UTC(), from.UTC())
 }
 
-func GetTrucksByCheckoutStatus(day time.Time, isCheckedOut bool) ([]Truck, error) {
-	// startOfDay := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
-	// endOfDay := startOfDay.Add(24 * time.Hour)
-	currentTime := time.Now()
-
-	var query string
-    activeCheckoutSubquery := `
-        EXISTS (
-            SELECT 1 FROM checkouts c
-            WHERE c.truck_id = t.id
-            AND c.start_date <= ? 
-            AND c.end_date > ?
-            AND c.released_at IS NULL
-        )
-    `
-
-    if isCheckedOut {
-        query = fmt.Sprintf(`
-            SELECT t.id, t.name, t.default_team, t.google_calendar_id, t.is_checked_out
-            FROM trucks t WHERE %s`, activeCheckoutSubquery)
-    } else {
-        query = fmt.Sprintf(`
-            SELECT t.id, t.name, t.default_team, t.google_calendar_id, t.is_checked_out
-            FROM trucks t WHERE NOT %s`, activeCheckoutSubquery)
-    }
-
-	rows, err := db.DB.Query(query, currentTime, currentTime)
+// queryTrucksByCheckouts returns the trucks with a checkout c matching
+// condition, or with isCheckedOut false, those without one.
+func queryTrucksByCheckouts(isCheckedOut bool, condition string, args ...any) ([]Truck, error) {
+	exists := "EXISTS"
+	if !isCheckedOut {
+		exists = "NOT EXISTS"
+	}
+	query := `
+		SELECT ` + truckColumns + `
+		FROM trucks t
+		WHERE ` + exists + ` (
+			SELECT 1 FROM checkouts c
+			WHERE c.truck_id = t.id AND ` + condition + `
+		)
+		ORDER BY t.name
+	`
+	rows, err := db.DB.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying trucks by checkout status: %w", err)
 	}
 	defer rows.Close()
 
 	var trucks []Truck
-
 	for rows.Next() {
-		var t Truck
-		var defaultTeam sql.NullString
-		var idStr, calendarIDStr string
-
-		if err := rows.Scan(&idStr, &t.Name, &defaultTeam, &calendarIDStr, &t.IsCheckedOut); err != nil {
+		truck, err := scanTruck(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scanning truck row: %w", err)
 		}
-
-		if t.ID, err = uuid.Parse(idStr); err != nil {
-			return nil, fmt.Errorf("parsing truck UUID: %w", err)
-		}
-
-		t.GoogleCalendarID = calendarIDStr
-		
-		if defaultTeam.Valid {
-			t.DefaultTeam = &defaultTeam.String
-		}
-		trucks = append(trucks, t)
+		trucks = append(trucks, *truck)
 	}
-
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("after row iteration: %w", err)
 	}
 
 	return trucks, nil
 }
+
+// occupiesBetween matches checkouts c that hold the truck for part of a
+// range, taking the range's end, start and start again as arguments. A
+// released checkout only held it until it was released.
+const occupiesBetween = `c.start_date < ? AND c.end_date > ?
+	AND c.cancelled_at IS NULL
+	AND (c.released_at IS NULL OR c.released_at > ?)`
+
+// occupiesAt matches checkouts c that hold the truck at a moment, taking
+// that moment three times as arguments.
+const occupiesAt = `c.start_date <= ? AND c.end_date > ?
+	AND c.cancelled_at IS NULL
+	AND (c.released_at IS NULL OR c.released_at > ?)`
+
+// Interval is the span of time [Start, End).
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// GetTruckAvailability returns the spans within [from, to) when the truck is
+// not booked, in order. Released checkouts only count until their release.
+func GetTruckAvailability(truckID uuid.UUID, from, to time.Time) ([]Interval, error) {
+	booked, err := queryCheckouts(`
+		SELECT `+checkoutColumns+`
+		FROM checkouts c
+		WHERE c.truck_id = ? AND `+occupiesBetween+`
+		ORDER BY c.start_date
+	`, truckID.String(), to.UTC(), from.UTC(), from.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	var free []Interval
+	cursor := from
+	for _, c := range booked {
+		end := c.HeldUntil()
+		if c.StartDate.After(cursor) {
+			free = append(free, Interval{Start: cursor, End: c.StartDate})
+		}
+		if end.After(cursor) {
+			cursor = end
+		}
+	}
+	if to.After(cursor) {
+		free = append(free, Interval{Start: cursor, End: to})
+	}
+	return free, nil
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	db "truck-checkout/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// inProgressAt matches checkouts c that have the truck at a moment, taking
+// that moment twice as arguments.
+const inProgressAt = `c.start_date <= ? AND c.end_date > ?
+	AND c.released_at IS NULL AND c.cancelled_at IS NULL`
+
+// StatusMismatch is a truck whose is_checked_out flag disagreed with its
+// checkouts.
+type StatusMismatch struct {
+	TruckID   uuid.UUID
+	TruckName string
+	// Flagged is what is_checked_out said; InProgress is whether a checkout
+	// actually had the truck.
+	Flagged    bool
+	InProgress bool
+}
+
+func (m StatusMismatch) String() string {
+	return fmt.Sprintf("%s was marked checked out=%t but has a checkout in progress=%t", m.TruckName, m.Flagged, m.InProgress)
+}
+
+// ReconcileTruckStatus recomputes every truck's is_checked_out flag from the
+// checkouts in progress at now, on behalf of actor. The flag only changes
+// when something writes it, so a booking that starts later or a checkout that
+// runs out on its own leaves it stale until this runs; those are fixed
+// quietly. It audits, fixes and returns only the trucks whose flag disagreed
+// for any other reason, such as a release that didn't clear it.
+//
+// Everything that needs to know whether a truck is out should ask the
+// checkouts table; once nothing reads the flag any more, the column can be
+// dropped along with this.
+func ReconcileTruckStatus(now time.Time, actor Actor) ([]StatusMismatch, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// started is whether a checkout in progress was booked ahead of its
+	// start, so the flag was never set for it; ranOut is whether the last
+	// checkout to have started ended without being released.
+	rows, err := tx.Query(`
+		SELECT t.id, t.name, t.is_checked_out,
+			EXISTS (
+				SELECT 1 FROM checkouts c WHERE c.truck_id = t.id AND `+inProgressAt+`
+			),
+			EXISTS (
+				SELECT 1 FROM checkouts c WHERE c.truck_id = t.id AND `+inProgressAt+`
+				AND strftime('%s', c.start_date) > strftime('%s', c.created_at)
+			),
+			(
+				SELECT c.released_at IS NULL FROM checkouts c
+				WHERE c.truck_id = t.id AND c.cancelled_at IS NULL AND c.start_date <= ?
+				ORDER BY c.end_date DESC LIMIT 1
+			)
+		FROM trucks t
+		ORDER BY t.name
+	`, now.UTC(), now.UTC(), now.UTC(), now.UTC(), now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("querying truck status: %w", err)
+	}
+	var stale, mismatches []StatusMismatch
+	for rows.Next() {
+		var m StatusMismatch
+		var started bool
+		var ranOut sql.NullBool
+		if err := rows.Scan(&m.TruckID, &m.TruckName, &m.Flagged, &m.InProgress, &started, &ranOut); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning truck status: %w", err)
+		}
+		switch {
+		case m.Flagged == m.InProgress:
+		case m.InProgress && started, !m.InProgress && ranOut.Bool:
+			stale = append(stale, m)
+		default:
+			mismatches = append(mismatches, m)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range append(stale, mismatches...) {
+		if _, err := tx.Exec(`UPDATE trucks SET is_checked_out = ? WHERE id = ?`, m.InProgress, m.TruckID.String()); err != nil {
+			return nil, fmt.Errorf("failed to fix status of %s: %w", m.TruckName, err)
+		}
+	}
+	for _, m := range mismatches {
+		err := writeAuditEvent(tx, actor, auditEntry{
+			action:     ActionTruckUpdate,
+			entityType: "truck",
+			entityID:   m.TruckID.String(),
+			truckID:    &m.TruckID,
+			before:     map[string]bool{"is_checked_out": m.Flagged},
+			after:      map[string]bool{"is_checked_out": m.InProgress},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return mismatches, nil
+}
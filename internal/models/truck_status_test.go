@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReconcileTruckStatus(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	// Reconcile a couple of hours from now, so that a checkout starting
+	// within the hour was booked ahead of its start.
+	now := time.Now()
+	at := now.Add(2 * time.Hour)
+	// Tulip's checkout has run out and Watson's booking has started without
+	// the flag noticing; both are expected. Magnolia's checkout started when it
+	// was booked and Andre350 has no checkout at all, so their flags are wrong.
+	// Libby is right.
+	flags := map[string]bool{"Tulip": true, "Watson": false, "Libby": true, "Magnolia": false, "Andre350": true}
+	for name, flagged := range flags {
+		if err := InsertTruck(name, &team, uuid.NewString(), flagged, testActor); err != nil {
+			t.Fatalf("failed to insert truck %s: %v", name, err)
+		}
+	}
+	for name, start := range map[string]time.Time{"Tulip": at.Add(-9 * time.Hour), "Watson": at.Add(-time.Hour), "Libby": now.Add(-time.Hour), "Magnolia": now.Add(-time.Hour)} {
+		truck, _ := GetTruckByName(name)
+		err := InsertCheckout(Checkout{
+			ID:        uuid.New(),
+			TruckID:   truck.ID,
+			UserID:    "user123",
+			UserName:  "John Doe",
+			TeamName:  "beltline",
+			StartDate: start,
+			EndDate:   start.Add(8 * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("failed to insert checkout: %v", err)
+		}
+	}
+
+	mismatches, err := ReconcileTruckStatus(at, testActor)
+	if err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+	if len(mismatches) != 2 ||
+		mismatches[0].TruckName != "Andre350" || !mismatches[0].Flagged || mismatches[0].InProgress ||
+		mismatches[1].TruckName != "Magnolia" || mismatches[1].Flagged || !mismatches[1].InProgress {
+		t.Fatalf("expected only Andre350 and Magnolia to be reported, got %+v", mismatches)
+	}
+	for name, want := range map[string]bool{"Tulip": false, "Watson": true, "Libby": true, "Magnolia": true, "Andre350": false} {
+		truck, _ := GetTruckByName(name)
+		if truck.IsCheckedOut != want {
+			t.Errorf("expected %s checked out=%t after reconciling", name, want)
+		}
+		events, err := GetAuditEventsByTruckID(truck.ID, 10)
+		if err != nil {
+			t.Fatalf("failed to load audit events: %v", err)
+		}
+		audited := false
+		for _, e := range events {
+			audited = audited || e.Action == ActionTruckUpdate
+		}
+		if wantAudit := name == "Magnolia" || name == "Andre350"; audited != wantAudit {
+			t.Errorf("expected %s audited=%t, got %t", name, wantAudit, audited)
+		}
+	}
+
+	if again, err := ReconcileTruckStatus(at, testActor); err != nil || len(again) != 0 {
+		t.Errorf("expected nothing left to fix, got %+v (%v)", again, err)
+	}
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql"
 	"os"
+	"slices"
 	"testing"
 	"time"
 	db "truck-checkout/internal/database"
@@ -42,7 +43,7 @@ func TestInsertTruck(t *testing.T) {
 	ResetTestDB(t)
 	team := "beltline"
 	calendarID := uuid.NewString()
-	err := InsertTruck("Tulip", &team, calendarID, true)
+	err := InsertTruck("Tulip", &team, calendarID, true, testActor)
 	if err != nil {
 		t.Fatalf("failed to insert truck: %v", err)
 	}
@@ -69,7 +70,7 @@ func TestInsertTruck_InvalidName(t *testing.T) {
 	ResetTestDB(t)
 	calendarID := uuid.NewString()
 	team := "beltline"
-	err := InsertTruck("BananaBoat", &team, calendarID, true)
+	err := InsertTruck("BananaBoat", &team, calendarID, true, testActor)
 	if err == nil {
 		t.Fatal("expected error for invalid truck name")
 	}
@@ -79,7 +80,7 @@ func TestUpdateTruck(t *testing.T) {
 	ResetTestDB(t)
 	team := "floaters"
 	calendarID := uuid.NewString()
-	err := InsertTruck("Libby", &team, calendarID, false)
+	err := InsertTruck("Libby", &team, calendarID, false, testActor)
 	if err != nil {
 		t.Fatalf("Insert failed: %v", err)
 	}
@@ -89,12 +90,12 @@ func TestUpdateTruck(t *testing.T) {
 		t.Fatalf("Get failed: %v", err)
 	}
 
-	// update Libby to be inactive and change team
+	// Change Libby's team; the checked-out flag is left to the checkouts
 	newTeam := "beltline"
 	truck.DefaultTeam = &newTeam
 	truck.IsCheckedOut = true
 
-	err = UpdateTruck(*truck)
+	err = UpdateTruck(*truck, testActor)
 	if err != nil {
 		t.Fatalf("Update failed: %v", err)
 	}
@@ -104,8 +105,8 @@ func TestUpdateTruck(t *testing.T) {
 		t.Fatalf("Get after update failed: %v", err)
 	}
 
-	if !updated.IsCheckedOut {
-		t.Errorf("expected IsCheckedOut to be true, got false")
+	if updated.IsCheckedOut {
+		t.Errorf("expected IsCheckedOut to stay false without a checkout, got true")
 	}
 
 	if *updated.DefaultTeam != "beltline" {
@@ -121,18 +122,18 @@ func TestGetAvailableTrucksForToday(t *testing.T) {
 	team2 := "beltline"
 
 	// Create available trucks (active)
-	err := InsertTruck("Tulip", &team1, uuid.NewString(), true)
+	err := InsertTruck("Tulip", &team1, uuid.NewString(), true, testActor)
 	if err != nil {
 		t.Fatalf("failed to insert truck Tulip: %v", err)
 	}
 
-	err = InsertTruck("Andre350", &team2, uuid.NewString(), true)
+	err = InsertTruck("Andre350", &team2, uuid.NewString(), true, testActor)
 	if err != nil {
 		t.Fatalf("failed to insert truck Andre350: %v", err)
 	}
 
 	// Create unavailable truck (unavaialble)
-	err = InsertTruck("Magnolia", &team1, uuid.NewString(), false)
+	err = InsertTruck("Magnolia", &team1, uuid.NewString(), false, testActor)
 	if err != nil {
 		t.Fatalf("failed to insert truck Magnolia: %v", err)
 	}
@@ -156,7 +157,7 @@ func TestGetAvailableTrucksForToday(t *testing.T) {
 		Purpose:   "Testing checkout overlap",
 	}
 
-	if err := CreateCheckout (checkout); err != nil {
+	if err := CreateCheckout(checkout, nil, testActor); err != nil {
 		t.Fatalf("failed to insert checkout: %v", err)
 	}
 
@@ -207,3 +208,149 @@ func getTruckNames(trucks []Truck) []string {
 	}
 	return names
 }
+
+// seedAvailabilityWeek books Tulip around the week of Monday Nov 2, 2026 and
+// leaves Watson free. Times are UTC.
+func seedAvailabilityWeek(t *testing.T) *Truck {
+	t.Helper()
+	ResetTestDB(t)
+
+	team := "beltline"
+	for _, name := range []string{"Tulip", "Watson"} {
+		if err := InsertTruck(name, &team, uuid.NewString(), false, testActor); err != nil {
+			t.Fatalf("failed to insert truck %s: %v", name, err)
+		}
+	}
+	tulip, _ := GetTruckByName("Tulip")
+
+	book := func(start, end time.Time) Checkout {
+		c := Checkout{
+			ID:        uuid.New(),
+			TruckID:   tulip.ID,
+			UserID:    "user123",
+			UserName:  "John Doe",
+			TeamName:  "beltline",
+			StartDate: start,
+			EndDate:   end,
+		}
+		if err := CreateCheckout(c, nil, testActor); err != nil {
+			t.Fatalf("failed to create checkout: %v", err)
+		}
+		return c
+	}
+	// Two crews share Tuesday.
+	book(at(3, 9, 0), at(3, 12, 0))
+	book(at(3, 12, 0), at(3, 15, 30))
+	// Wednesday's crew released the truck at 10:00.
+	released := book(at(4, 7, 0), at(4, 15, 30))
+	if _, err := db.DB.Exec(`UPDATE checkouts SET released_at = ?, released_by = ? WHERE id = ?`, at(4, 10, 0), "user123", released.ID.String()); err != nil {
+		t.Fatalf("failed to release checkout: %v", err)
+	}
+	// Thursday's booking was cancelled.
+	cancelled := book(at(5, 7, 0), at(5, 15, 30))
+	if _, err := CancelCheckout(cancelled.ID, testActor); err != nil {
+		t.Fatalf("failed to cancel checkout: %v", err)
+	}
+	// Friday through Monday, over the weekend.
+	book(at(6, 7, 0), at(9, 15, 30))
+	return tulip
+}
+
+// at is the given day of November 2026 at hour:min UTC.
+func at(day, hour, min int) time.Time {
+	return time.Date(2026, time.November, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestGetTruckAvailability(t *testing.T) {
+	tulip := seedAvailabilityWeek(t)
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []Interval
+	}{
+		{"whole week", at(2, 0, 0), at(9, 0, 0), []Interval{
+			{at(2, 0, 0), at(3, 9, 0)},
+			{at(3, 15, 30), at(4, 7, 0)},
+			{at(4, 10, 0), at(6, 7, 0)},
+		}},
+		{"weekend inside a multi-day booking", at(7, 0, 0), at(9, 0, 0), nil},
+		{"back-to-back slots", at(3, 7, 0), at(3, 15, 30), []Interval{{at(3, 7, 0), at(3, 9, 0)}}},
+		{"after a release", at(4, 9, 0), at(4, 15, 30), []Interval{{at(4, 10, 0), at(4, 15, 30)}}},
+		{"cancelled booking", at(5, 7, 0), at(5, 15, 30), []Interval{{at(5, 7, 0), at(5, 15, 30)}}},
+		{"after the multi-day booking", at(9, 12, 0), at(10, 0, 0), []Interval{{at(9, 15, 30), at(10, 0, 0)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetTruckAvailability(tulip.ID, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("GetTruckAvailability error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetTruckAvailability = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Start.Equal(tt.want[i].Start) || !got[i].End.Equal(tt.want[i].End) {
+					t.Errorf("interval %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGetTrucksByCheckoutStatusBetween(t *testing.T) {
+	seedAvailabilityWeek(t)
+
+	tests := []struct {
+		name       string
+		from, to   time.Time
+		checkedOut []string
+		available  []string
+	}{
+		{"shared Tuesday", at(3, 13, 0), at(3, 14, 0), []string{"Tulip"}, []string{"Watson"}},
+		{"after a release", at(4, 11, 0), at(4, 12, 0), nil, []string{"Tulip", "Watson"}},
+		{"cancelled booking", at(5, 7, 0), at(5, 15, 30), nil, []string{"Tulip", "Watson"}},
+		{"weekend", at(7, 0, 0), at(8, 0, 0), []string{"Tulip"}, []string{"Watson"}},
+		{"whole week", at(2, 0, 0), at(9, 0, 0), []string{"Tulip"}, []string{"Watson"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := GetTrucksByCheckoutStatusBetween(tt.from, tt.to, true)
+			if err != nil {
+				t.Fatalf("checked out query error: %v", err)
+			}
+			free, err := GetTrucksByCheckoutStatusBetween(tt.from, tt.to, false)
+			if err != nil {
+				t.Fatalf("available query error: %v", err)
+			}
+			if got := getTruckNames(out); !slices.Equal(got, tt.checkedOut) {
+				t.Errorf("checked out = %v, want %v", got, tt.checkedOut)
+			}
+			if got := getTruckNames(free); !slices.Equal(got, tt.available) {
+				t.Errorf("available = %v, want %v", got, tt.available)
+			}
+		})
+	}
+
+	// The time argument picks the moment, rather than always meaning now.
+	out, err := GetTrucksByCheckoutStatus(at(7, 12, 0), true)
+	if err != nil {
+		t.Fatalf("GetTrucksByCheckoutStatus error: %v", err)
+	}
+	if got := getTruckNames(out); !slices.Equal(got, []string{"Tulip"}) {
+		t.Errorf("checked out on Saturday = %v, want [Tulip]", got)
+	}
+	free, _ := GetTrucksByCheckoutStatus(at(5, 12, 0), false)
+	if got := getTruckNames(free); !slices.Equal(got, []string{"Tulip", "Watson"}) {
+		t.Errorf("available on Thursday = %v, want [Tulip Watson]", got)
+	}
+	// Before Tuesday's first slot the truck is free, though booked later.
+	free, _ = GetTrucksByCheckoutStatus(at(3, 8, 0), false)
+	if got := getTruckNames(free); !slices.Equal(got, []string{"Tulip", "Watson"}) {
+		t.Errorf("available before Tuesday's slots = %v, want [Tulip Watson]", got)
+	}
+	out, _ = GetTrucksByCheckoutStatus(at(3, 9, 0), true)
+	if got := getTruckNames(out); !slices.Equal(got, []string{"Tulip"}) {
+		t.Errorf("checked out as Tuesday's first slot starts = %v, want [Tulip]", got)
+	}
+}
@@ -10,30 +10,62 @@ import (
 	"github.com/google/uuid"
 )
 
+// Role controls what a user may do beyond their own checkouts.
+type Role string
+
+const (
+	RoleMember     Role = "member"
+	RoleTeamLead   Role = "team_lead"
+	RoleFleetAdmin Role = "fleet_admin"
+)
+
+// ParseRole accepts a role name or one of the short forms "lead" and "admin".
+func ParseRole(s string) (Role, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "member":
+		return RoleMember, true
+	case "team_lead", "lead":
+		return RoleTeamLead, true
+	case "fleet_admin", "admin":
+		return RoleFleetAdmin, true
+	}
+	return "", false
+}
+
 type User struct {
 	ID          string    `json:"id"`
 	SlackUserID string    `json:"slack_user_id"`
 	Username    string    `json:"username"`
 	Team        string    `json:"team"`
+	Role        Role      `json:"role"`
 	CreatedAt   time.Time `json:"created_at"`
+	// DisplayName is the user's Slack display name, refreshed along with
+	// Username from their Slack profile.
+	DisplayName string `json:"display_name,omitempty"`
+	Phone       string `json:"phone,omitempty"`
+	// DeactivatedAt is set once their Slack account is deactivated.
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
 }
 
-func GetUserBySlackID(slackUserID string) (*User, error) {
-	query := `
-        SELECT id, slack_user_id, username, team, created_at 
-        FROM users 
-        WHERE slack_user_id = ?
-    `
+// userColumns is the users row in the order scanUser reads it.
+const userColumns = `id, slack_user_id, username, team, role, created_at, display_name, phone, deactivated_at`
 
+func scanUser(row rowScanner) (*User, error) {
 	var user User
-	err := db.DB.QueryRow(query, slackUserID).Scan(
-		&user.ID,
-		&user.SlackUserID,
-		&user.Username,
-		&user.Team,
-		&user.CreatedAt,
-	)
+	var deactivatedAt sql.NullTime
+	err := row.Scan(&user.ID, &user.SlackUserID, &user.Username, &user.Team, &user.Role, &user.CreatedAt,
+		&user.DisplayName, &user.Phone, &deactivatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if deactivatedAt.Valid {
+		user.DeactivatedAt = &deactivatedAt.Time
+	}
+	return &user, nil
+}
 
+func GetUserBySlackID(slackUserID string) (*User, error) {
+	user, err := scanUser(db.DB.QueryRow(`SELECT `+userColumns+` FROM users WHERE slack_user_id = ?`, slackUserID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Return (nil, nil) to indicate "not found" without an error.
@@ -41,10 +73,12 @@ func GetUserBySlackID(slackUserID string) (*User, error) {
 		return nil, err // A real database error occurred.
 	}
 
-	return &user, nil
+	return user, nil
 }
 
-func CreateUser(slackUserID, username, team string) (*User, error) {
+// CreateUser adds a user on behalf of actor, who is usually the new user
+// themselves.
+func CreateUser(slackUserID, username, team string, actor Actor) (*User, error) {
 	if strings.TrimSpace(slackUserID) == "" {
 		return nil, fmt.Errorf("slack_user_id cannot be empty")
 	}
@@ -59,23 +93,41 @@ func CreateUser(slackUserID, username, team string) (*User, error) {
 		SlackUserID: slackUserID,
 		Username:    username,
 		Team:        team,
-		CreatedAt:   time.Now(),
+		Role:        RoleMember,
+		CreatedAt:   time.Now().UTC(),
 	}
 
 	query := `
-        INSERT INTO users (id, slack_user_id, username, team, created_at)
-        VALUES (?, ?, ?, ?, ?)
+        INSERT INTO users (id, slack_user_id, username, team, role, created_at)
+        VALUES (?, ?, ?, ?, ?, ?)
     `
 
-	_, err := db.DB.Exec(query, user.ID, user.SlackUserID, user.Username, user.Team, user.CreatedAt)
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(query, user.ID, user.SlackUserID, user.Username, user.Team, user.Role, user.CreatedAt); err != nil {
+		return nil, err
+	}
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionUserCreate,
+		entityType: "user",
+		entityID:   user.SlackUserID,
+		after:      user,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
-func GetOrCreateUserBySlackID(slackUserID, username, team string) (*User, error) {
+func GetOrCreateUserBySlackID(slackUserID, username, team string, actor Actor) (*User, error) {
 	user, err := GetUserBySlackID(slackUserID)
 	if err != nil {
 		return nil, fmt.Errorf("error checking for existing user: %w", err)
@@ -85,28 +137,100 @@ func GetOrCreateUserBySlackID(slackUserID, username, team string) (*User, error)
 		return user, nil
 	}
 
-	return CreateUser(slackUserID, username, team)
+	return CreateUser(slackUserID, username, team, actor)
 }
 
-func UpdateUser(user User) error {
+// UpdateUser saves the user's name, team and phone number on behalf of actor.
+// It returns ErrUserNotFound if the user has never used the bot.
+func UpdateUser(user User, actor Actor) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := getUserForUpdate(tx, user.SlackUserID)
+	if err != nil {
+		return err
+	}
+
 	query := `
         UPDATE users 
-        SET username = ?, team = ?
+        SET username = ?, team = ?, phone = ?
         WHERE slack_user_id = ?
     `
 
-	_, err := db.DB.Exec(query, user.Username, user.Team, user.SlackUserID)
-	return err
+	if _, err := tx.Exec(query, user.Username, user.Team, user.Phone, user.SlackUserID); err != nil {
+		return err
+	}
+
+	after := *before
+	after.Username = user.Username
+	after.Team = user.Team
+	after.Phone = user.Phone
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionUserUpdate,
+		entityType: "user",
+		entityID:   user.SlackUserID,
+		before:     before,
+		after:      after,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func GetAllUsers() ([]User, error) {
-	query := `
-        SELECT id, slack_user_id, username, team, created_at 
-        FROM users 
-        ORDER BY username
-    `
+// getUserForUpdate loads a user inside tx, returning ErrUserNotFound if the
+// user has never used the bot.
+func getUserForUpdate(tx *sql.Tx, slackUserID string) (*User, error) {
+	user, err := scanUser(tx.QueryRow(`SELECT `+userColumns+` FROM users WHERE slack_user_id = ?`, slackUserID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, slackUserID)
+		}
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	return user, nil
+}
 
-	rows, err := db.DB.Query(query)
+// SetUserRole changes a user's role on behalf of actor. It returns
+// ErrUserNotFound if the user has never used the bot.
+func SetUserRole(slackUserID string, role Role, actor Actor) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := getUserForUpdate(tx, slackUserID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET role = ? WHERE slack_user_id = ?`, role, slackUserID); err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	after := *before
+	after.Role = role
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionUserRole,
+		entityType: "user",
+		entityID:   slackUserID,
+		before:     before,
+		after:      after,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func GetAllUsers() ([]User, error) {
+	rows, err := db.DB.Query(`SELECT ` + userColumns + ` FROM users ORDER BY username`)
 	if err != nil {
 		return nil, err
 	}
@@ -114,19 +238,72 @@ func GetAllUsers() ([]User, error) {
 
 	var users []User
 	for rows.Next() {
-		var user User
-		err := rows.Scan(
-			&user.ID,
-			&user.SlackUserID,
-			&user.Username,
-			&user.Team,
-			&user.CreatedAt,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
-		users = append(users, user)
+		users = append(users, *user)
 	}
 
 	return users, nil
+}
+
+// SlackProfile is the part of a Slack users.info response kept on the user.
+type SlackProfile struct {
+	RealName    string
+	DisplayName string
+	Deleted     bool
+}
+
+// SyncSlackProfile refreshes the user's name from their Slack profile and
+// records when their account is deactivated, or clears that if it comes
+// back. deactivated is true only when this call is the one that noticed the
+// deactivation. Nothing is written or audited if nothing changed.
+func SyncSlackProfile(slackUserID string, profile SlackProfile, now time.Time, actor Actor) (deactivated bool, err error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := getUserForUpdate(tx, slackUserID)
+	if err != nil {
+		return false, err
+	}
+
+	after := *before
+	if name := strings.TrimSpace(profile.RealName); name != "" {
+		after.Username = name
+	}
+	after.DisplayName = strings.TrimSpace(profile.DisplayName)
+	switch {
+	case profile.Deleted && before.DeactivatedAt == nil:
+		at := now.UTC()
+		after.DeactivatedAt = &at
+		deactivated = true
+	case !profile.Deleted:
+		after.DeactivatedAt = nil
+	}
+	if after.Username == before.Username && after.DisplayName == before.DisplayName &&
+		(after.DeactivatedAt == nil) == (before.DeactivatedAt == nil) {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`UPDATE users SET username = ?, display_name = ?, deactivated_at = ? WHERE slack_user_id = ?`,
+		after.Username, after.DisplayName, after.DeactivatedAt, slackUserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to sync user: %w", err)
+	}
+	err = writeAuditEvent(tx, actor, auditEntry{
+		action:     ActionUserUpdate,
+		entityType: "user",
+		entityID:   slackUserID,
+		before:     before,
+		after:      after,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deactivated, tx.Commit()
 }
\ No newline at end of file
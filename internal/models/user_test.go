@@ -1,7 +1,11 @@
 package models
 
 import (
+	"database/sql"
+	"errors"
 	"testing"
+	"time"
+	db "truck-checkout/internal/database"
 )
 
 func TestGetUserBySlackID(t *testing.T) {
@@ -23,7 +27,7 @@ func TestGetUserBySlackID(t *testing.T) {
 		ResetTestDB(t)
 
 		// Create a test user
-		createdUser, err := CreateUser("U123456", "testuser", "forest_restoration")
+		createdUser, err := CreateUser("U123456", "testuser", "forest_restoration", testActor)
 		if err != nil {
 			t.Fatalf("Failed to create test user: %v", err)
 		}
@@ -57,7 +61,7 @@ func TestCreateUser(t *testing.T) {
 	t.Run("ValidUser", func(t *testing.T) {
 		ResetTestDB(t)
 
-		user, err := CreateUser("U789012", "newuser", "road_maintenance")
+		user, err := CreateUser("U789012", "newuser", "road_maintenance", testActor)
 		if err != nil {
 			t.Fatalf("Failed to create user: %v", err)
 		}
@@ -93,13 +97,13 @@ func TestCreateUser(t *testing.T) {
 		ResetTestDB(t)
 
 		// Create first user
-		_, err := CreateUser("U111111", "user1", "team1")
+		_, err := CreateUser("U111111", "user1", "team1", testActor)
 		if err != nil {
 			t.Fatalf("Failed to create first user: %v", err)
 		}
 
 		// Try to create second user with same slack_user_id
-		_, err = CreateUser("U111111", "user2", "team2")
+		_, err = CreateUser("U111111", "user2", "team2", testActor)
 		if err == nil {
 			t.Error("Expected error when creating user with duplicate slack_user_id")
 		}
@@ -109,19 +113,19 @@ func TestCreateUser(t *testing.T) {
 		ResetTestDB(t)
 
 		// Test with empty slack_user_id
-		_, err := CreateUser("", "username", "team")
+		_, err := CreateUser("", "username", "team", testActor)
 		if err == nil {
 			t.Error("Expected error when creating user with empty slack_user_id")
 		}
 
 		// Test with empty username
-		_, err = CreateUser("U222222", "", "team")
+		_, err = CreateUser("U222222", "", "team", testActor)
 		if err == nil {
 			t.Error("Expected error when creating user with empty username")
 		}
 
 		// Test with empty team
-		_, err = CreateUser("U333333", "username", "")
+		_, err = CreateUser("U333333", "username", "", testActor)
 		if err == nil {
 			t.Error("Expected error when creating user with empty team")
 		}
@@ -133,7 +137,7 @@ func TestUpdateUser(t *testing.T) {
 		ResetTestDB(t)
 
 		// Create a user
-		user, err := CreateUser("U444444", "originaluser", "originalteam")
+		user, err := CreateUser("U444444", "originaluser", "originalteam", testActor)
 		if err != nil {
 			t.Fatalf("Failed to create user: %v", err)
 		}
@@ -141,7 +145,7 @@ func TestUpdateUser(t *testing.T) {
 		// Update the user
 		user.Username = "updateduser"
 		user.Team = "updatedteam"
-		err = UpdateUser(*user)
+		err = UpdateUser(*user, testActor)
 		if err != nil {
 			t.Errorf("Failed to update user: %v", err)
 		}
@@ -174,10 +178,9 @@ func TestUpdateUser(t *testing.T) {
 			Username:    "ghost",
 			Team:        "phantom",
 		}
-		err := UpdateUser(nonexistentUser)
-		// This should not return an error in SQLite (it just affects 0 rows)
-		if err != nil {
-			t.Errorf("Unexpected error when updating nonexistent user: %v", err)
+		err := UpdateUser(nonexistentUser, testActor)
+		if !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("expected ErrUserNotFound updating a nonexistent user, got %v", err)
 		}
 	})
 }
@@ -210,7 +213,7 @@ func TestGetAllUsers(t *testing.T) {
 		}
 
 		for _, tu := range testUsers {
-			_, err := CreateUser(tu.slackID, tu.username, tu.team)
+			_, err := CreateUser(tu.slackID, tu.username, tu.team, testActor)
 			if err != nil {
 				t.Fatalf("Failed to create test user %s: %v", tu.username, err)
 			}
@@ -256,7 +259,7 @@ func TestUserModelIntegration(t *testing.T) {
 		}
 
 		// 2. Create user
-		_, err = CreateUser(slackID, "lifecycle_user", "test_team")
+		_, err = CreateUser(slackID, "lifecycle_user", "test_team", testActor)
 		if err != nil {
 			t.Fatalf("Failed to create user: %v", err)
 		}
@@ -273,7 +276,7 @@ func TestUserModelIntegration(t *testing.T) {
 		// 4. Update user
 		foundUser.Username = "updated_lifecycle_user"
 		foundUser.Team = "updated_team"
-		err = UpdateUser(*foundUser)
+		err = UpdateUser(*foundUser, testActor)
 		if err != nil {
 			t.Errorf("Failed to update user: %v", err)
 		}
@@ -307,3 +310,81 @@ func TestUserModelIntegration(t *testing.T) {
 		}
 	})
 }
+
+func TestCreateTables_AddsRoleToExistingUsers(t *testing.T) {
+	legacy, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer legacy.Close()
+
+	_, err = legacy.Exec(`
+		CREATE TABLE users (
+			id TEXT PRIMARY KEY,
+			slack_user_id TEXT NOT NULL UNIQUE,
+			username TEXT NOT NULL,
+			team TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO users (id, slack_user_id, username, team) VALUES ('1', 'U1', 'old', 'beltline');
+	`)
+	if err != nil {
+		t.Fatalf("failed to create legacy table: %v", err)
+	}
+
+	// Running it twice must be harmless
+	for i := 0; i < 2; i++ {
+		if err := db.CreateTables(legacy); err != nil {
+			t.Fatalf("failed to migrate tables: %v", err)
+		}
+	}
+
+	var role string
+	if err := legacy.QueryRow(`SELECT role FROM users WHERE id = '1'`).Scan(&role); err != nil {
+		t.Fatalf("failed to read role: %v", err)
+	}
+	if role != string(RoleMember) {
+		t.Errorf("expected existing users to become members, got %q", role)
+	}
+}
+
+func TestSyncSlackProfile(t *testing.T) {
+	ResetTestDB(t)
+
+	if _, err := CreateUser("U123", "jdoe", "beltline", testActor); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	now := time.Now()
+
+	deactivated, err := SyncSlackProfile("U123", SlackProfile{RealName: "Jane Doe", DisplayName: "jane"}, now, testActor)
+	if err != nil || deactivated {
+		t.Fatalf("expected a plain refresh, got %v (%v)", deactivated, err)
+	}
+	user, _ := GetUserBySlackID("U123")
+	if user.Username != "Jane Doe" || user.DisplayName != "jane" || user.DeactivatedAt != nil {
+		t.Errorf("expected refreshed names, got %+v", user)
+	}
+
+	// Only the first sync after deactivation reports it
+	if deactivated, err := SyncSlackProfile("U123", SlackProfile{RealName: "Jane Doe", DisplayName: "jane", Deleted: true}, now, testActor); err != nil || !deactivated {
+		t.Fatalf("expected deactivation to be reported, got %v (%v)", deactivated, err)
+	}
+	if deactivated, _ := SyncSlackProfile("U123", SlackProfile{RealName: "Jane Doe", Deleted: true}, now, testActor); deactivated {
+		t.Error("expected deactivation to be reported only once")
+	}
+	if user, _ := GetUserBySlackID("U123"); user.DeactivatedAt == nil {
+		t.Error("expected the user to be marked deactivated")
+	}
+
+	// Reactivated accounts are cleared
+	if _, err := SyncSlackProfile("U123", SlackProfile{RealName: "Jane Doe"}, now, testActor); err != nil {
+		t.Fatalf("failed to sync profile: %v", err)
+	}
+	if user, _ := GetUserBySlackID("U123"); user.DeactivatedAt != nil {
+		t.Error("expected the reactivated user to be cleared")
+	}
+
+	if _, err := SyncSlackProfile("U999", SlackProfile{RealName: "Ghost"}, now, testActor); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
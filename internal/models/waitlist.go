@@ -0,0 +1,297 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	db "truck-checkout/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting   WaitlistStatus = "waiting"
+	WaitlistOffered   WaitlistStatus = "offered"
+	WaitlistClaimed   WaitlistStatus = "claimed"
+	WaitlistExpired   WaitlistStatus = "expired"
+	WaitlistCancelled WaitlistStatus = "cancelled"
+)
+
+// WaitlistDayFormat is the layout used for the day a waitlist entry applies to.
+const WaitlistDayFormat = "2006-01-02"
+
+type WaitlistEntry struct {
+	ID             uuid.UUID      `json:"id"`
+	TruckID        *uuid.UUID     `json:"truck_id,omitempty"` // nil means any truck
+	SlackUserID    string         `json:"slack_user_id"`
+	UserName       string         `json:"user_name"`
+	Day            string         `json:"day"`
+	Status         WaitlistStatus `json:"status"`
+	OfferedTruckID *uuid.UUID     `json:"offered_truck_id,omitempty"`
+	OfferExpiresAt *time.Time     `json:"offer_expires_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+const waitlistColumns = `id, truck_id, slack_user_id, user_name, day, status, offered_truck_id, offer_expires_at, created_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWaitlistEntry(row rowScanner) (*WaitlistEntry, error) {
+	var entry WaitlistEntry
+	var truckID, offeredTruckID sql.NullString
+	var expiresAt sql.NullTime
+
+	err := row.Scan(&entry.ID, &truckID, &entry.SlackUserID, &entry.UserName, &entry.Day,
+		&entry.Status, &offeredTruckID, &expiresAt, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if truckID.Valid {
+		id, err := uuid.Parse(truckID.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing waitlist truck UUID: %w", err)
+		}
+		entry.TruckID = &id
+	}
+	if offeredTruckID.Valid {
+		id, err := uuid.Parse(offeredTruckID.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing offered truck UUID: %w", err)
+		}
+		entry.OfferedTruckID = &id
+	}
+	if expiresAt.Valid {
+		entry.OfferExpiresAt = &expiresAt.Time
+	}
+
+	return &entry, nil
+}
+
+// JoinWaitlist adds a user to the waitlist for a truck on a given day, taken
+// from day's date in its own location. A nil truckID puts the user on the
+// list for any truck.
+func JoinWaitlist(truckID *uuid.UUID, slackUserID, userName string, day time.Time) (*WaitlistEntry, error) {
+	if strings.TrimSpace(slackUserID) == "" {
+		return nil, fmt.Errorf("slack_user_id cannot be empty")
+	}
+
+	entry := WaitlistEntry{
+		ID:          uuid.New(),
+		TruckID:     truckID,
+		SlackUserID: slackUserID,
+		UserName:    userName,
+		Day:         day.Format(WaitlistDayFormat),
+		Status:      WaitlistWaiting,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	var truckIDArg any
+	if truckID != nil {
+		truckIDArg = truckID.String()
+	}
+
+	var existing int
+	err := db.DB.QueryRow(`
+		SELECT COUNT(*) FROM waitlist_entries
+		WHERE slack_user_id = ? AND day = ? AND truck_id IS ? AND status IN ('waiting', 'offered')
+	`, slackUserID, entry.Day, truckIDArg).Scan(&existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing waitlist entries: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrAlreadyWaitlisted
+	}
+
+	_, err = db.DB.Exec(`
+		INSERT INTO waitlist_entries (id, truck_id, slack_user_id, user_name, day, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, entry.ID.String(), truckIDArg, entry.SlackUserID, entry.UserName, entry.Day, entry.Status, entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// LeaveWaitlist cancels a user's open entries for a day and returns how many were removed.
+func LeaveWaitlist(slackUserID string, day time.Time) (int64, error) {
+	result, err := db.DB.Exec(`
+		UPDATE waitlist_entries SET status = ?
+		WHERE slack_user_id = ? AND day = ? AND status IN ('waiting', 'offered')
+	`, WaitlistCancelled, slackUserID, day.Format(WaitlistDayFormat))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func GetWaitlistEntryByID(id uuid.UUID) (*WaitlistEntry, error) {
+	row := db.DB.QueryRow(`SELECT `+waitlistColumns+` FROM waitlist_entries WHERE id = ?`, id.String())
+	return scanWaitlistEntry(row)
+}
+
+// OfferTruckToNextWaiter hands a freed truck to the first person waiting for
+// it (or for any truck) on one of days, taken from each day's date in its own
+// location. The earliest day goes first, then whoever joined first. The
+// offer is held until expiresAt. It returns (nil, nil) when nobody is
+// waiting or the truck already has an outstanding offer.
+func OfferTruckToNextWaiter(truckID uuid.UUID, days []time.Time, expiresAt time.Time) (*WaitlistEntry, error) {
+	if len(days) == 0 {
+		return nil, nil
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var outstanding int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM waitlist_entries
+		WHERE offered_truck_id = ? AND status = 'offered' AND offer_expires_at > ?
+	`, truckID.String(), time.Now().UTC()).Scan(&outstanding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check outstanding offers: %w", err)
+	}
+	if outstanding > 0 {
+		return nil, nil
+	}
+
+	// Someone waiting for any truck is only offered one their team may use,
+	// as CheckTeamAccess would refuse the rest when they claim it.
+	args := []any{truckID.String(), truckID.String()}
+	for _, day := range days {
+		args = append(args, day.Format(WaitlistDayFormat))
+	}
+	row := tx.QueryRow(`
+		SELECT `+waitlistColumns+` FROM waitlist_entries
+		WHERE status = 'waiting'
+		AND (truck_id = ? OR (truck_id IS NULL AND EXISTS (
+			SELECT 1 FROM trucks t LEFT JOIN users u ON u.slack_user_id = waitlist_entries.slack_user_id
+			WHERE t.id = ? AND (t.default_team IS NULL OR t.default_team = '' OR t.default_team = u.team)
+		)))
+		AND day IN (?`+strings.Repeat(", ?", len(days)-1)+`)
+		ORDER BY day, created_at, rowid
+		LIMIT 1
+	`, args...)
+	entry, err := scanWaitlistEntry(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find next waiter: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE waitlist_entries
+		SET status = 'offered', offered_truck_id = ?, offer_expires_at = ?
+		WHERE id = ?
+	`, truckID.String(), expiresAt.UTC(), entry.ID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark waitlist entry offered: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	entry.Status = WaitlistOffered
+	entry.OfferedTruckID = &truckID
+	entry.OfferExpiresAt = &expiresAt
+	return entry, nil
+}
+
+// ClaimWaitlistOffer accepts an outstanding offer on behalf of the user it was made to.
+func ClaimWaitlistOffer(id uuid.UUID, slackUserID string) (*WaitlistEntry, error) {
+	entry, err := GetWaitlistEntryByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOfferUnavailable
+		}
+		return nil, err
+	}
+	if entry.SlackUserID != slackUserID {
+		return nil, ErrPermissionDenied
+	}
+	if entry.Status != WaitlistOffered || entry.OfferExpiresAt == nil || !entry.OfferExpiresAt.After(time.Now()) {
+		return nil, ErrOfferUnavailable
+	}
+
+	result, err := db.DB.Exec(`
+		UPDATE waitlist_entries SET status = 'claimed'
+		WHERE id = ? AND status = 'offered'
+	`, id.String())
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrOfferUnavailable
+	}
+
+	entry.Status = WaitlistClaimed
+	return entry, nil
+}
+
+// AbandonWaitlistClaim gives up a claimed offer whose checkout fell through,
+// marking the entry expired so the truck can be offered to the next person.
+func AbandonWaitlistClaim(id uuid.UUID) error {
+	result, err := db.DB.Exec(`
+		UPDATE waitlist_entries SET status = 'expired'
+		WHERE id = ? AND status = 'claimed'
+	`, id.String())
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrOfferUnavailable
+	}
+	return nil
+}
+
+// ExpireWaitlistOffers marks offers that ran out before now as expired and
+// returns them so the truck can be offered to the next person.
+func ExpireWaitlistOffers(now time.Time) ([]WaitlistEntry, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT `+waitlistColumns+` FROM waitlist_entries
+		WHERE status = 'offered' AND offer_expires_at <= ?
+	`, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("querying expired offers: %w", err)
+	}
+
+	var expired []WaitlistEntry
+	for rows.Next() {
+		entry, err := scanWaitlistEntry(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning waitlist row: %w", err)
+		}
+		expired = append(expired, *entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("after row iteration: %w", err)
+	}
+
+	for i := range expired {
+		if _, err := tx.Exec(`UPDATE waitlist_entries SET status = 'expired' WHERE id = ?`, expired[i].ID.String()); err != nil {
+			return nil, fmt.Errorf("failed to expire offer: %w", err)
+		}
+		expired[i].Status = WaitlistExpired
+	}
+
+	return expired, tx.Commit()
+}
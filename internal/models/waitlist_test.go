@@ -0,0 +1,144 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestWaitlistOfferOrder(t *testing.T) {
+	ResetTestDB(t)
+
+	team := "beltline"
+	if err := InsertTruck("Tulip", &team, uuid.NewString(), false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to get truck: %v", err)
+	}
+
+	today := time.Now()
+
+	// Dana is on another team, so skipped for Tulip. First in line then
+	// wants any truck, second wants Tulip specifically, third is waiting
+	// for a different day.
+	for _, u := range []struct{ id, name, team string }{{"U000", "dana", "urban_trees"}, {"U111", "alice", "beltline"}} {
+		if _, err := CreateUser(u.id, u.name, u.team, testActor); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	if _, err := JoinWaitlist(nil, "U000", "dana", today); err != nil {
+		t.Fatalf("failed to join waitlist: %v", err)
+	}
+	first, err := JoinWaitlist(nil, "U111", "alice", today)
+	if err != nil {
+		t.Fatalf("failed to join waitlist: %v", err)
+	}
+	second, err := JoinWaitlist(&truck.ID, "U222", "bob", today)
+	if err != nil {
+		t.Fatalf("failed to join waitlist: %v", err)
+	}
+	third, err := JoinWaitlist(&truck.ID, "U333", "charlie", today.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("failed to join waitlist: %v", err)
+	}
+
+	if _, err := JoinWaitlist(&truck.ID, "U222", "bob", today); err == nil {
+		t.Error("expected error when joining the same waitlist twice")
+	}
+
+	offer, err := OfferTruckToNextWaiter(truck.ID, []time.Time{today}, time.Now().Add(15*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to offer truck: %v", err)
+	}
+	if offer == nil || offer.ID != first.ID {
+		t.Fatalf("expected offer to go to first waiter, got %+v", offer)
+	}
+	if offer.OfferedTruckID == nil || *offer.OfferedTruckID != truck.ID {
+		t.Error("expected offered truck to be recorded")
+	}
+
+	// While an offer is outstanding the truck is not offered again
+	again, err := OfferTruckToNextWaiter(truck.ID, []time.Time{today}, time.Now().Add(15*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to offer truck: %v", err)
+	}
+	if again != nil {
+		t.Errorf("expected no second offer while one is outstanding, got %+v", again)
+	}
+
+	// Nobody but the offeree can claim
+	if _, err := ClaimWaitlistOffer(offer.ID, "U222"); err == nil {
+		t.Error("expected error when another user claims the offer")
+	}
+
+	// Let the first offer lapse and move on to the next person
+	expired, err := ExpireWaitlistOffers(time.Now().Add(16 * time.Minute))
+	if err != nil {
+		t.Fatalf("failed to expire offers: %v", err)
+	}
+	if len(expired) != 1 || expired[0].ID != first.ID {
+		t.Fatalf("expected first offer to expire, got %+v", expired)
+	}
+	if _, err := ClaimWaitlistOffer(first.ID, "U111"); err == nil {
+		t.Error("expected error when claiming an expired offer")
+	}
+
+	offer, err = OfferTruckToNextWaiter(truck.ID, []time.Time{today}, time.Now().Add(15*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to offer truck: %v", err)
+	}
+	if offer == nil || offer.ID != second.ID {
+		t.Fatalf("expected offer to go to second waiter, got %+v", offer)
+	}
+
+	claimed, err := ClaimWaitlistOffer(offer.ID, "U222")
+	if err != nil {
+		t.Fatalf("failed to claim offer: %v", err)
+	}
+	if claimed.Status != WaitlistClaimed {
+		t.Errorf("expected status claimed, got %s", claimed.Status)
+	}
+
+	// Nobody else is waiting today
+	offer, err = OfferTruckToNextWaiter(truck.ID, []time.Time{today}, time.Now().Add(15*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to offer truck: %v", err)
+	}
+	if offer != nil {
+		t.Errorf("expected no waiters left for today, got %+v", offer)
+	}
+
+	// A truck freed for tomorrow too goes to tomorrow's waiter
+	offer, err = OfferTruckToNextWaiter(truck.ID, []time.Time{today, today.AddDate(0, 0, 1)}, time.Now().Add(15*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to offer truck: %v", err)
+	}
+	if offer == nil || offer.ID != third.ID {
+		t.Errorf("expected offer to go to tomorrow's waiter, got %+v", offer)
+	}
+}
+
+func TestLeaveWaitlist(t *testing.T) {
+	ResetTestDB(t)
+
+	today := time.Now()
+	if _, err := JoinWaitlist(nil, "U111", "alice", today); err != nil {
+		t.Fatalf("failed to join waitlist: %v", err)
+	}
+
+	removed, err := LeaveWaitlist("U111", today)
+	if err != nil {
+		t.Fatalf("failed to leave waitlist: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 entry removed, got %d", removed)
+	}
+
+	// Rejoining after leaving is allowed
+	if _, err := JoinWaitlist(nil, "U111", "alice", today); err != nil {
+		t.Errorf("failed to rejoin waitlist: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"truck-checkout/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/slack-go/slack"
+)
+
+// announcementText is the announce channel line for a checkout.
+func (h *Handler) announcementText(userName, truckName string, assets []models.Asset, start, end time.Time) string {
+	return fmt.Sprintf("🚛 *%s* checked out truck *%s*%s (%s)", userName, truckName, withAssets(assets), h.formatDateRange(start, end))
+}
+
+// announcementBlocks lays out an announcement, with the Release, Extend and
+// Request handoff buttons while the checkout is still open.
+func announcementBlocks(text string, checkoutID uuid.UUID, withButtons bool) []slack.Block {
+	blocks := []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil)}
+	if !withButtons {
+		return blocks
+	}
+
+	id := checkoutID.String()
+	release := slack.NewButtonBlockElement("release_checkout", id, slack.NewTextBlockObject("plain_text", "Release", false, false))
+	extend := slack.NewButtonBlockElement("extend_checkout", id, slack.NewTextBlockObject("plain_text", "Extend a day", false, false))
+	handoff := slack.NewButtonBlockElement("request_handoff", id, slack.NewTextBlockObject("plain_text", "Request handoff", false, false))
+	return append(blocks, slack.NewActionBlock("checkout_actions", release, extend, handoff))
+}
+
+// announceCheckout posts a checkout to the announce channel and remembers the
+// message so later changes can update it in place. The checkout has already
+// been made by then, so a failed post is only logged.
+func (h *Handler) announceCheckout(client Messenger, checkout models.Checkout, userName string, truckName string, assets []models.Asset) {
+	text := h.announcementText(userName, truckName, assets, checkout.StartDate, checkout.EndDate)
+
+	channel, ts, err := client.PostMessage(h.cfg.AnnounceChannel,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(announcementBlocks(text, checkout.ID, true)...))
+	if err != nil {
+		log.Printf("Failed to post message to #%s: %v", h.cfg.AnnounceChannel, err)
+		return
+	}
+	if err := models.SetCheckoutAnnouncement(checkout.ID, channel, ts); err != nil {
+		log.Printf("Failed to save announcement of checkout %s: %v", checkout.ID, err)
+	}
+}
+
+// updateAnnouncement rewrites a checkout's announcement with text. It
+// returns false if the checkout has no announcement on record or the update
+// failed, in which case callers post a new message instead.
+func updateAnnouncement(client Messenger, checkout *models.Checkout, text string, withButtons bool) bool {
+	if checkout == nil || checkout.AnnounceTS == "" {
+		return false
+	}
+	err := client.UpdateMessage(checkout.AnnounceChannel, checkout.AnnounceTS,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(announcementBlocks(text, checkout.ID, withButtons)...))
+	if err != nil {
+		log.Printf("Failed to update announcement of checkout %s: %v", checkout.ID, err)
+		return false
+	}
+	return true
+}
+
+// checkoutAnnouncementText rebuilds the announcement line of a stored
+// checkout.
+func (h *Handler) checkoutAnnouncementText(checkout *models.Checkout, truckName string) string {
+	assets, err := models.GetCheckoutAssets(checkout.ID)
+	if err != nil {
+		log.Printf("Failed to load assets of checkout %s: %v", checkout.ID, err)
+	}
+	return h.announcementText(checkout.UserName, truckName, assets, checkout.StartDate, checkout.EndDate)
+}
+
+// announceRelease marks the checkout's announcement as released, or posts a
+// new message if there isn't one to update.
+func (h *Handler) announceRelease(client Messenger, truck *models.Truck, checkout *models.Checkout, userName string) {
+	text := fmt.Sprintf("%s\n✅ Released by *%s* at %s", h.checkoutAnnouncementText(checkout, truck.Name), userName,
+		h.cal.Now().Format("3:04 PM"))
+	if updateAnnouncement(client, checkout, text, false) {
+		return
+	}
+
+	message := fmt.Sprintf("🚛 *%s* released truck *%s* (previously checked out by %s)", userName, truck.Name, checkout.UserName)
+	if _, _, err := client.PostMessage(h.cfg.AnnounceChannel, slack.MsgOptionText(message, false)); err != nil {
+		log.Printf("Failed to post message to #%s: %v", h.cfg.AnnounceChannel, err)
+	}
+}
+
+// replyEphemeral answers a button click with a message only the clicker sees.
+func replyEphemeral(client Messenger, callback *slack.InteractionCallback, text string, blocks ...slack.Block) {
+	options := []slack.MsgOption{slack.MsgOptionText(text, false)}
+	if len(blocks) > 0 {
+		options = append(options, slack.MsgOptionBlocks(blocks...))
+	}
+	if err := client.PostEphemeral(callback.Channel.ID, callback.User.ID, options...); err != nil {
+		log.Printf("Failed to reply to %s: %v", callback.User.ID, err)
+	}
+}
+
+// announcedCheckout loads the checkout and truck behind an announcement
+// button, replying with the error if either is gone.
+func (h *Handler) announcedCheckout(client Messenger, callback *slack.InteractionCallback, value string) (*models.Checkout, *models.Truck, bool) {
+	checkoutID, err := uuid.Parse(value)
+	if err != nil {
+		log.Printf("Invalid checkout ID %q: %v", value, err)
+		return nil, nil, false
+	}
+	checkout, err := models.GetCheckoutByID(checkoutID)
+	if err != nil {
+		replyEphemeral(client, callback, h.errorMessage(models.ErrCheckoutNotFound, ""))
+		return nil, nil, false
+	}
+	truck, err := models.GetTruckByID(checkout.TruckID)
+	if err != nil {
+		replyEphemeral(client, callback, h.errorMessage(err, ""))
+		return nil, nil, false
+	}
+	return checkout, truck, true
+}
+
+// handleReleaseButton releases the checkout from its announcement, the same
+// as `/release` would.
+func (h *Handler) handleReleaseButton(client Messenger, callback *slack.InteractionCallback, value string) {
+	checkout, truck, ok := h.announcedCheckout(client, callback, value)
+	if !ok {
+		return
+	}
+
+	// The button stays on the message until it's updated, so make sure this
+	// checkout is the one the truck is out on right now.
+	if checkout.StartDate.After(h.cal.Now()) {
+		replyEphemeral(client, callback, fmt.Sprintf("ℹ️ This checkout of `%s` hasn't started yet. Use `/cancel` to cancel it instead.", truck.Name))
+		return
+	}
+	active, err := models.GetActiveCheckoutByTruckID(truck.ID)
+	if err != nil || active.ID != checkout.ID {
+		replyEphemeral(client, callback, h.errorMessage(models.ErrNoActiveCheckout, truck.Name))
+		return
+	}
+
+	user, err := h.currentUser(callback.User.ID)
+	if err != nil {
+		replyEphemeral(client, callback, "❌ Error retrieving user information.")
+		return
+	}
+	actor := models.Actor{SlackUserID: callback.User.ID, Source: models.SourceButton}
+	override, err := models.AuthorizeCheckoutChange(user, checkout)
+	if err != nil {
+		log.Printf("Warning: User %s tried to release truck %s held by %s", callback.User.ID, truck.Name, checkout.UserID)
+		replyEphemeral(client, callback, fmt.Sprintf("🚫 Truck `%s` is checked out by %s. Only they, their team lead or a fleet admin can release it.", truck.Name, checkout.UserName))
+		return
+	}
+	if override {
+		log.Printf("Override: %s (%s) released truck %s held by %s", callback.User.Name, user.Role, truck.Name, checkout.UserName)
+		auditOverride(actor, "checkout", checkout.ID.String(), &truck.ID,
+			fmt.Sprintf("%s released a checkout held by %s", user.Role, checkout.UserID))
+	}
+
+	text, outstanding, err := h.releaseTruck(client, truck, checkout, callback.User.Name, actor, false)
+	if err != nil {
+		replyEphemeral(client, callback, h.errorMessage(err, truck.Name))
+		return
+	}
+	if len(outstanding) > 0 {
+		text, blocks := custodyReminderBlocks(text, truck, outstanding)
+		replyEphemeral(client, callback, text, blocks...)
+		return
+	}
+	replyEphemeral(client, callback, text)
+}
+
+// handleExtendButton extends the checkout by one business day, subject to
+// the holder's license and certification and the maximum length and blackout
+// dates. A fleet admin stopped by those is
+// offered an "Extend anyway" button, which calls this again with overridePolicy.
+func (h *Handler) handleExtendButton(client Messenger, callback *slack.InteractionCallback, value string, overridePolicy bool) {
+	checkout, truck, ok := h.announcedCheckout(client, callback, value)
+	if !ok {
+		return
+	}
+
+	user, err := h.currentUser(callback.User.ID)
+	if err != nil {
+		replyEphemeral(client, callback, "❌ Error retrieving user information.")
+		return
+	}
+	actor := models.Actor{SlackUserID: callback.User.ID, Source: models.SourceButton}
+	override, err := models.AuthorizeCheckoutChange(user, checkout)
+	if err != nil {
+		replyEphemeral(client, callback, fmt.Sprintf("🚫 Truck `%s` is checked out by %s. Only they, their team lead or a fleet admin can extend it.", truck.Name, checkout.UserName))
+		return
+	}
+	if overridePolicy && !user.IsFleetAdmin() {
+		replyEphemeral(client, callback, h.errorMessage(models.ErrPermissionDenied, truck.Name))
+		return
+	}
+
+	newEnd := h.cal.ExtendEnd(checkout.EndDate, 1)
+	if err := models.CheckDriverQualified(checkout.UserID, truck, newEnd); err != nil {
+		replyEphemeral(client, callback, h.errorMessage(err, truck.Name))
+		return
+	}
+	request := models.CheckoutRequest{
+		TruckName:    truck.Name,
+		UserID:       checkout.UserID,
+		TeamName:     checkout.TeamName,
+		Start:        checkout.StartDate,
+		End:          newEnd,
+		BusinessDays: h.cal.BusinessDaysIn(checkout.StartDate, newEnd),
+		Now:          h.cal.Now(),
+	}
+	policy := h.cfg.CheckoutPolicy()
+	err = policy.CheckExtension(request)
+	var policyErr *models.PolicyError
+	if errors.As(err, &policyErr) && overridePolicy {
+		request.Override = true
+		if err = policy.CheckExtension(request); err == nil {
+			log.Printf("Override: fleet admin %s extended checkout %s despite %v", callback.User.Name, checkout.ID, policyErr)
+			if err := models.RecordOverride(actor, "checkout", checkout.ID.String(), &truck.ID, policyErr.Violations); err != nil {
+				log.Printf("Failed to audit policy override: %v", err)
+			}
+		}
+	}
+	if err != nil {
+		text := h.errorMessage(err, truck.Name)
+		if canOverride(err, user) {
+			anyway := slack.NewButtonBlockElement("extend_checkout_override", checkout.ID.String(),
+				slack.NewTextBlockObject("plain_text", "Extend anyway", false, false))
+			anyway.Style = slack.StyleDanger
+			replyEphemeral(client, callback, text,
+				slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+				slack.NewActionBlock("extend_override", anyway))
+			return
+		}
+		replyEphemeral(client, callback, text)
+		return
+	}
+
+	extended, err := models.ExtendCheckout(checkout.ID, newEnd, actor)
+	if err != nil {
+		log.Printf("Failed to extend checkout %s: %v", checkout.ID, err)
+		replyEphemeral(client, callback, h.errorMessage(err, truck.Name))
+		return
+	}
+	if override {
+		log.Printf("Override: %s (%s) extended truck %s held by %s", callback.User.Name, user.Role, truck.Name, checkout.UserName)
+		auditOverride(actor, "checkout", checkout.ID.String(), &truck.ID,
+			fmt.Sprintf("%s extended a checkout held by %s", user.Role, checkout.UserID))
+	}
+	log.Printf("Checkout %s of truck %s extended to %s by %s", extended.ID, truck.Name, newEnd, callback.User.Name)
+	h.moveCalendarEvent(truck, extended)
+
+	dates := h.formatDateRange(extended.StartDate, extended.EndDate)
+	if !updateAnnouncement(client, extended, h.checkoutAnnouncementText(extended, truck.Name), true) {
+		message := fmt.Sprintf("🚛 *%s* extended truck *%s* (%s)", callback.User.Name, truck.Name, dates)
+		if _, _, err := client.PostMessage(h.cfg.AnnounceChannel, slack.MsgOptionText(message, false)); err != nil {
+			log.Printf("Failed to post message to #%s: %v", h.cfg.AnnounceChannel, err)
+		}
+	}
+	replyEphemeral(client, callback, fmt.Sprintf("✅ Truck `%s` is now checked out for %s.", truck.Name, dates))
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"truck-checkout/internal/models"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// How many events `/audit Tulip` shows.
+const auditHistoryLimit = 15
+
+// HandleAudit shows a truck's recent history, e.g. `/audit Tulip`, or DMs a
+// CSV export of a month's events, e.g. `/audit export 2026-10`. Both are for
+// fleet admins only.
+func (h *Handler) HandleAudit(client *socketmode.Client, req Responder, args []string, userId string) {
+	actor, err := h.currentUser(userId)
+	if err != nil {
+		req.Ack(map[string]string{"text": "❌ Error retrieving user information."})
+		return
+	}
+	if !actor.IsFleetAdmin() {
+		log.Printf("Warning: User %s tried to run /audit without being a fleet admin", userId)
+		req.Ack(map[string]string{"text": h.errorMessage(models.ErrPermissionDenied, "")})
+		return
+	}
+
+	if len(args) == 0 || len(args) > 2 {
+		req.Ack(map[string]string{"text": "ℹ️ Use `/audit [truck-name]` or `/audit export [YYYY-MM]`."})
+		return
+	}
+
+	if strings.EqualFold(args[0], "export") {
+		h.handleAuditExport(client, req, args[1:], userId)
+		return
+	}
+
+	truckName := cases.Title(language.English).String(strings.ToLower(args[0]))
+	truck, err := models.GetTruckByName(truckName)
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+
+	events, err := models.GetAuditEventsByTruckID(truck.ID, auditHistoryLimit)
+	if err != nil {
+		log.Printf("Failed to load audit events for %s: %v", truckName, err)
+		req.Ack(map[string]string{"text": "❌ Could not load the audit log."})
+		return
+	}
+	if len(events) == 0 {
+		req.Ack(map[string]string{"text": fmt.Sprintf("ℹ️ No recorded activity for `%s`.", truckName)})
+		return
+	}
+
+	msg := fmt.Sprintf("🗂️ *Recent activity for %s:*\n", truckName)
+	for _, e := range events {
+		msg += fmt.Sprintf("• %s — <@%s> %s (%s)\n",
+			e.OccurredAt.In(h.cal.Location()).Format("Jan 2 3:04 PM"), e.ActorID, e.Action, strings.ReplaceAll(string(e.Source), "_", " "))
+	}
+	req.Ack(map[string]string{"text": msg})
+}
+
+func (h *Handler) handleAuditExport(client *socketmode.Client, req Responder, args []string, userId string) {
+	month := h.cal.Now()
+	if len(args) > 0 {
+		parsed, err := time.ParseInLocation("2006-01", args[0], h.cal.Location())
+		if err != nil {
+			req.Ack(map[string]string{"text": "⚠️ Invalid month. Use `/audit export 2026-10`."})
+			return
+		}
+		month = parsed
+	}
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, h.cal.Location())
+	to := from.AddDate(0, 1, 0)
+
+	events, err := models.GetAuditEventsBetween(from, to)
+	if err != nil {
+		log.Printf("Failed to load audit events for export: %v", err)
+		req.Ack(map[string]string{"text": "❌ Could not load the audit log."})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := models.WriteAuditCSV(&buf, events); err != nil {
+		log.Printf("Failed to write audit export: %v", err)
+		req.Ack(map[string]string{"text": "❌ Could not build the audit export."})
+		return
+	}
+
+	req.Ack(map[string]string{
+		"text": fmt.Sprintf("📤 Sending %d events for %s by DM.", len(events), from.Format("January 2006")),
+	})
+
+	channel, _, _, err := client.OpenConversation(&slack.OpenConversationParameters{Users: []string{userId}})
+	if err != nil {
+		log.Printf("Failed to open DM with %s for audit export: %v", userId, err)
+		return
+	}
+	_, err = client.UploadFileV2(slack.UploadFileV2Parameters{
+		Reader:   &buf,
+		FileSize: buf.Len(),
+		Filename: fmt.Sprintf("audit-%s.csv", from.Format("2006-01")),
+		Title:    fmt.Sprintf("Truck audit log, %s", from.Format("January 2006")),
+		Channel:  channel.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to upload audit export for %s: %v", userId, err)
+	}
+}
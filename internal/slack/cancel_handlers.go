@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"truck-checkout/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/slack-go/slack"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// HandleCancel cancels one of the user's upcoming reservations. With no
+// arguments it shows a picker of them; `/cancel Tulip` cancels the next
+// reservation of that truck.
+func (h *Handler) HandleCancel(client Messenger, req Responder, args []string, userId string, userName string) {
+	if len(args) > 1 {
+		req.Ack(map[string]string{"text": "⚠️ Too many arguments. Try `/cancel` or `/cancel Tulip`"})
+		return
+	}
+
+	upcoming, err := models.GetUpcomingCheckoutsByUserID(userId, h.cal.Now())
+	if err != nil {
+		log.Printf("Failed to load upcoming reservations for %s: %v", userId, err)
+		req.Ack(map[string]string{"text": h.errorMessage(err, "")})
+		return
+	}
+	if len(upcoming) == 0 {
+		req.Ack(map[string]string{"text": "ℹ️ You have no upcoming reservations to cancel."})
+		return
+	}
+
+	if len(args) == 0 {
+		req.Ack(h.cancelPicker(upcoming))
+		return
+	}
+
+	truckName := cases.Title(language.English).String(strings.ToLower(args[0]))
+	truck, err := models.GetTruckByName(truckName)
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+	for _, checkout := range upcoming {
+		if checkout.TruckID != truck.ID {
+			continue
+		}
+		user, err := h.currentUser(userId)
+		if err != nil {
+			req.Ack(map[string]string{"text": "❌ Error retrieving user information."})
+			return
+		}
+		actor := models.Actor{SlackUserID: userId, Source: models.SourceSlashCommand}
+		text, err := h.cancelReservation(client, &checkout, truck, user, userName, actor)
+		if err != nil {
+			text = h.errorMessage(err, truckName)
+		}
+		req.Ack(map[string]string{"text": text})
+		return
+	}
+	req.Ack(map[string]string{"text": fmt.Sprintf("ℹ️ You have no upcoming reservation for `%s`.", truckName)})
+}
+
+// seriesOptionPrefix marks picker values that cancel the rest of a series
+// rather than a single checkout.
+const seriesOptionPrefix = "series:"
+
+// Slack allows at most this many options in a static select.
+const maxSelectOptions = 100
+
+// cancelPicker builds the reply listing upcoming reservations in a select
+// menu; choosing one cancels it. Recurring reservations also get an option
+// to cancel every remaining occurrence.
+func (h *Handler) cancelPicker(upcoming []models.Checkout) map[string]interface{} {
+	text := "🗓️ Which reservation do you want to cancel?"
+	return map[string]interface{}{
+		"text": text,
+		"blocks": []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+			h.cancelMenu(upcoming),
+		},
+	}
+}
+
+// cancelMenu is the select menu of reservations used by cancelPicker.
+func (h *Handler) cancelMenu(upcoming []models.Checkout) *slack.ActionBlock {
+	var options []*slack.OptionBlockObject
+	remaining := make(map[uuid.UUID]int)
+	var seriesIDs []uuid.UUID
+	for _, checkout := range upcoming {
+		if checkout.SeriesID == nil {
+			continue
+		}
+		if remaining[*checkout.SeriesID] == 0 {
+			seriesIDs = append(seriesIDs, *checkout.SeriesID)
+		}
+		remaining[*checkout.SeriesID]++
+	}
+	for _, id := range seriesIDs {
+		series, err := models.GetCheckoutSeriesByID(id)
+		if err != nil {
+			log.Printf("Failed to load checkout series %s: %v", id, err)
+			continue
+		}
+		label := fmt.Sprintf("All %d remaining, %s", remaining[id], h.seriesSummary(*series))
+		if truck, err := models.GetTruckByID(series.TruckID); err == nil {
+			label = truck.Name + ", " + label
+		}
+		options = append(options, slack.NewOptionBlockObject(
+			seriesOptionPrefix+id.String(),
+			slack.NewTextBlockObject("plain_text", truncateLabel(label), false, false),
+			nil,
+		))
+	}
+	for _, checkout := range upcoming {
+		if len(options) == maxSelectOptions {
+			break
+		}
+		label := h.formatDateRange(checkout.StartDate.In(h.cal.Location()), checkout.EndDate.In(h.cal.Location()))
+		if truck, err := models.GetTruckByID(checkout.TruckID); err == nil {
+			label = truck.Name + ", " + label
+		}
+		options = append(options, slack.NewOptionBlockObject(
+			checkout.ID.String(),
+			slack.NewTextBlockObject("plain_text", truncateLabel(label), false, false),
+			nil,
+		))
+	}
+
+	return slack.NewActionBlock("cancel_reservation",
+		slack.NewOptionsSelectBlockElement(
+			"static_select",
+			slack.NewTextBlockObject("plain_text", "Choose a reservation...", false, false),
+			"cancel_checkout",
+			options...,
+		),
+	)
+}
+
+// truncateLabel keeps option text within Slack's 75 character limit.
+func truncateLabel(label string) string {
+	if r := []rune(label); len(r) > 75 {
+		return string(r[:74]) + "…"
+	}
+	return label
+}
+
+// handleCancelSelection cancels the reservation picked from the /cancel menu
+// and replaces the menu with the outcome.
+func (h *Handler) handleCancelSelection(client Messenger, callback *slack.InteractionCallback, value string) {
+	reply := func(text string) {
+		err := client.Respond(callback.ResponseURL, &slack.WebhookMessage{Text: text, ReplaceOriginal: true})
+		if err != nil {
+			log.Printf("Failed to reply to %s: %v", callback.User.ID, err)
+		}
+	}
+
+	if strings.HasPrefix(value, seriesOptionPrefix) {
+		reply(h.cancelSeriesRemainder(client, callback, strings.TrimPrefix(value, seriesOptionPrefix)))
+		return
+	}
+
+	checkoutID, err := uuid.Parse(value)
+	if err != nil {
+		log.Printf("Invalid checkout ID %q: %v", value, err)
+		return
+	}
+	checkout, err := models.GetCheckoutByID(checkoutID)
+	if err != nil {
+		reply(h.errorMessage(models.ErrCheckoutNotFound, ""))
+		return
+	}
+	truck, err := models.GetTruckByID(checkout.TruckID)
+	if err != nil {
+		reply(h.errorMessage(err, ""))
+		return
+	}
+	user, err := h.currentUser(callback.User.ID)
+	if err != nil {
+		reply("❌ Error retrieving user information.")
+		return
+	}
+
+	actor := models.Actor{SlackUserID: callback.User.ID, Source: models.SourceButton}
+	text, err := h.cancelReservation(client, checkout, truck, user, callback.User.Name, actor)
+	if err != nil {
+		text = h.errorMessage(err, truck.Name)
+	}
+	reply(text)
+}
+
+// cancelReservation cancels checkout if user may change it, then tidies up
+// after it: the calendar event, the announcement, a DM to the holder when
+// someone else cancelled, and the waitlist for the days it freed up.
+func (h *Handler) cancelReservation(client Messenger, checkout *models.Checkout, truck *models.Truck, user *models.User, userName string, actor models.Actor) (string, error) {
+	override, err := models.AuthorizeCheckoutChange(user, checkout)
+	if err != nil {
+		log.Printf("Warning: User %s tried to cancel %s's reservation of %s", actor.SlackUserID, checkout.UserID, truck.Name)
+		return "", err
+	}
+
+	cancelled, err := models.CancelCheckout(checkout.ID, actor)
+	if err != nil {
+		return "", err
+	}
+	if override {
+		log.Printf("Override: %s (%s) cancelled %s's reservation of %s", userName, user.Role, checkout.UserName, truck.Name)
+		auditOverride(actor, "checkout", checkout.ID.String(), &truck.ID,
+			fmt.Sprintf("%s cancelled a reservation held by %s", user.Role, checkout.UserID))
+	}
+
+	h.deleteCalendarEvent(truck, cancelled)
+
+	loc := h.cal.Location()
+	dates := h.formatDateRange(cancelled.StartDate.In(loc), cancelled.EndDate.In(loc))
+	// Update the reservation's announcement so its buttons go away, or post
+	// a new message if there isn't one to update.
+	text := fmt.Sprintf("%s\n🗓️ Cancelled by *%s*", h.checkoutAnnouncementText(cancelled, truck.Name), userName)
+	if !updateAnnouncement(client, cancelled, text, false) {
+		message := fmt.Sprintf("🗓️ *%s* cancelled the reservation of truck *%s* for %s", userName, truck.Name, dates)
+		if override {
+			message += fmt.Sprintf(" (booked by %s)", cancelled.UserName)
+		}
+		if _, _, err := client.PostMessage(h.cfg.AnnounceChannel, slack.MsgOptionText(message, false)); err != nil {
+			log.Printf("Failed to post message to #%s: %v", h.cfg.AnnounceChannel, err)
+		}
+	}
+
+	if override {
+		notice := fmt.Sprintf("🗓️ Your reservation of truck *%s* for %s was cancelled by <@%s>.", truck.Name, dates, actor.SlackUserID)
+		if err := client.DirectMessage(cancelled.UserID, slack.MsgOptionText(notice, false)); err != nil {
+			log.Printf("Failed to DM %s about cancelled reservation: %v", cancelled.UserID, err)
+		}
+	}
+
+	h.offerFreedTruck(client, truck, cancelled.StartDate, cancelled.EndDate)
+
+	log.Printf("Reservation %s of truck %s cancelled by %s", cancelled.ID, truck.Name, userName)
+	if override {
+		return fmt.Sprintf("✅ %s's reservation of `%s` for %s has been cancelled.", cancelled.UserName, truck.Name, dates), nil
+	}
+	return fmt.Sprintf("✅ Your reservation of `%s` for %s has been cancelled.", truck.Name, dates), nil
+}
+
+// cancelSeriesRemainder cancels every occurrence of a series that hasn't
+// started yet, offers each freed window to the waitlist and returns the text
+// to show the user.
+func (h *Handler) cancelSeriesRemainder(client Messenger, callback *slack.InteractionCallback, value string) string {
+	seriesID, err := uuid.Parse(value)
+	if err != nil {
+		log.Printf("Invalid series ID %q: %v", value, err)
+		return h.errorMessage(models.ErrCheckoutNotFound, "")
+	}
+	series, err := models.GetCheckoutSeriesByID(seriesID)
+	if err != nil {
+		return h.errorMessage(err, "")
+	}
+	truck, err := models.GetTruckByID(series.TruckID)
+	if err != nil {
+		return h.errorMessage(err, "")
+	}
+	user, err := h.currentUser(callback.User.ID)
+	if err != nil {
+		return "❌ Error retrieving user information."
+	}
+
+	actor := models.Actor{SlackUserID: callback.User.ID, Source: models.SourceButton}
+	override, err := models.AuthorizeCheckoutChange(user, &models.Checkout{UserID: series.UserID, TeamName: series.TeamName})
+	if err != nil {
+		log.Printf("Warning: User %s tried to cancel %s's recurring reservation of %s", actor.SlackUserID, series.UserID, truck.Name)
+		return h.errorMessage(err, truck.Name)
+	}
+
+	cancelled, err := models.CancelSeries(seriesID, h.cal.Now(), actor)
+	if err != nil {
+		log.Printf("Failed to cancel series %s: %v", seriesID, err)
+		return h.errorMessage(err, truck.Name)
+	}
+	if len(cancelled) == 0 {
+		return h.errorMessage(models.ErrCheckoutNotFound, truck.Name)
+	}
+	if override {
+		log.Printf("Override: %s (%s) cancelled %s's recurring reservation of %s", callback.User.Name, user.Role, series.UserName, truck.Name)
+		auditOverride(actor, "checkout_series", series.ID.String(), &truck.ID,
+			fmt.Sprintf("%s cancelled a recurring reservation held by %s", user.Role, series.UserID))
+	}
+	for i := range cancelled {
+		h.deleteCalendarEvent(truck, &cancelled[i])
+	}
+
+	when := h.seriesSummary(*series)
+	message := fmt.Sprintf("🗓️ *%s* cancelled the %d remaining occurrences of truck *%s* %s", callback.User.Name, len(cancelled), truck.Name, when)
+	if _, _, err := client.PostMessage(h.cfg.AnnounceChannel, slack.MsgOptionText(message, false)); err != nil {
+		log.Printf("Failed to post message to #%s: %v", h.cfg.AnnounceChannel, err)
+	}
+	if override {
+		notice := fmt.Sprintf("🗓️ Your recurring reservation of truck *%s* %s was cancelled by <@%s>.", truck.Name, when, actor.SlackUserID)
+		if err := client.DirectMessage(series.UserID, slack.MsgOptionText(notice, false)); err != nil {
+			log.Printf("Failed to DM %s about cancelled series: %v", series.UserID, err)
+		}
+	}
+
+	for _, c := range cancelled {
+		h.offerFreedTruck(client, truck, c.StartDate, c.EndDate)
+	}
+
+	log.Printf("Series %s of truck %s cancelled by %s (%d occurrences)", series.ID, truck.Name, callback.User.Name, len(cancelled))
+	return fmt.Sprintf("✅ Cancelled the %d remaining occurrences of `%s` %s.", len(cancelled), truck.Name, when)
+}
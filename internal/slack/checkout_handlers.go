@@ -1,82 +1,163 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
+	"truck-checkout/internal/calendar"
 	"truck-checkout/internal/models"
 
 	"github.com/google/uuid"
-	"github.com/slack-go/slack"
-	"github.com/slack-go/slack/socketmode"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
-// Helper function to check if a day is a business day (Monday-Friday)
-func isValidDay(t time.Time) bool {
-	weekday := t.Weekday()
-	return weekday >= time.Monday && weekday <= time.Saturday
+// addCalendarEvent puts a new checkout on its truck's Google calendar and
+// records the event so it can be moved or deleted later.
+func (h *Handler) addCalendarEvent(truck *models.Truck, checkout *models.Checkout) {
+	if h.calendarService == nil || truck.GoogleCalendarID == "" {
+		return
+	}
+	summary := fmt.Sprintf("%s (%s)", checkout.UserName, checkout.TeamName)
+	eventID, err := calendar.InsertEvent(h.calendarService, truck.GoogleCalendarID, summary, checkout.StartDate, checkout.EndDate)
+	if err != nil {
+		log.Printf("Failed to add calendar event for truck %s: %v", truck.Name, err)
+		return
+	}
+	if err := models.SetCheckoutCalendarEvent(checkout.ID, eventID); err != nil {
+		log.Printf("Failed to save calendar event of checkout %s: %v", checkout.ID, err)
+		return
+	}
+	checkout.CalendarEventID = eventID
 }
 
-// Helper function to add business days to a date
-func addBusinessDays(start time.Time, businessDays int) time.Time {
-	current := start
-	daysAdded := 0
+// moveCalendarEvent brings the checkout's event in line with how long the
+// truck is now held, after an extension or an early release.
+func (h *Handler) moveCalendarEvent(truck *models.Truck, checkout *models.Checkout) {
+	if h.calendarService == nil || checkout.CalendarEventID == "" || truck.GoogleCalendarID == "" {
+		return
+	}
+	if err := calendar.MoveEvent(h.calendarService, truck.GoogleCalendarID, checkout.CalendarEventID, checkout.StartDate, checkout.HeldUntil()); err != nil {
+		log.Printf("Failed to move calendar event %s for truck %s: %v", checkout.CalendarEventID, truck.Name, err)
+	}
+}
 
-	for daysAdded < businessDays {
-		current = current.AddDate(0, 0, 1)
-		if isValidDay(current) {
-			daysAdded++
-		}
+// deleteCalendarEvent removes the checkout's event from the truck's Google
+// calendar, when both are known.
+func (h *Handler) deleteCalendarEvent(truck *models.Truck, checkout *models.Checkout) {
+	if h.calendarService == nil || checkout.CalendarEventID == "" || truck.GoogleCalendarID == "" {
+		return
+	}
+	if err := calendar.DeleteEvent(h.calendarService, truck.GoogleCalendarID, checkout.CalendarEventID); err != nil {
+		log.Printf("Failed to delete calendar event %s for truck %s: %v", checkout.CalendarEventID, truck.Name, err)
 	}
+}
 
-	return current
+// Helper function to format date range for display
+func (h *Handler) formatDateRange(start, end time.Time) string {
+	return h.cal.FormatRange(start, end)
 }
 
-// Helper function to calculate the end date for a multi-day checkout
-func calculateEndDate(startDate time.Time, businessDays int) time.Time {
-	if businessDays == 1 {
-		// Single day checkout - end same day at 3:30 PM
-		return time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 15, 30, 0, 0, startDate.Location())
+// checkoutLength is how long a checkout lasts: a number of whole business
+// days, or a slot of hours on a single business day.
+type checkoutLength struct {
+	businessDays int
+	slot         *calendar.Slot
+}
+
+func wholeDays(n int) checkoutLength {
+	return checkoutLength{businessDays: n}
+}
+
+// parseCheckoutLength parses a number of business days such as "4" or a
+// slot such as "9am-12pm".
+func parseCheckoutLength(s string) (checkoutLength, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 {
+			return checkoutLength{}, fmt.Errorf("invalid number of days %d", n)
+		}
+		return wholeDays(n), nil
+	}
+	slot, err := calendar.ParseSlot(s)
+	if err != nil {
+		return checkoutLength{}, err
 	}
+	return checkoutLength{businessDays: 1, slot: &slot}, nil
+}
 
-	// Multi-day checkout - find the last business day and set end time to 3:30 PM
-	endDate := addBusinessDays(startDate, businessDays-1) // -1 because start day counts as day 1
-	return time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 15, 30, 0, 0, endDate.Location())
+// String renders the length in a form parseCheckoutLength accepts.
+func (l checkoutLength) String() string {
+	if l.slot != nil {
+		return l.slot.String()
+	}
+	return strconv.Itoa(l.businessDays)
 }
 
-// Helper function to format date range for display
-func formatDateRange(start, end time.Time) string {
-	if start.Year() == end.Year() && start.Month() == end.Month() && start.Day() == end.Day() {
-		// Same day
-		return fmt.Sprintf("%s (7:00 AM - 3:30 PM)", start.Format("Jan 2, 2006"))
+func (l checkoutLength) label() string {
+	if l.slot != nil {
+		return l.slot.String()
+	}
+	return fmt.Sprintf("%d business days", l.businessDays)
+}
+
+// window returns the start and end of a checkout of this length counted
+// from from on cal.
+func (l checkoutLength) window(cal *calendar.BusinessCalendar, from time.Time) (time.Time, time.Time, error) {
+	if l.slot != nil {
+		return cal.SlotWindow(from, *l.slot)
 	}
-	// Different days
-	return fmt.Sprintf("%s 7:00 AM - %s 3:30 PM", start.Format("Jan 2"), end.Format("Jan 2, 2006"))
+	start, end := cal.CheckoutWindow(from, l.businessDays)
+	return start, end, nil
 }
 
-func performCheckout(client *socketmode.Client, user *models.User, truckName string, businessDays int, slackUserId string, userName string) (string, error) {
+// anyTruck is the truck name users pass to `/checkout any` when they don't
+// mind which truck they get.
+const anyTruck = "Any"
+
+// performCheckout checks out a truck, along with any trailers or equipment,
+// for the user. The checkout's length counts from from: now, or a later day
+// it's booked for. override lets a fleet admin past the overridable policy
+// rules. Errors come straight from the models package; callers turn them
+// into Slack text with errorMessage.
+func (h *Handler) performCheckout(client Messenger, user *models.User, truckName string, length checkoutLength, from time.Time, assets []models.Asset, override bool, slackUserId string, userName string, source models.AuditSource) (string, error) {
+	actor := models.Actor{SlackUserID: slackUserId, Source: source}
+	if strings.EqualFold(truckName, anyTruck) {
+		return h.performAnyCheckout(client, user, length, from, assets, override, userName, actor)
+	}
+
 	truck, err := models.GetTruckByName(truckName)
 	if err != nil {
-		return "", fmt.Errorf("❌ Truck `%s` not found", truckName)
+		return "", err
 	}
 
-	if truck.IsCheckedOut {
-		return "", fmt.Errorf("🚫 Truck `%s` is already checked out", truckName)
+	if err := models.CheckTeamAccess(truck, user.Team); err != nil {
+		return "", err
 	}
 
-	if truck.DefaultTeam != nil && user.Team != *truck.DefaultTeam {
-		// TODO: Show cross-team warning
-		// showCrossTeamWarning(client, req, user, truck, truckName, businessDays)
-		return "", fmt.Errorf("⚠️ Warning: %s is typically used by %s team, but you're on %s team. Cross-team warning will be implemented next", truckName, *truck.DefaultTeam, user.Team)
+	now := h.cal.Now()
+	start, end, err := length.window(h.cal, from)
+	if err != nil {
+		return "", err
 	}
 
-	now := time.Now()
-	start := time.Date(now.Year(), now.Month(), now.Day(), 7, 0, 0, 0, now.Location())
-	end := calculateEndDate(start, businessDays)
+	if err := models.CheckDriverQualified(slackUserId, truck, end); err != nil {
+		return "", err
+	}
+
+	request := models.CheckoutRequest{
+		TruckName:    truck.Name,
+		UserID:       slackUserId,
+		TeamName:     user.Team,
+		Start:        start,
+		End:          end,
+		BusinessDays: length.businessDays,
+		Now:          now,
+	}
+	check := policyCheck(h.cfg.CheckoutPolicy(), request, user, override)
 
 	checkout := models.Checkout{
 		ID:        uuid.New(),
@@ -86,61 +167,216 @@ func performCheckout(client *socketmode.Client, user *models.User, truckName str
 		TeamName:  user.Team, // Use the user's actual team
 		StartDate: start,
 		EndDate:   end,
-		Purpose:   fmt.Sprintf("Quick checkout via slash command (%d business days)", businessDays),
+		Purpose:   fmt.Sprintf("Quick checkout via slash command (%s)", length.label()),
+		AssetIDs:  assetIDs(assets),
 	}
 
-	if err := models.CreateCheckout(checkout); err != nil {
-		log.Printf("CreateCheckout failed: %v", err)
-		return "", fmt.Errorf("❌ Could not check out the truck due to a database error")
+	if err := models.CreateCheckout(checkout, check, actor); err != nil {
+		return "", err
+	}
+	logOverrides(check, userName)
+	if !start.After(now) {
+		handOffCustody(truck.ID, checkout.ID, slackUserId, actor)
 	}
+	h.addCalendarEvent(truck, &checkout)
 
-	// Send message to #vehicleupdates channel
-	channelID := "vehicleupdates"
-	dateRange := formatDateRange(start, end)
-	message := fmt.Sprintf("🚛 *%s* checked out truck *%s* (%s)", userName, truckName, dateRange)
+	h.announceCheckout(client, checkout, userName, truckName, assets)
 
-	_, _, err = client.PostMessage(channelID, slack.MsgOptionText(message, false))
+	return h.checkoutResponseText(truckName, assets, length.businessDays, start, end), nil
+}
+
+// policyCheck is the checkout policy check for req, which the models package
+// runs in the same transaction as the insert. A fleet admin who asks to
+// override may break the overridable rules.
+func policyCheck(policy *models.CheckoutPolicy, req models.CheckoutRequest, user *models.User, override bool) *models.PolicyCheck {
+	req.Override = override && user.IsFleetAdmin()
+	return &models.PolicyCheck{Policy: policy, Request: req}
+}
+
+// canOverride reports whether user could get past err by overriding: it is
+// a policy violation, every rule broken is overridable and the user is a
+// fleet admin.
+func canOverride(err error, user *models.User) bool {
+	var policyErr *models.PolicyError
+	if !errors.As(err, &policyErr) || !user.IsFleetAdmin() {
+		return false
+	}
+	for _, v := range policyErr.Violations {
+		if !v.Overridable {
+			return false
+		}
+	}
+	return true
+}
+
+// logOverrides logs the policy rules a checkout was let through despite. The
+// models package has already audited them.
+func logOverrides(check *models.PolicyCheck, userName string) {
+	if len(check.Overridden) > 0 {
+		log.Printf("Override: fleet admin %s checked out despite %v", userName, &models.PolicyError{Violations: check.Overridden})
+	}
+}
+
+// performAnyCheckout checks out whichever free truck suits the user's team
+// best and explains the choice in the response.
+func (h *Handler) performAnyCheckout(client Messenger, user *models.User, length checkoutLength, from time.Time, assets []models.Asset, override bool, userName string, actor models.Actor) (string, error) {
+	slackUserId := actor.SlackUserID
+	now := h.cal.Now()
+	start, end, err := length.window(h.cal, from)
+	if err != nil {
+		return "", err
+	}
+
+	// The license is needed whichever truck is picked; trucks needing a
+	// certification the user lacks are left out of the choice.
+	if err := models.CheckDriverQualified(slackUserId, nil, end); err != nil {
+		return "", err
+	}
+	uncertified, err := models.UncertifiedTrucks(slackUserId, end)
 	if err != nil {
-		log.Printf("Failed to post message to #vehicleupdates: %v", err)
-		return "", fmt.Errorf("❌ Could not post update to #vehicleupdates channel")
+		return "", err
+	}
+
+	policy := h.cfg.CheckoutPolicy()
+	request := models.CheckoutRequest{
+		UserID:       slackUserId,
+		TeamName:     user.Team,
+		Start:        start,
+		End:          end,
+		BusinessDays: length.businessDays,
+		Now:          now,
 	}
+	check := policyCheck(policy, request, user, override)
+
+	checkout := models.Checkout{
+		ID:        uuid.New(),
+		UserID:    slackUserId,
+		UserName:  string(userName),
+		TeamName:  user.Team,
+		StartDate: start,
+		EndDate:   end,
+		Purpose:   fmt.Sprintf("Any-truck checkout via slash command (%s)", length.label()),
+		AssetIDs:  assetIDs(assets),
+	}
+
+	exclude := append(policy.ExcludedTrucks(check.Request), uncertified...)
+	truck, match, err := models.CreateCheckoutForAnyTruck(checkout, exclude, check, actor)
+	if err != nil {
+		return "", err
+	}
+	logOverrides(check, userName)
+	if !start.After(now) {
+		handOffCustody(truck.ID, checkout.ID, slackUserId, actor)
+	}
+	h.addCalendarEvent(truck, &checkout)
+
+	h.announceCheckout(client, checkout, userName, truck.Name, assets)
 
-	var responseText string
+	reason := "none of your team's trucks were free, so I picked an unassigned one"
+	if match == models.MatchTeamTruck {
+		reason = fmt.Sprintf("it's one of the %s team's trucks", user.Team)
+	}
+
+	return fmt.Sprintf("%s\nℹ️ I picked `%s` because %s.", h.checkoutResponseText(truck.Name, assets, length.businessDays, start, end), truck.Name, reason), nil
+}
+
+func (h *Handler) checkoutResponseText(truckName string, assets []models.Asset, businessDays int, start, end time.Time) string {
 	if businessDays == 1 {
-		responseText = fmt.Sprintf("✅ Truck `%s` checked out for today (7:00 AM - 3:30 PM)!", truckName)
-	} else {
-		responseText = fmt.Sprintf("✅ Truck `%s` checked out for %d business days (%s)!", truckName, businessDays, dateRange)
+		return fmt.Sprintf("✅ Truck `%s`%s checked out for %s!", truckName, withAssets(assets), h.formatDateRange(start, end))
+	}
+	return fmt.Sprintf("✅ Truck `%s`%s checked out for %d business days (%s)!", truckName, withAssets(assets), businessDays, h.formatDateRange(start, end))
+}
+
+// withAssets describes the assets booked with a truck, e.g. " with Chipper
+// and Trailer", or returns "" if there are none.
+func withAssets(assets []models.Asset) string {
+	if len(assets) == 0 {
+		return ""
+	}
+	var names []string
+	for _, a := range assets {
+		names = append(names, a.Name)
+	}
+	if len(names) == 1 {
+		return " with " + names[0]
+	}
+	return " with " + strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+func assetIDs(assets []models.Asset) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, a := range assets {
+		ids = append(ids, a.ID)
 	}
+	return ids
+}
 
-	return responseText, nil
+// lookupAssets resolves the asset names given after `with`, listing an asset
+// once however many times it was named.
+func lookupAssets(names []string) ([]models.Asset, error) {
+	var assets []models.Asset
+	seen := make(map[uuid.UUID]bool)
+	for _, name := range names {
+		a, err := models.GetAssetByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[a.ID] {
+			continue
+		}
+		seen[a.ID] = true
+		assets = append(assets, *a)
+	}
+	return assets, nil
 }
 
-func HandleCheckout(client *socketmode.Client, req *socketmode.Request, truckName string, businessDays int, slackUserId string, userName string, triggerId string, channelId string) {
+// HandleCheckout checks out a truck, e.g. `/checkout Tulip 2 with Chipper`
+// or `/checkout Tulip 9am-12pm`.
+// assetNames are the trailers and equipment to book along with it. override
+// is set when a fleet admin adds `override` to book despite the policy.
+func (h *Handler) HandleCheckout(client Messenger, req Responder, truckName string, length checkoutLength, assetNames []string, override bool, slackUserId string, userName string, triggerId string, channelId string) {
 	truckName = cases.Title(language.English).String(strings.ToLower(truckName))
-	_, err := models.GetTruckByName(truckName)
+	if truckName != anyTruck {
+		_, err := models.GetTruckByName(truckName)
+		if err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+	}
+
+	assets, err := lookupAssets(assetNames)
 	if err != nil {
-		client.Ack(*req, map[string]string{"text": fmt.Sprintf("❌ Truck `%s` not found.", truckName)})
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
 		return
 	}
 
-	user, err := models.GetUserBySlackID(slackUserId)
+	user, err := h.currentUser(slackUserId)
 	if err != nil {
-		client.Ack(*req, map[string]string{"text": "❌ Error retrieving user information."})
+		req.Ack(map[string]string{"text": "❌ Error retrieving user information."})
 		return
 	}
 
-	if user == nil {
-		showTeamSelectionModal(client, req, triggerId, truckName, businessDays, slackUserId, userName, channelId)
-		client.Ack(*req, map[string]string{"text": "👋 Please select your team to continue with checkout."})
+	if user == nil || user.Team == "" {
+		// The modal acks the command itself.
+		showTeamSelectionModal(client, req, triggerId, truckName, length, assetNames, slackUserId, userName, channelId)
+		return
+	}
+	if override && !user.IsFleetAdmin() {
+		log.Printf("Warning: User %s tried to override the checkout policy without being a fleet admin", slackUserId)
+		req.Ack(map[string]string{"text": h.errorMessage(models.ErrPermissionDenied, truckName)})
 		return
 	}
 
-	responseText, err := performCheckout(client, user, truckName, businessDays, slackUserId, userName)
+	responseText, err := h.performCheckout(client, user, truckName, length, h.cal.Now(), assets, override, slackUserId, userName, models.SourceSlashCommand)
 	if err != nil {
 		log.Printf("Checkout error: %v", err)
-		client.Ack(*req, map[string]string{"text": err.Error()})
+		text := h.errorMessage(err, truckName)
+		if canOverride(err, user) {
+			text += "\nℹ️ Add `override` to the end of the command to check it out anyway."
+		}
+		req.Ack(map[string]string{"text": text})
 		return
 	}
 
-	client.Ack(*req, map[string]string{"text": responseText})
+	req.Ack(map[string]string{"text": responseText})
 }
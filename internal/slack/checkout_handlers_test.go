@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	db "truck-checkout/internal/database"
+	"truck-checkout/internal/models"
+
+	"github.com/slack-go/slack"
+)
+
+// testActor sets up the trucks, assets and users a test needs.
+var testActor = models.Actor{SlackUserID: "U_TEST", Source: models.SourceAPI}
+
+func TestMain(m *testing.M) {
+	testDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		panic(err)
+	}
+	// Handlers run on the socket mode goroutine, so keep every query on the
+	// one in-memory database.
+	testDB.SetMaxOpenConns(1)
+	if err := db.CreateTables(testDB); err != nil {
+		panic(err)
+	}
+	db.DB = testDB
+
+	code := m.Run()
+
+	testDB.Close()
+	os.Exit(code)
+}
+
+func TestCheckoutScenario_NewUserPicksTeam(t *testing.T) {
+	models.ResetTestDB(t)
+	h := newTestHandler()
+	team := "beltline"
+	if err := models.InsertTruck("Tulip", &team, "", false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	admin := models.Actor{SlackUserID: "UADMIN", Source: models.SourceSlashCommand}
+	if _, err := models.RecordQualification("U123", models.QualificationLicense, "", time.Now().AddDate(1, 0, 0), admin); err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+
+	f := newFakeSlack(t, h)
+
+	// Without a profile, /checkout opens the team picker instead.
+	ack := f.SlashCommand(slack.SlashCommand{
+		Command:   "/checkout",
+		Text:      "Tulip 2",
+		UserID:    "U123",
+		UserName:  "jo",
+		ChannelID: "C123",
+		TriggerID: "trigger-1",
+	})
+	if strings.Contains(string(ack), "text") {
+		t.Errorf("expected a bare ack while the modal is open, got %s", ack)
+	}
+	opened := f.Calls("views.open")
+	if len(opened) != 1 {
+		t.Fatalf("expected the team selection modal to open once, got %d", len(opened))
+	}
+	if got := opened[0].Params.Get("trigger_id"); got != "trigger-1" {
+		t.Errorf("expected the modal on trigger-1, got %q", got)
+	}
+	var view slack.ModalViewRequest
+	if err := json.Unmarshal([]byte(opened[0].Params.Get("view")), &view); err != nil {
+		t.Fatalf("failed to decode modal: %v", err)
+	}
+	if view.CallbackID != "team_selection" {
+		t.Fatalf("expected the team_selection modal, got %q", view.CallbackID)
+	}
+
+	ack = f.Interact(slack.InteractionCallback{
+		Type: slack.InteractionTypeViewSubmission,
+		User: slack.User{ID: "U123", Name: "jo"},
+		View: slack.View{
+			CallbackID:      view.CallbackID,
+			PrivateMetadata: view.PrivateMetadata,
+			State: &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+				"team_block": {"team_select": {SelectedOption: slack.OptionBlockObject{Value: "beltline"}}},
+			}},
+		},
+	})
+	if !strings.Contains(string(ack), `"response_action":"clear"`) {
+		t.Errorf("expected the modal to close, got %s", ack)
+	}
+
+	announcement := f.WaitForCall("chat.postMessage", func(c slackCall) bool {
+		return c.Params.Get("channel") == h.cfg.AnnounceChannel
+	})
+	if !strings.Contains(announcement.Params.Get("text"), "checked out truck *Tulip*") {
+		t.Errorf("unexpected announcement %q", announcement.Params.Get("text"))
+	}
+	ephemeral := f.WaitForCall("chat.postEphemeral", nil)
+	if ephemeral.Params.Get("channel") != "C123" || ephemeral.Params.Get("user") != "U123" {
+		t.Errorf("expected the confirmation in C123 for U123, got %v", ephemeral.Params)
+	}
+	if !strings.Contains(ephemeral.Params.Get("text"), "Created your profile with team beltline") {
+		t.Errorf("unexpected confirmation %q", ephemeral.Params.Get("text"))
+	}
+
+	user, err := models.GetUserBySlackID("U123")
+	if err != nil || user == nil {
+		t.Fatalf("expected a profile for U123, got %v (%v)", user, err)
+	}
+	if user.Team != "beltline" {
+		t.Errorf("expected team beltline, got %q", user.Team)
+	}
+	checkouts, err := models.GetOpenCheckoutsByUserID("U123", time.Now())
+	if err != nil {
+		t.Fatalf("failed to load checkouts: %v", err)
+	}
+	if len(checkouts) != 1 {
+		t.Fatalf("expected one checkout, got %d", len(checkouts))
+	}
+	if checkouts[0].AnnounceTS == "" {
+		t.Error("expected the announcement to be saved on the checkout")
+	}
+}
+
+func TestCheckout_AdminOverridesOnlyWhenAsked(t *testing.T) {
+	models.ResetTestDB(t)
+	h := newTestHandler()
+	openAllHours(h)
+
+	h.cfg.FleetAdmins = []string{"UADMIN"}
+	h.cfg.Policy = models.CheckoutPolicy{
+		OneTruckPerUser: true,
+		Overridable:     []models.PolicyRule{models.RuleOneTruckPerUser},
+	}
+
+	team := "beltline"
+	for _, name := range []string{"Tulip", "Libby", "Watson"} {
+		if err := models.InsertTruck(name, &team, "", false, testActor); err != nil {
+			t.Fatalf("failed to insert truck: %v", err)
+		}
+	}
+	admin := models.Actor{SlackUserID: "UADMIN", Source: models.SourceSlashCommand}
+	for _, u := range []struct{ id, name string }{{"UADMIN", "ada"}, {"U123", "jo"}} {
+		if _, err := models.GetOrCreateUserBySlackID(u.id, u.name, "beltline", testActor); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		if _, err := models.RecordQualification(u.id, models.QualificationLicense, "", time.Now().AddDate(1, 0, 0), admin); err != nil {
+			t.Fatalf("failed to record license: %v", err)
+		}
+	}
+
+	checkout := func(truck string, override bool, userID, userName string) string {
+		m := &recordingMessenger{}
+		h.HandleCheckout(m, m, truck, wholeDays(1), nil, override, userID, userName, "trigger-1", "C123")
+		return m.ackText(t)
+	}
+
+	for _, u := range []struct{ id, name, truck string }{{"UADMIN", "ada", "Libby"}, {"U123", "jo", "Watson"}} {
+		if text := checkout(u.truck, false, u.id, u.name); !strings.Contains(text, u.truck) {
+			t.Fatalf("expected %s to check out %s, got %q", u.name, u.truck, text)
+		}
+	}
+
+	// An admin is stopped like anyone else, but told how to override
+	if text := checkout("Tulip", false, "UADMIN", "ada"); !strings.Contains(text, "isn't allowed") || !strings.Contains(text, "`override`") {
+		t.Errorf("expected the admin to be stopped with an override hint, got %q", text)
+	}
+	if text := checkout("Tulip", true, "U123", "jo"); !strings.Contains(text, "permission") {
+		t.Errorf("expected a non-admin override to be refused, got %q", text)
+	}
+	if text := checkout("Tulip", true, "UADMIN", "ada"); !strings.Contains(text, "Tulip") || strings.Contains(text, "isn't allowed") {
+		t.Errorf("expected the admin's override to go through, got %q", text)
+	}
+}
+
+func TestCheckout_WithAssetNamedTwice(t *testing.T) {
+	models.ResetTestDB(t)
+	h := newTestHandler()
+	openAllHours(h)
+
+	h.cfg.FleetAdmins = []string{"UADMIN"}
+
+	team := "beltline"
+	if err := models.InsertTruck("Tulip", &team, "", false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	admin := models.Actor{SlackUserID: "UADMIN", Source: models.SourceSlashCommand}
+	for _, u := range []struct{ id, name string }{{"UADMIN", "ada"}, {"U123", "jo"}} {
+		if _, err := models.GetOrCreateUserBySlackID(u.id, u.name, "beltline", testActor); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	if _, err := models.RecordQualification("U123", models.QualificationLicense, "", time.Now().AddDate(1, 0, 0), admin); err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+
+	m := &recordingMessenger{}
+	h.HandleFleet(m, m, []string{"asset", "chipper", "equipment", "none", "pto,hitch"}, "UADMIN")
+	if text := m.ackText(t); !strings.Contains(text, "Added equipment `Chipper`") {
+		t.Fatalf("unexpected reply adding the asset: %q", text)
+	}
+	chipper, err := models.GetAssetByName("Chipper")
+	if err != nil {
+		t.Fatalf("failed to load asset: %v", err)
+	}
+	if len(chipper.Requires) != 0 || len(chipper.Features) != 2 || chipper.Features[0] != "pto" {
+		t.Errorf("expected Chipper to provide pto and hitch and require nothing, got %+v", chipper)
+	}
+
+	m = &recordingMessenger{}
+	h.HandleCheckout(m, m, "tulip", wholeDays(1), []string{"Chipper", "chipper"}, false, "U123", "jo", "trigger-1", "C123")
+	if text := m.ackText(t); !strings.Contains(text, "Tulip") || !strings.Contains(text, "Chipper") {
+		t.Fatalf("expected Tulip to be checked out with Chipper, got %q", text)
+	}
+	truck, _ := models.GetTruckByName("Tulip")
+	checkout, err := models.GetActiveCheckoutByTruckID(truck.ID)
+	if err != nil {
+		t.Fatalf("expected the checkout to be active: %v", err)
+	}
+	if assets, err := models.GetCheckoutAssets(checkout.ID); err != nil || len(assets) != 1 {
+		t.Errorf("expected Chipper booked once, got %+v (%v)", assets, err)
+	}
+}
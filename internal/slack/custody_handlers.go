@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"truck-checkout/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/slack-go/slack"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// handOffCustody records the truck's keys and fuel card going to the person
+// who checked it out, once the checkout has started; ReconcileTruckStatus
+// catches bookings that start later. A failure is only logged; the checkout
+// still stands.
+func handOffCustody(truckID, checkoutID uuid.UUID, holder string, actor models.Actor) {
+	for _, item := range models.CustodyItems {
+		if err := models.HandOffCustody(truckID, &checkoutID, item, holder, actor); err != nil {
+			log.Printf("Failed to record %s handoff for truck %s: %v", item, truckID, err)
+		}
+	}
+}
+
+// returnCustody records every item of the truck that is still out as back
+// in the lockbox.
+func returnCustody(truckID uuid.UUID, checkoutID *uuid.UUID, actor models.Actor) error {
+	outstanding, err := models.OutstandingCustody(truckID)
+	if err != nil {
+		return err
+	}
+	for _, e := range outstanding {
+		if err := models.ReturnCustody(truckID, checkoutID, e.Item, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// custodyReminder builds the release reply for a truck whose keys or fuel
+// card are still recorded as out, with a button to confirm their return.
+func custodyReminder(text string, truck *models.Truck, outstanding []models.CustodyEvent) map[string]interface{} {
+	text, blocks := custodyReminderBlocks(text, truck, outstanding)
+	return map[string]interface{}{
+		"text":   text,
+		"blocks": blocks,
+	}
+}
+
+// custodyReminderBlocks appends the reminder to text and returns it with the
+// message blocks, for replies that aren't slash command acks.
+func custodyReminderBlocks(text string, truck *models.Truck, outstanding []models.CustodyEvent) (string, []slack.Block) {
+	var items []string
+	for _, e := range outstanding {
+		items = append(items, fmt.Sprintf("the %s (with <@%s>)", e.Item.Label(), e.HolderID))
+	}
+	text += fmt.Sprintf("\n⚠️ Nobody has confirmed returning %s to the lockbox. Please put them back and confirm below.",
+		strings.Join(items, " and "))
+
+	confirm := slack.NewButtonBlockElement(
+		"confirm_custody_return",
+		truck.ID.String(),
+		slack.NewTextBlockObject("plain_text", "Returned to lockbox", false, false),
+	).WithStyle(slack.StylePrimary)
+
+	return text, []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+		slack.NewActionBlock("custody_return", confirm),
+	}
+}
+
+// handleConfirmCustodyReturn records a truck's keys and fuel card as back in
+// the lockbox when the user clicks the button from the release reminder.
+func (h *Handler) handleConfirmCustodyReturn(client Messenger, callback *slack.InteractionCallback, value string) {
+	reply := func(text string) {
+		err := client.Respond(callback.ResponseURL, &slack.WebhookMessage{Text: text, ReplaceOriginal: true})
+		if err != nil {
+			log.Printf("Failed to reply to %s: %v", callback.User.ID, err)
+		}
+	}
+
+	truckID, err := uuid.Parse(value)
+	if err != nil {
+		log.Printf("Invalid truck ID %q: %v", value, err)
+		return
+	}
+	truck, err := models.GetTruckByID(truckID)
+	if err != nil {
+		reply(h.errorMessage(err, ""))
+		return
+	}
+
+	actor := models.Actor{SlackUserID: callback.User.ID, Source: models.SourceButton}
+	if err := returnCustody(truck.ID, nil, actor); err != nil {
+		log.Printf("Failed to record custody return for %s: %v", truck.Name, err)
+		reply("❌ Could not record the return. Please try again.")
+		return
+	}
+	log.Printf("Keys and fuel card for %s returned by %s", truck.Name, callback.User.ID)
+	reply(fmt.Sprintf("🔑 Thanks! The keys and fuel card for `%s` are recorded as back in the lockbox.", truck.Name))
+}
+
+// HandleWhereIs reports the last known holder of a truck's keys or fuel
+// card, e.g. `/whereis keys Tulip`.
+func (h *Handler) HandleWhereIs(client Messenger, req Responder, args []string) {
+	if len(args) != 2 {
+		req.Ack(map[string]string{"text": "ℹ️ Use `/whereis keys [truck-name]` or `/whereis fuelcard [truck-name]`."})
+		return
+	}
+	item, ok := models.ParseCustodyItem(args[0])
+	if !ok {
+		req.Ack(map[string]string{"text": "⚠️ I only track `keys` and `fuelcard`."})
+		return
+	}
+
+	truckName := cases.Title(language.English).String(strings.ToLower(args[1]))
+	truck, err := models.GetTruckByName(truckName)
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+
+	e, err := models.GetCustody(truck.ID, item)
+	if err != nil {
+		log.Printf("Failed to look up %s custody for %s: %v", item, truckName, err)
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+	if e == nil {
+		req.Ack(map[string]string{"text": fmt.Sprintf("ℹ️ Nothing has been recorded for the %s of `%s` yet.", item.Label(), truckName)})
+		return
+	}
+
+	since := e.RecordedAt.In(h.cal.Location()).Format("Jan 2 3:04 PM")
+	were := "was"
+	if item == models.CustodyKeys {
+		were = "were"
+	}
+	var msg string
+	if e.InLockbox() {
+		msg = fmt.Sprintf("🔑 The %s of `%s` should be in the lockbox, returned by <@%s> on %s.", item.Label(), truckName, e.RecordedBy, since)
+	} else {
+		msg = fmt.Sprintf("🔑 The %s of `%s` %s last with <@%s>, since %s.", item.Label(), truckName, were, e.HolderID, since)
+	}
+	req.Ack(map[string]string{"text": msg})
+}
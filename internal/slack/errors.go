@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"truck-checkout/internal/calendar"
+	"truck-checkout/internal/models"
+)
+
+// errorMessage turns an error from the models package into the text shown to
+// the Slack user. truckName fills in messages about the requested truck.
+// Anything unrecognised is logged and reported as a generic failure.
+func (h *Handler) errorMessage(err error, truckName string) string {
+	var crossTeam *models.CrossTeamError
+	var policy *models.PolicyError
+	var assetUnavailable *models.AssetUnavailableError
+	var incompatible *models.IncompatibleAssetError
+	var qualification *models.QualificationError
+	var tooLow *models.ReadingTooLowError
+
+	switch {
+	case errors.As(err, &crossTeam):
+		return fmt.Sprintf("🚫 `%s` belongs to the %s team and you're on %s, so you can't check it out. Ask a fleet admin if your team needs it.",
+			crossTeam.Truck, crossTeam.TruckTeam, crossTeam.UserTeam)
+	case errors.As(err, &policy):
+		msg := "🚫 This checkout isn't allowed:"
+		for _, v := range policy.Violations {
+			msg += "\n• " + v.Message
+			if v.Overridable {
+				msg += " _(an admin can override this)_"
+			}
+		}
+		return msg
+	case errors.As(err, &qualification):
+		what, record := "a driver's license", "`/profile license YYYY-MM-DD`"
+		if qualification.Kind == models.QualificationCertification {
+			what = fmt.Sprintf("a %s certification to drive `%s`", qualification.TruckClass, qualification.Truck)
+			record = fmt.Sprintf("`/profile cert %s YYYY-MM-DD`", qualification.TruckClass)
+		}
+		if qualification.ExpiredAt != nil {
+			return fmt.Sprintf("🚫 You need %s that's valid for the whole checkout, but yours on file expires %s. Record your renewal with %s.",
+				what, qualification.ExpiredAt.In(h.cal.Location()).Format("Jan 2, 2006"), record)
+		}
+		return fmt.Sprintf("🚫 You need %s on file to check out this truck. Record it with %s.", what, record)
+	case errors.As(err, &assetUnavailable):
+		return fmt.Sprintf("🚫 `%s` is already booked for part of that period.", assetUnavailable.Asset)
+	case errors.As(err, &incompatible):
+		return fmt.Sprintf("⚠️ `%s` needs a truck with %s, and `%s` doesn't have one.", incompatible.Asset, incompatible.Requires, incompatible.Truck)
+	case errors.As(err, &tooLow):
+		return fmt.Sprintf("⚠️ %d is lower than the last odometer reading of %d for `%s`. Check the number and try again.", tooLow.Reading, tooLow.Last, truckName)
+	case errors.Is(err, models.ErrReadingRecorded):
+		return "ℹ️ That odometer reading has already been recorded for this checkout."
+	case errors.Is(err, models.ErrInvalidReading):
+		return "⚠️ Invalid odometer reading. Use `start` or `end` and whole miles like `48210`."
+	case errors.Is(err, models.ErrInvalidFuel):
+		return "⚠️ Invalid fuel purchase. Gallons must be positive and the cost can't be negative."
+	case errors.Is(err, models.ErrAssetNotFound):
+		return "❌ I don't know that trailer or piece of equipment. Check the name and try again."
+	case errors.Is(err, models.ErrTruckNotFound), errors.Is(err, models.ErrInvalidTruck):
+		return fmt.Sprintf("❌ Truck `%s` not found.", truckName)
+	case errors.Is(err, models.ErrTruckUnavailable):
+		return fmt.Sprintf("🚫 Truck `%s` is already checked out. Use `/waitlist %s` to get it when it frees up.", truckName, truckName)
+	case errors.Is(err, models.ErrInMaintenance):
+		return fmt.Sprintf("🔧 Truck `%s` is out of service for maintenance for part of that period.", truckName)
+	case errors.Is(err, models.ErrInvalidPeriod):
+		return "⚠️ The end date must be after the start date."
+	case errors.Is(err, models.ErrCheckoutOverlap):
+		return fmt.Sprintf("🚫 Truck `%s` is already booked for part of that period.", truckName)
+	case errors.Is(err, models.ErrUserNotFound):
+		return "ℹ️ That person hasn't used the truck bot yet, so they have no profile."
+	case errors.Is(err, models.ErrNoTrucksAvailable):
+		return "🚫 No trucks are free for those dates. Use `/waitlist any` to get the next one that frees up."
+	case errors.Is(err, models.ErrNoActiveCheckout):
+		return fmt.Sprintf("ℹ️ Truck `%s` is not currently checked out.", truckName)
+	case errors.Is(err, models.ErrCheckoutNotFound):
+		return "ℹ️ That reservation was already cancelled or released."
+	case errors.Is(err, models.ErrCheckoutStarted):
+		return fmt.Sprintf("ℹ️ That reservation has already started. Use `/release %s` instead.", truckName)
+	case errors.Is(err, models.ErrInvalidTeam):
+		return "⚠️ That team isn't recognised. Please pick one of the listed teams."
+	case errors.Is(err, models.ErrAlreadyWaitlisted):
+		return "ℹ️ You're already on the waitlist for that day."
+	case errors.Is(err, models.ErrOfferUnavailable):
+		return "⌛ This offer is no longer available."
+	case errors.Is(err, models.ErrPermissionDenied):
+		return "🚫 You don't have permission to do that."
+	case errors.Is(err, calendar.ErrOutsideHours):
+		return "⚠️ That time range is outside business hours. Pick a slot within the working day, like `9am-12pm`."
+	default:
+		log.Printf("Unexpected error: %v", err)
+		return "❌ Something went wrong due to a database error. Please try again."
+	}
+}
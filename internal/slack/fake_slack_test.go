@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// fakeSlackTimeout bounds how long a test waits for the handlers to answer.
+const fakeSlackTimeout = 5 * time.Second
+
+// slackCall is one request the handlers made to the fake Web API, or one
+// message posted to a response_url (Method "response_url").
+type slackCall struct {
+	Method string
+	// Params holds the form fields, or the top-level fields of a JSON body
+	// with nested values re-encoded as JSON.
+	Params url.Values
+}
+
+// fakeSlack stands in for Slack in handler tests. It serves the Web API
+// methods the handlers call, recording each call, and a socket mode endpoint
+// that a real socketmode.Client connects to, so slash commands and
+// interactions go through HandleSlashCommand and HandleInteractive exactly
+// as they would in production.
+type fakeSlack struct {
+	t      *testing.T
+	server *httptest.Server
+	client *socketmode.Client
+
+	connected chan struct{}
+	hello     sync.Once
+	writeMu   sync.Mutex
+	conn      *websocket.Conn
+
+	mu       sync.Mutex
+	calls    []slackCall
+	acks     map[string][]json.RawMessage
+	envelope int
+	ts       int
+}
+
+// newFakeSlack starts a fake Slack and a socket mode client connected to it,
+// dispatching events to h the way cmd/app does. Both are shut down when the
+// test ends.
+func newFakeSlack(t *testing.T, h *Handler) *fakeSlack {
+	t.Helper()
+	f := &fakeSlack{
+		t:         t,
+		connected: make(chan struct{}),
+		acks:      map[string][]json.RawMessage{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"ok": true, "url": "ws" + strings.TrimPrefix(f.server.URL, "http") + "/ws"})
+	})
+	mux.HandleFunc("/ws", f.serveSocket)
+	mux.HandleFunc("/api/", f.serveAPI)
+	mux.HandleFunc("/response_url", func(w http.ResponseWriter, r *http.Request) {
+		f.record("response_url", r)
+		w.WriteHeader(http.StatusOK)
+	})
+	f.server = httptest.NewServer(mux)
+
+	api := slack.New("xoxb-test",
+		slack.OptionAPIURL(f.server.URL+"/api/"),
+		slack.OptionAppLevelToken("xapp-test"),
+	)
+	f.client = socketmode.New(api)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.client.RunContext(ctx)
+	}()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt := <-f.client.Events:
+				switch evt.Type {
+				case socketmode.EventTypeSlashCommand:
+					h.HandleSlashCommand(f.client, evt)
+				case socketmode.EventTypeInteractive:
+					h.HandleInteractive(f.client, evt)
+				}
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		f.server.CloseClientConnections()
+		<-done
+		f.server.Close()
+	})
+
+	select {
+	case <-f.connected:
+	case <-time.After(fakeSlackTimeout):
+		t.Fatal("socket mode client never connected to the fake Slack")
+	}
+	return f
+}
+
+// serveSocket upgrades the socket mode connection, says hello and collects
+// the acks the client sends back.
+func (f *fakeSlack) serveSocket(w http.ResponseWriter, r *http.Request) {
+	// The client sends Slack's origin, not ours.
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		f.t.Errorf("upgrading socket mode connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	f.writeMu.Lock()
+	f.conn = conn
+	err = conn.WriteJSON(map[string]any{"type": "hello", "num_connections": 1})
+	f.writeMu.Unlock()
+	if err != nil {
+		return
+	}
+	f.hello.Do(func() { close(f.connected) })
+
+	// Slack pings the client, which reconnects if the pings stop.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				f.writeMu.Lock()
+				conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+				f.writeMu.Unlock()
+			}
+		}
+	}()
+
+	for {
+		var ack struct {
+			EnvelopeID string          `json:"envelope_id"`
+			Payload    json.RawMessage `json:"payload"`
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err := json.Unmarshal(data, &ack); err != nil {
+			f.t.Errorf("decoding ack %s: %v", data, err)
+			continue
+		}
+		f.mu.Lock()
+		f.acks[ack.EnvelopeID] = append(f.acks[ack.EnvelopeID], ack.Payload)
+		f.mu.Unlock()
+	}
+}
+
+// serveAPI records a Web API call and answers it the way Slack would, as far
+// as the handlers care.
+func (f *fakeSlack) serveAPI(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+	call := f.record(method, r)
+
+	switch method {
+	case "chat.postMessage", "chat.update":
+		f.mu.Lock()
+		f.ts++
+		ts := fmt.Sprintf("1700000000.%06d", f.ts)
+		f.mu.Unlock()
+		if method == "chat.update" {
+			ts = call.Params.Get("ts")
+		}
+		writeJSON(w, map[string]any{"ok": true, "channel": call.Params.Get("channel"), "ts": ts})
+	case "chat.postEphemeral":
+		writeJSON(w, map[string]any{"ok": true, "message_ts": "1700000000.000000"})
+	case "views.open", "views.update":
+		writeJSON(w, map[string]any{"ok": true, "view": map[string]any{"id": "V0FAKE"}})
+	case "conversations.open":
+		writeJSON(w, map[string]any{"ok": true, "channel": map[string]any{"id": "D" + call.Params.Get("users")}})
+	default:
+		writeJSON(w, map[string]any{"ok": true})
+	}
+}
+
+// record saves a request as a call, decoding either a form or a JSON body.
+func (f *fakeSlack) record(method string, r *http.Request) slackCall {
+	call := slackCall{Method: method, Params: url.Values{}}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		body, _ := io.ReadAll(r.Body)
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			f.t.Errorf("decoding %s body %s: %v", method, body, err)
+		}
+		for k, v := range fields {
+			var s string
+			if json.Unmarshal(v, &s) == nil {
+				call.Params.Set(k, s)
+			} else {
+				call.Params.Set(k, string(v))
+			}
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			f.t.Errorf("decoding %s form: %v", method, err)
+		}
+		call.Params = r.Form
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+	return call
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// send delivers a socket mode envelope and waits for the client to ack it,
+// returning the ack's payload.
+func (f *fakeSlack) send(kind string, payload any) json.RawMessage {
+	f.t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		f.t.Fatalf("encoding %s payload: %v", kind, err)
+	}
+
+	f.mu.Lock()
+	f.envelope++
+	id := fmt.Sprintf("envelope-%d", f.envelope)
+	f.mu.Unlock()
+
+	f.writeMu.Lock()
+	err = f.conn.WriteJSON(map[string]any{
+		"envelope_id":              id,
+		"type":                     kind,
+		"payload":                  json.RawMessage(body),
+		"accepts_response_payload": true,
+	})
+	f.writeMu.Unlock()
+	if err != nil {
+		f.t.Fatalf("sending %s: %v", kind, err)
+	}
+
+	var acks []json.RawMessage
+	f.eventually(fmt.Sprintf("an ack for %s", kind), func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		acks = f.acks[id]
+		return len(acks) > 0
+	})
+	return acks[0]
+}
+
+// SlashCommand runs a slash command and returns its ack.
+func (f *fakeSlack) SlashCommand(cmd slack.SlashCommand) json.RawMessage {
+	f.t.Helper()
+	return f.send("slash_commands", cmd)
+}
+
+// Interact delivers a button click or modal submission and returns its ack.
+// Replies to the callback's response_url are recorded as calls.
+func (f *fakeSlack) Interact(callback slack.InteractionCallback) json.RawMessage {
+	f.t.Helper()
+	if callback.ResponseURL == "" {
+		callback.ResponseURL = f.server.URL + "/response_url"
+	}
+	return f.send("interactive", &callback)
+}
+
+// Calls returns the calls made so far to method.
+func (f *fakeSlack) Calls(method string) []slackCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []slackCall
+	for _, c := range f.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// WaitForCall waits for the first call to method matching match, for
+// handlers that keep talking to Slack after they ack.
+func (f *fakeSlack) WaitForCall(method string, match func(slackCall) bool) slackCall {
+	f.t.Helper()
+	var found slackCall
+	f.eventually("a call to "+method, func() bool {
+		for _, c := range f.Calls(method) {
+			if match == nil || match(c) {
+				found = c
+				return true
+			}
+		}
+		return false
+	})
+	return found
+}
+
+func (f *fakeSlack) eventually(what string, ok func() bool) {
+	f.t.Helper()
+	deadline := time.Now().Add(fakeSlackTimeout)
+	for !ok() {
+		if time.Now().After(deadline) {
+			f.t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"truck-checkout/internal/models"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// currentUser loads the Slack user acting on a command. Users listed as fleet
+// admins in the configuration are admins even before they have a profile,
+// so they get a stand-in User with no team. It returns nil for anyone else
+// without a profile.
+func (h *Handler) currentUser(slackUserID string) (*models.User, error) {
+	user, err := models.GetUserBySlackID(slackUserID)
+	if err != nil {
+		return nil, err
+	}
+	if h.cfg.IsFleetAdmin(slackUserID) {
+		if user == nil {
+			user = &models.User{SlackUserID: slackUserID}
+		}
+		user.Role = models.RoleFleetAdmin
+	}
+	return user, nil
+}
+
+// userMention matches the escaped form Slack sends for @mentions in slash
+// command text, e.g. <@U123ABC|jane>.
+var userMention = regexp.MustCompile(`^<@([A-Z0-9]+)(?:\|[^>]*)?>$`)
+
+func parseUserID(s string) string {
+	if m := userMention.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return strings.TrimPrefix(s, "@")
+}
+
+// HandleFleet runs fleet-admin commands, e.g. `/fleet role @jane lead`,
+// `/fleet assign Tulip beltline`, `/fleet features Tulip hitch`,
+// `/fleet asset Chipper equipment hitch`, `/fleet asset Lowboy trailer none ramp`,
+// `/fleet class Andre350 dump`, `/fleet maintenance Tulip 2026-11-03 2026-11-05`,
+// `/fleet license @jane 2027-05-01` or `/fleet cert @jane dump 2027-05-01`.
+func (h *Handler) HandleFleet(client Messenger, req Responder, args []string, userId string) {
+	usage := "ℹ️ Use `/fleet role @user member|lead|admin`, `/fleet assign [truck-name] [team|none]`, " +
+		"`/fleet features [truck-name] [hitch,...|none]`, `/fleet asset [name] trailer|equipment [requires|none] [provides]`, " +
+		"`/fleet class [truck-name] [class|none]`, `/fleet maintenance [truck-name] YYYY-MM-DD [YYYY-MM-DD]`, " +
+		"`/fleet license @user YYYY-MM-DD` or `/fleet cert @user [class] YYYY-MM-DD`."
+
+	actor, err := h.currentUser(userId)
+	if err != nil {
+		req.Ack(map[string]string{"text": "❌ Error retrieving user information."})
+		return
+	}
+	if !actor.IsFleetAdmin() {
+		log.Printf("Warning: User %s tried to run /fleet without being a fleet admin", userId)
+		req.Ack(map[string]string{"text": h.errorMessage(models.ErrPermissionDenied, "")})
+		return
+	}
+
+	// Most subcommands take two arguments; `asset` takes up to two more,
+	// `maintenance` one more and `cert` always takes three.
+	var command string
+	if len(args) > 0 {
+		command = strings.ToLower(args[0])
+	}
+	switch {
+	case len(args) == 3 && command != "cert":
+	case len(args) == 4 && (command == "asset" || command == "cert" || command == "maintenance"):
+	case len(args) == 5 && command == "asset":
+	default:
+		req.Ack(map[string]string{"text": usage})
+		return
+	}
+	fleetActor := models.Actor{SlackUserID: userId, Source: models.SourceSlashCommand}
+
+	switch command {
+	case "role":
+		target := parseUserID(args[1])
+		role, ok := models.ParseRole(args[2])
+		if !ok {
+			req.Ack(map[string]string{"text": "⚠️ Role must be `member`, `lead` or `admin`."})
+			return
+		}
+		if err := models.SetUserRole(target, role, fleetActor); err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, "")})
+			return
+		}
+		log.Printf("Fleet admin %s set role of %s to %s", userId, target, role)
+		req.Ack(map[string]string{"text": fmt.Sprintf("✅ <@%s> is now a %s.", target, strings.ReplaceAll(string(role), "_", " "))})
+
+	case "assign":
+		truckName := cases.Title(language.English).String(strings.ToLower(args[1]))
+		truck, err := models.GetTruckByName(truckName)
+		if err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+		team := strings.ToLower(args[2])
+		if team == "none" {
+			truck.DefaultTeam = nil
+		} else {
+			truck.DefaultTeam = &team
+		}
+		if err := models.UpdateTruck(*truck, fleetActor); err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+		log.Printf("Fleet admin %s assigned truck %s to %s", userId, truckName, team)
+		req.Ack(map[string]string{"text": fmt.Sprintf("✅ Truck `%s` is now assigned to %s.", truckName, team)})
+
+	case "features":
+		truckName := cases.Title(language.English).String(strings.ToLower(args[1]))
+		truck, err := models.GetTruckByName(truckName)
+		if err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+		truck.Features = nil
+		if !strings.EqualFold(args[2], "none") {
+			truck.Features = strings.Split(args[2], ",")
+		}
+		if err := models.UpdateTruck(*truck, fleetActor); err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+		log.Printf("Fleet admin %s set features of truck %s to %s", userId, truckName, args[2])
+		req.Ack(map[string]string{"text": fmt.Sprintf("✅ Truck `%s` now has features: %s.", truckName, strings.ToLower(args[2]))})
+
+	case "asset":
+		assetType, ok := models.ParseAssetType(args[2])
+		if !ok {
+			req.Ack(map[string]string{"text": "⚠️ Asset type must be `trailer` or `equipment`."})
+			return
+		}
+		var requires, features []string
+		if len(args) >= 4 && !strings.EqualFold(args[3], "none") {
+			requires = strings.Split(args[3], ",")
+		}
+		if len(args) == 5 {
+			features = strings.Split(args[4], ",")
+		}
+		asset, err := models.InsertAsset(cases.Title(language.English).String(strings.ToLower(args[1])), assetType, features, requires, fleetActor)
+		if err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, "")})
+			return
+		}
+		log.Printf("Fleet admin %s added %s %s", userId, asset.Type, asset.Name)
+		req.Ack(map[string]string{"text": fmt.Sprintf("✅ Added %s `%s`. Book it with `/checkout [truck-name] with %s`.", asset.Type, asset.Name, asset.Name)})
+
+	case "class":
+		truckName := cases.Title(language.English).String(strings.ToLower(args[1]))
+		truck, err := models.GetTruckByName(truckName)
+		if err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+		truck.Class = strings.ToLower(args[2])
+		if truck.Class == "none" {
+			truck.Class = ""
+		}
+		if err := models.UpdateTruck(*truck, fleetActor); err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+		log.Printf("Fleet admin %s set class of truck %s to %q", userId, truckName, truck.Class)
+		if truck.Class == "" {
+			req.Ack(map[string]string{"text": fmt.Sprintf("✅ Truck `%s` now only needs a driver's license.", truckName)})
+			return
+		}
+		req.Ack(map[string]string{"text": fmt.Sprintf("✅ Truck `%s` now needs a %s certification.", truckName, truck.Class)})
+
+	case "maintenance":
+		truckName := cases.Title(language.English).String(strings.ToLower(args[1]))
+		truck, err := models.GetTruckByName(truckName)
+		if err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+		first, err := time.ParseInLocation("2006-01-02", args[2], h.cal.Location())
+		if err != nil {
+			req.Ack(map[string]string{"text": "⚠️ Invalid date. Use YYYY-MM-DD, e.g. `2026-11-03`."})
+			return
+		}
+		last := first
+		if len(args) == 4 {
+			if last, err = time.ParseInLocation("2006-01-02", args[3], h.cal.Location()); err != nil {
+				req.Ack(map[string]string{"text": "⚠️ Invalid date. Use YYYY-MM-DD, e.g. `2026-11-05`."})
+				return
+			}
+		}
+		block, err := models.ScheduleMaintenance(models.MaintenanceBlock{
+			TruckID:   truck.ID,
+			StartDate: first,
+			EndDate:   last.AddDate(0, 0, 1),
+		}, fleetActor)
+		if err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+		log.Printf("Fleet admin %s took truck %s out of service from %s to %s", userId, truckName, block.StartDate, block.EndDate)
+		req.Ack(map[string]string{"text": fmt.Sprintf("🔧 Truck `%s` is out of service from %s through %s.", truckName, first.Format("Jan 2"), last.Format("Jan 2"))})
+
+	case "license", "cert":
+		target := parseUserID(args[1])
+		kind, class := models.QualificationLicense, ""
+		if command == "cert" {
+			kind, class = models.QualificationCertification, args[2]
+		}
+		expires, err := h.parseExpiryDate(args[len(args)-1])
+		if err != nil {
+			req.Ack(map[string]string{"text": "⚠️ Invalid expiry date. Use YYYY-MM-DD, e.g. `2027-05-01`."})
+			return
+		}
+		q, err := models.RecordQualification(target, kind, class, expires, fleetActor)
+		if err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, "")})
+			return
+		}
+		log.Printf("Fleet admin %s recorded %s for %s expiring %s", userId, q.Label(), target, args[len(args)-1])
+		req.Ack(map[string]string{"text": fmt.Sprintf("✅ Recorded <@%s>'s %s, expiring %s.", target, q.Label(), expires.Format("Jan 2, 2006"))})
+
+	default:
+		req.Ack(map[string]string{"text": usage})
+	}
+}
+
+// parseExpiryDate reads a license or certification expiry date given as
+// YYYY-MM-DD. The license or certification is good for the whole of that
+// day, so it expires at the very end of it.
+func (h *Handler) parseExpiryDate(s string) (time.Time, error) {
+	day, err := time.ParseInLocation("2006-01-02", s, h.cal.Location())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
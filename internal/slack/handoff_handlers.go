@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"truck-checkout/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/slack-go/slack"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// HandleRequest asks whoever has a truck out to hand it over, e.g.
+// `/request Tulip`.
+func (h *Handler) HandleRequest(client Messenger, req Responder, args []string, userId string, userName string) {
+	if len(args) != 1 {
+		req.Ack(map[string]string{"text": "ℹ️ Use `/request [truck-name]` to ask whoever has a truck out to hand it over to you."})
+		return
+	}
+	truckName := cases.Title(language.English).String(strings.ToLower(args[0]))
+
+	truck, err := models.GetTruckByName(truckName)
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+	checkout, err := models.GetActiveCheckoutByTruckID(truck.ID)
+	if err != nil {
+		req.Ack(map[string]string{
+			"text": fmt.Sprintf("ℹ️ Truck `%s` isn't checked out right now. Use `/checkout %s` to take it.", truck.Name, truck.Name),
+		})
+		return
+	}
+
+	text, err := h.requestHandoff(client, checkout, truck, userId)
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truck.Name)})
+		return
+	}
+	req.Ack(map[string]string{"text": text})
+}
+
+// handleRequestHandoff is the Request handoff button on a checkout
+// announcement.
+func (h *Handler) handleRequestHandoff(client Messenger, callback *slack.InteractionCallback, value string) {
+	checkout, truck, ok := h.announcedCheckout(client, callback, value)
+	if !ok {
+		return
+	}
+	if checkout.ReleasedAt != nil || checkout.CancelledAt != nil || !checkout.EndDate.After(h.cal.Now()) {
+		replyEphemeral(client, callback, h.errorMessage(models.ErrNoActiveCheckout, truck.Name))
+		return
+	}
+
+	text, err := h.requestHandoff(client, checkout, truck, callback.User.ID)
+	if err != nil {
+		text = h.errorMessage(err, truck.Name)
+	}
+	replyEphemeral(client, callback, text)
+}
+
+// requestHandoff DMs the holder of checkout with Accept and Decline buttons
+// for handing the truck over to requesterID, and returns the text to show
+// the requester. The requester must be able to check the truck out
+// themselves.
+func (h *Handler) requestHandoff(client Messenger, checkout *models.Checkout, truck *models.Truck, requesterID string) (string, error) {
+	if checkout.UserID == requesterID {
+		return fmt.Sprintf("ℹ️ You already have `%s`.", truck.Name), nil
+	}
+
+	requester, err := models.GetUserBySlackID(requesterID)
+	if err != nil {
+		return "", err
+	}
+	if requester == nil {
+		return "⚠️ Pick your team with `/profile team [team]` before requesting a truck.", nil
+	}
+	if err := models.CheckTeamAccess(truck, requester.Team); err != nil {
+		return "", err
+	}
+	if err := models.CheckDriverQualified(requesterID, truck, checkout.EndDate); err != nil {
+		return "", err
+	}
+
+	text := fmt.Sprintf("🔁 <@%s> would like to take over truck *%s* for the rest of your checkout (until %s). Are you done with it?",
+		requesterID, truck.Name, h.formatDateRange(h.cal.Now(), checkout.EndDate))
+	value := checkout.ID.String() + ":" + requesterID
+	accept := slack.NewButtonBlockElement("accept_handoff", value, slack.NewTextBlockObject("plain_text", "Accept", false, false))
+	accept.Style = slack.StylePrimary
+	decline := slack.NewButtonBlockElement("decline_handoff", value, slack.NewTextBlockObject("plain_text", "Decline", false, false))
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+		slack.NewActionBlock("handoff_response", accept, decline),
+	}
+	if err := client.DirectMessage(checkout.UserID, slack.MsgOptionText(text, false), slack.MsgOptionBlocks(blocks...)); err != nil {
+		log.Printf("Failed to DM %s about a handoff request: %v", checkout.UserID, err)
+		return fmt.Sprintf("❌ Couldn't reach %s. Please try again.", checkout.UserName), nil
+	}
+
+	log.Printf("%s asked %s to hand over truck %s", requesterID, checkout.UserID, truck.Name)
+	return fmt.Sprintf("✅ Asked %s to hand over `%s`. I'll let you know what they say.", checkout.UserName, truck.Name), nil
+}
+
+// handleHandoffResponse handles the holder's Accept or Decline on a handoff
+// request. Accepting releases their checkout and checks the truck out to the
+// requester for the rest of it in one go.
+func (h *Handler) handleHandoffResponse(client Messenger, callback *slack.InteractionCallback, value string, accepted bool) {
+	reply := func(text string) {
+		err := client.Respond(callback.ResponseURL, &slack.WebhookMessage{Text: text, ReplaceOriginal: true})
+		if err != nil {
+			log.Printf("Failed to reply to %s: %v", callback.User.ID, err)
+		}
+	}
+	notify := func(userID, text string) {
+		if err := client.DirectMessage(userID, slack.MsgOptionText(text, false)); err != nil {
+			log.Printf("Failed to DM %s about a handoff: %v", userID, err)
+		}
+	}
+
+	id, requesterID, _ := strings.Cut(value, ":")
+	checkoutID, err := uuid.Parse(id)
+	if err != nil || requesterID == "" {
+		log.Printf("Invalid handoff request %q", value)
+		return
+	}
+	checkout, err := models.GetCheckoutByID(checkoutID)
+	if err != nil {
+		reply(h.errorMessage(models.ErrCheckoutNotFound, ""))
+		return
+	}
+	truck, err := models.GetTruckByID(checkout.TruckID)
+	if err != nil {
+		reply(h.errorMessage(err, ""))
+		return
+	}
+	if callback.User.ID != checkout.UserID {
+		reply(h.errorMessage(models.ErrPermissionDenied, truck.Name))
+		return
+	}
+
+	if !accepted {
+		log.Printf("%s declined to hand over truck %s to %s", checkout.UserID, truck.Name, requesterID)
+		reply(fmt.Sprintf("👍 You're keeping `%s`.", truck.Name))
+		notify(requesterID, fmt.Sprintf("🚫 <@%s> can't hand over truck *%s* right now.", checkout.UserID, truck.Name))
+		return
+	}
+
+	requester, err := models.GetUserBySlackID(requesterID)
+	if err != nil || requester == nil {
+		reply("❌ Error retrieving user information.")
+		return
+	}
+	// The requester's team or qualifications may have changed since they
+	// asked.
+	if err := models.CheckTeamAccess(truck, requester.Team); err != nil {
+		reply(h.errorMessage(err, truck.Name))
+		return
+	}
+	if err := models.CheckDriverQualified(requesterID, truck, checkout.EndDate); err != nil {
+		reply(h.errorMessage(err, truck.Name))
+		return
+	}
+
+	now := h.cal.Now()
+	check := policyCheck(h.cfg.CheckoutPolicy(), models.CheckoutRequest{
+		TruckName:    truck.Name,
+		UserID:       requesterID,
+		TeamName:     requester.Team,
+		BusinessDays: h.cal.BusinessDaysIn(now, checkout.EndDate),
+		Now:          now,
+	}, requester, false)
+
+	actor := models.Actor{SlackUserID: callback.User.ID, Source: models.SourceButton}
+	next, err := models.HandOffCheckout(checkout.ID, models.Checkout{
+		ID:       uuid.New(),
+		UserID:   requesterID,
+		UserName: requester.Username,
+		TeamName: requester.Team,
+		Purpose:  fmt.Sprintf("Handed over by %s", checkout.UserName),
+	}, check, actor)
+	if err != nil {
+		log.Printf("Failed to hand over truck %s to %s: %v", truck.Name, requesterID, err)
+		reply(h.errorMessage(err, truck.Name))
+		return
+	}
+	logOverrides(check, requester.Username)
+	handOffCustody(truck.ID, next.ID, requesterID, actor)
+	handedOff := *checkout
+	handedOff.ReleasedAt = &next.StartDate
+	h.moveCalendarEvent(truck, &handedOff)
+	h.addCalendarEvent(truck, next)
+	log.Printf("Truck %s handed over from %s to %s", truck.Name, checkout.UserName, requester.Username)
+
+	handedOver := fmt.Sprintf("%s\n🔁 Handed over to *%s* at %s", h.checkoutAnnouncementText(checkout, truck.Name), requester.Username,
+		h.cal.Now().Format("3:04 PM"))
+	updateAnnouncement(client, checkout, handedOver, false)
+	assets, err := models.GetCheckoutAssets(next.ID)
+	if err != nil {
+		log.Printf("Failed to load assets handed over with %s: %v", truck.Name, err)
+	}
+	h.announceCheckout(client, *next, requester.Username, truck.Name, assets)
+
+	dates := h.formatDateRange(next.StartDate, next.EndDate)
+	reply(fmt.Sprintf("✅ You handed `%s` over to %s. Please pass on the keys and fuel card.", truck.Name, requester.Username))
+	notify(requesterID, fmt.Sprintf("✅ <@%s> handed over truck *%s*. It's checked out to you for %s.", checkout.UserID, truck.Name, dates))
+}
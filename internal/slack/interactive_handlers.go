@@ -3,17 +3,15 @@ package handlers
 import (
 	"fmt"
 	"log"
-	"strconv"
 	"strings"
 	"truck-checkout/internal/models"
 
 	"github.com/slack-go/slack"
-	"github.com/slack-go/slack/socketmode"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
-func showTeamSelectionModal(client *socketmode.Client, req *socketmode.Request, triggerID string, truckName string, businessDays int, userId string, userName string, channelId string) {
+func showTeamSelectionModal(client Messenger, req Responder, triggerID string, truckName string, length checkoutLength, assetNames []string, userId string, userName string, channelId string) {
 	// Create options for team selection
 	var options []*slack.OptionBlockObject
 	for _, team := range models.ValidTeams {
@@ -29,7 +27,7 @@ func showTeamSelectionModal(client *socketmode.Client, req *socketmode.Request,
 	}
 
 	// Store checkout parameters in metadata so we can retrieve them later
-	metadata := fmt.Sprintf("%s|%d|%s|%s|%s", truckName, businessDays, userId, userName, channelId)
+	metadata := fmt.Sprintf("%s|%s|%s|%s|%s|%s", truckName, length, userId, userName, channelId, strings.Join(assetNames, ","))
 	
 	modalRequest := slack.ModalViewRequest{
 		Type:            slack.ViewType("modal"),
@@ -61,22 +59,22 @@ func showTeamSelectionModal(client *socketmode.Client, req *socketmode.Request,
 	}
 	
 	// Show the modal
-	_, err := client.OpenView(triggerID, modalRequest)
+	err := client.OpenView(triggerID, modalRequest)
 	if err != nil {
 		log.Printf("Failed to open team selection modal: %v", err)
-		client.Ack(*req, map[string]string{
+		req.Ack(map[string]string{
 			"text": "❌ Error showing team selection. Please try again.",
 		})
 		return
 	}
 	
 	// Acknowledge the slash command (modal is now open)
-	client.Ack(*req, map[string]string{})
+	req.Ack(map[string]string{})
 }
 
-func handleButtonActions(client *socketmode.Client, req *socketmode.Request, callback *slack.InteractionCallback) {
+func (h *Handler) handleButtonActions(client Messenger, req Responder, callback *slack.InteractionCallback) {
 	if len(callback.ActionCallback.BlockActions) == 0 {
-		client.Ack(*req)
+		req.Ack()
 		return
 	}
 
@@ -87,9 +85,37 @@ func handleButtonActions(client *socketmode.Client, req *socketmode.Request, cal
 		// Handle "Ask in #vehicleupdates" button
 	case "continue_anyway":
 		// Handle "Continue anyway" button
+	case "claim_waitlist_offer":
+		req.Ack()
+		h.handleClaimWaitlistOffer(client, callback, action.Value)
+		return
+	case "confirm_custody_return":
+		req.Ack()
+		h.handleConfirmCustodyReturn(client, callback, action.Value)
+		return
+	case "release_checkout":
+		req.Ack()
+		h.handleReleaseButton(client, callback, action.Value)
+		return
+	case "extend_checkout", "extend_checkout_override":
+		req.Ack()
+		h.handleExtendButton(client, callback, action.Value, action.ActionID == "extend_checkout_override")
+		return
+	case "request_handoff":
+		req.Ack()
+		h.handleRequestHandoff(client, callback, action.Value)
+		return
+	case "accept_handoff", "decline_handoff":
+		req.Ack()
+		h.handleHandoffResponse(client, callback, action.Value, action.ActionID == "accept_handoff")
+		return
+	case "cancel_checkout":
+		req.Ack()
+		h.handleCancelSelection(client, callback, action.SelectedOption.Value)
+		return
 	}
 
-	client.Ack(*req)
+	req.Ack()
 }
 
 // This function builds a simple modal that just displays an error message.
@@ -113,29 +139,40 @@ func buildErrorModal(errorMessage string) slack.ModalViewRequest {
 	}
 }
 
-func handleTeamSelectionModal(client *socketmode.Client, req *socketmode.Request, callback *slack.InteractionCallback) {
+func (h *Handler) handleTeamSelectionModal(client Messenger, req Responder, callback *slack.InteractionCallback) {
 	teamValue := callback.View.State.Values["team_block"]["team_select"].SelectedOption.Value
 	metadata := callback.View.PrivateMetadata
 	parts := strings.Split(metadata, "|")
-	if len(parts) != 5 {
-		client.Ack(*req, map[string]string{
+	// Modals opened before assets were added have no sixth part.
+	if len(parts) != 5 && len(parts) != 6 {
+		req.Ack(map[string]string{
 			"text": "❌ Error processing team selection.",
 		})
 		return
 	}
 
 	truckName := parts[0]
-	businessDays, _ := strconv.Atoi(parts[1])
+	length, err := parseCheckoutLength(parts[1])
+	if err != nil {
+		req.Ack(map[string]string{
+			"text": "❌ Error processing team selection.",
+		})
+		return
+	}
 	userId := parts[2]
 	userName := parts[3]
 	channelId := parts[4]
+	var assetNames []string
+	if len(parts) == 6 && parts[5] != "" {
+		assetNames = strings.Split(parts[5], ",")
+	}
 
 	log.Printf("User %s selected team %s for truck %s", userName, teamValue, truckName)
 
-	user, err := models.GetOrCreateUserBySlackID(userId, userName, teamValue)
+	user, err := models.GetOrCreateUserBySlackID(userId, userName, teamValue, models.Actor{SlackUserID: userId, Source: models.SourceModal})
 	if err != nil {
 		log.Printf("Failed to create user %s (%s) with team %s: %v", userName, userId, teamValue, err)
-		client.Ack(*req, map[string]string{
+		req.Ack(map[string]string{
 			"text": "❌ Error creating user profile. Please try again.",
 		})
 		return
@@ -143,26 +180,30 @@ func handleTeamSelectionModal(client *socketmode.Client, req *socketmode.Request
 
 	log.Printf("User %s (%s) with team %s is checking out the truck %s", userName, userId, teamValue, truckName)
 
-	responseText, err := performCheckout(client, user, truckName, businessDays, userId, userName)
+	assets, err := lookupAssets(assetNames)
+	var responseText string
+	if err == nil {
+		responseText, err = h.performCheckout(client, user, truckName, length, h.cal.Now(), assets, false, userId, userName, models.SourceModal)
+	}
 	if err != nil {
 		log.Printf("Checkout error: %v", err)
-		errorView := buildErrorModal(err.Error())
+		errorView := buildErrorModal(h.errorMessage(err, truckName))
 		response := map[string]interface{}{
 			"response_action": "update",
 			"view":            errorView,
 		}
 
-		client.Ack(*req, response)
+		req.Ack(response)
 		return
 	}
 
 	combinedMessage := fmt.Sprintf("👋 Welcome! Created your profile with team %s. %s", teamValue, responseText)
 	log.Printf("Final response to user %s: %s in %s", userName, combinedMessage, channelId)
-	client.Ack(*req, map[string]interface{}{
+	req.Ack(map[string]interface{}{
         "response_action": "clear",
     })
 
-	_, err = client.PostEphemeral(
+	err = client.PostEphemeral(
         channelId,
         callback.User.ID, 
         slack.MsgOptionText(combinedMessage, false),
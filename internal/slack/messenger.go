@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// Responder answers the one slash command or interaction being handled.
+// payload, if given, is the response, e.g. {"text": ...} or a modal
+// response_action.
+type Responder interface {
+	Ack(payload ...interface{})
+}
+
+// socketResponder answers a socket mode request.
+type socketResponder struct {
+	client *socketmode.Client
+	req    *socketmode.Request
+}
+
+// NewSocketResponder answers req over client.
+func NewSocketResponder(client *socketmode.Client, req *socketmode.Request) Responder {
+	return &socketResponder{client: client, req: req}
+}
+
+func (r *socketResponder) Ack(payload ...interface{}) {
+	r.client.Ack(*r.req, payload...)
+}
+
+// Messenger is what the handlers need from Slack. Keeping it this narrow
+// lets the checkout logic run outside a socket mode session, from the HTTP
+// API or a scheduler, and lets tests record what would have been sent.
+type Messenger interface {
+	// PostMessage posts to a channel and returns where the message landed,
+	// for updating it later.
+	PostMessage(channelID string, options ...slack.MsgOption) (channel string, ts string, err error)
+	PostEphemeral(channelID, userID string, options ...slack.MsgOption) error
+	UpdateMessage(channelID, ts string, options ...slack.MsgOption) error
+	OpenView(triggerID string, view slack.ModalViewRequest) error
+	UpdateView(viewID string, view slack.ModalViewRequest) error
+	// DirectMessage sends userID a message from the bot.
+	DirectMessage(userID string, options ...slack.MsgOption) error
+	// Respond replies through an interaction's response_url, e.g. to
+	// replace the message whose button was pressed.
+	Respond(responseURL string, msg *slack.WebhookMessage) error
+}
+
+// socketMessenger is a Messenger over a socket mode connection.
+type socketMessenger struct {
+	client *socketmode.Client
+}
+
+// NewSocketMessenger adapts a socket mode client to Messenger.
+func NewSocketMessenger(client *socketmode.Client) Messenger {
+	return &socketMessenger{client: client}
+}
+
+func (m *socketMessenger) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	return m.client.PostMessage(channelID, options...)
+}
+
+func (m *socketMessenger) PostEphemeral(channelID, userID string, options ...slack.MsgOption) error {
+	_, err := m.client.PostEphemeral(channelID, userID, options...)
+	return err
+}
+
+func (m *socketMessenger) UpdateMessage(channelID, ts string, options ...slack.MsgOption) error {
+	_, _, _, err := m.client.UpdateMessage(channelID, ts, options...)
+	return err
+}
+
+func (m *socketMessenger) OpenView(triggerID string, view slack.ModalViewRequest) error {
+	_, err := m.client.OpenView(triggerID, view)
+	return err
+}
+
+func (m *socketMessenger) UpdateView(viewID string, view slack.ModalViewRequest) error {
+	_, err := m.client.UpdateView(view, "", "", viewID)
+	return err
+}
+
+// DirectMessage posts to the user's ID, which Slack delivers to their DM
+// with the bot.
+func (m *socketMessenger) DirectMessage(userID string, options ...slack.MsgOption) error {
+	_, _, err := m.client.PostMessage(userID, options...)
+	return err
+}
+
+func (m *socketMessenger) Respond(responseURL string, msg *slack.WebhookMessage) error {
+	return slack.PostWebhook(responseURL, msg)
+}
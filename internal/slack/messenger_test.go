@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"truck-checkout/internal/calendar"
+	"truck-checkout/internal/config"
+	"truck-checkout/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/slack-go/slack"
+)
+
+// sentMessage is a message a handler sent through a recordingMessenger.
+type sentMessage struct {
+	// Kind is "post", "ephemeral", "update", "dm" or "respond".
+	Kind    string
+	Channel string
+	User    string
+	TS      string
+	Text    string
+}
+
+// recordingMessenger is a Messenger that keeps everything the handlers send
+// instead of talking to Slack. It is also the Responder for the request
+// under test, so acks land alongside the messages.
+type recordingMessenger struct {
+	acks     []interface{}
+	messages []sentMessage
+	views    []slack.ModalViewRequest
+	ts       int
+	// failPosts makes PostMessage fail, as if the announce channel were
+	// unreachable.
+	failPosts bool
+}
+
+func (m *recordingMessenger) Ack(payload ...interface{}) {
+	var p interface{}
+	if len(payload) > 0 {
+		p = payload[0]
+	}
+	m.acks = append(m.acks, p)
+}
+
+func (m *recordingMessenger) record(kind, channel, user, ts string, options []slack.MsgOption) {
+	_, values, _ := slack.UnsafeApplyMsgOptions("", channel, "", options...)
+	m.messages = append(m.messages, sentMessage{Kind: kind, Channel: channel, User: user, TS: ts, Text: values.Get("text")})
+}
+
+func (m *recordingMessenger) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	if m.failPosts {
+		return "", "", fmt.Errorf("channel_not_found")
+	}
+	m.ts++
+	ts := fmt.Sprintf("1700000000.%06d", m.ts)
+	m.record("post", channelID, "", ts, options)
+	return channelID, ts, nil
+}
+
+func (m *recordingMessenger) PostEphemeral(channelID, userID string, options ...slack.MsgOption) error {
+	m.record("ephemeral", channelID, userID, "", options)
+	return nil
+}
+
+func (m *recordingMessenger) UpdateMessage(channelID, ts string, options ...slack.MsgOption) error {
+	m.record("update", channelID, "", ts, options)
+	return nil
+}
+
+func (m *recordingMessenger) OpenView(triggerID string, view slack.ModalViewRequest) error {
+	m.views = append(m.views, view)
+	return nil
+}
+
+func (m *recordingMessenger) UpdateView(viewID string, view slack.ModalViewRequest) error {
+	m.views = append(m.views, view)
+	return nil
+}
+
+func (m *recordingMessenger) DirectMessage(userID string, options ...slack.MsgOption) error {
+	m.record("dm", userID, userID, "", options)
+	return nil
+}
+
+func (m *recordingMessenger) Respond(responseURL string, msg *slack.WebhookMessage) error {
+	m.messages = append(m.messages, sentMessage{Kind: "respond", Channel: responseURL, Text: msg.Text})
+	return nil
+}
+
+// ackText is the text of the last ack.
+func (m *recordingMessenger) ackText(t *testing.T) string {
+	t.Helper()
+	if len(m.acks) == 0 {
+		t.Fatal("expected an ack")
+	}
+	switch payload := m.acks[len(m.acks)-1].(type) {
+	case map[string]string:
+		return payload["text"]
+	case map[string]interface{}:
+		if text, ok := payload["text"].(string); ok {
+			return text
+		}
+	}
+	t.Fatalf("expected a text ack, got %#v", m.acks[len(m.acks)-1])
+	return ""
+}
+
+// dmsTo returns the DMs sent to userID.
+func (m *recordingMessenger) dmsTo(userID string) []sentMessage {
+	var dms []sentMessage
+	for _, msg := range m.messages {
+		if msg.Kind == "dm" && msg.Channel == userID {
+			dms = append(dms, msg)
+		}
+	}
+	return dms
+}
+
+// newTestHandler returns a Handler with the default configuration and
+// business calendar, which a test may change.
+func newTestHandler() *Handler {
+	return NewHandler(config.Default(), calendar.DefaultBusinessCalendar(), nil)
+}
+
+// openAllHours gives h a calendar that is open around the clock, so
+// checkouts start straight away whenever the test runs.
+func openAllHours(h *Handler) {
+	hours := map[time.Weekday]calendar.Hours{}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		hours[d] = calendar.Hours{Open: 0, Close: 24 * 60}
+	}
+	h.cal = calendar.NewBusinessCalendar(hours, nil, time.UTC)
+}
+
+func TestCheckoutAndRelease_RecordingMessenger(t *testing.T) {
+	models.ResetTestDB(t)
+	h := newTestHandler()
+	openAllHours(h)
+
+	team := "beltline"
+	if err := models.InsertTruck("Tulip", &team, "", false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	if _, err := models.GetOrCreateUserBySlackID("U123", "jo", "beltline", testActor); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	admin := models.Actor{SlackUserID: "UADMIN", Source: models.SourceSlashCommand}
+	if _, err := models.RecordQualification("U123", models.QualificationLicense, "", time.Now().AddDate(1, 0, 0), admin); err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+
+	m := &recordingMessenger{}
+	h.HandleCheckout(m, m, "tulip", wholeDays(1), nil, false, "U123", "jo", "trigger-1", "C123")
+	if text := m.ackText(t); !strings.Contains(text, "Tulip") {
+		t.Errorf("unexpected checkout reply %q", text)
+	}
+	if len(m.messages) != 1 || m.messages[0].Kind != "post" || m.messages[0].Channel != h.cfg.AnnounceChannel {
+		t.Fatalf("expected one announcement, got %+v", m.messages)
+	}
+	announcement := m.messages[0]
+
+	m = &recordingMessenger{}
+	h.HandleReleaseTruck(m, m, "Tulip", "U123", "jo", false)
+	if text := m.ackText(t); !strings.Contains(text, "released successfully") {
+		t.Errorf("unexpected release reply %q", text)
+	}
+	var updated *sentMessage
+	for i := range m.messages {
+		if m.messages[i].Kind == "update" {
+			updated = &m.messages[i]
+		}
+	}
+	if updated == nil {
+		t.Fatalf("expected the announcement to be updated, got %+v", m.messages)
+	}
+	if updated.Channel != announcement.Channel || updated.TS != announcement.TS {
+		t.Errorf("expected an update of %s/%s, got %s/%s", announcement.Channel, announcement.TS, updated.Channel, updated.TS)
+	}
+	if !strings.Contains(updated.Text, "Released by *jo*") {
+		t.Errorf("unexpected release announcement %q", updated.Text)
+	}
+}
+
+func TestRelease_GoesByCheckoutsNotFlag(t *testing.T) {
+	models.ResetTestDB(t)
+	h := newTestHandler()
+	h.cfg.FleetAdmins = []string{"UADMIN"}
+
+	// Tulip is flagged as out with no checkout; Watson's checkout ran past
+	// its end without being released.
+	team := "beltline"
+	for _, truck := range []struct {
+		name    string
+		flagged bool
+	}{{"Tulip", true}, {"Watson", false}} {
+		if err := models.InsertTruck(truck.name, &team, "", truck.flagged, testActor); err != nil {
+			t.Fatalf("failed to insert truck: %v", err)
+		}
+	}
+	watson, err := models.GetTruckByName("Watson")
+	if err != nil {
+		t.Fatalf("failed to load truck: %v", err)
+	}
+	if _, err := models.GetOrCreateUserBySlackID("U123", "jo", team, testActor); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	start := time.Now().Add(-6 * time.Hour)
+	overdue := models.Checkout{
+		ID:        uuid.New(),
+		TruckID:   watson.ID,
+		UserID:    "U123",
+		UserName:  "jo",
+		TeamName:  team,
+		StartDate: start,
+		EndDate:   start.Add(2 * time.Hour),
+	}
+	if err := models.CreateCheckout(overdue, nil, testActor); err != nil {
+		t.Fatalf("failed to create checkout: %v", err)
+	}
+
+	m := &recordingMessenger{}
+	h.HandleReleaseTruck(m, m, "Tulip", "UADMIN", "ada", false)
+	if text := m.ackText(t); !strings.Contains(text, "not currently checked out") {
+		t.Errorf("expected the stale flag to be ignored, got %q", text)
+	}
+
+	m = &recordingMessenger{}
+	h.HandleReleaseTruck(m, m, "Watson", "U123", "jo", true)
+	if text := m.ackText(t); !strings.Contains(text, "released successfully") {
+		t.Errorf("expected the overdue checkout to be released, got %q", text)
+	}
+	if released, err := models.GetCheckoutByID(overdue.ID); err != nil || released.ReleasedAt == nil {
+		t.Errorf("expected the overdue checkout to be marked released, got %+v (%v)", released, err)
+	}
+}
+
+func TestExtendAndCancel_RecordingMessenger(t *testing.T) {
+	models.ResetTestDB(t)
+	h := newTestHandler()
+	openAllHours(h)
+
+	team := "beltline"
+	if err := models.InsertTruck("Tulip", &team, "", false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	user, err := models.GetOrCreateUserBySlackID("U123", "jo", "beltline", testActor)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	admin := models.Actor{SlackUserID: "UADMIN", Source: models.SourceSlashCommand}
+	if _, err := models.RecordQualification("U123", models.QualificationLicense, "", time.Now().AddDate(1, 0, 0), admin); err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+
+	m := &recordingMessenger{}
+	h.HandleCheckout(m, m, "tulip", wholeDays(1), nil, false, "U123", "jo", "trigger-1", "C123")
+	truck, _ := models.GetTruckByName("Tulip")
+	checkout, err := models.GetActiveCheckoutByTruckID(truck.ID)
+	if err != nil {
+		t.Fatalf("expected the checkout to be active: %v", err)
+	}
+
+	// A license that runs out before the extra day stops the extension.
+	if _, err := models.RecordQualification("U123", models.QualificationLicense, "", checkout.EndDate.Add(time.Minute), admin); err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+	callback := &slack.InteractionCallback{User: slack.User{ID: "U123", Name: "jo"}}
+	m = &recordingMessenger{}
+	h.handleExtendButton(m, callback, checkout.ID.String(), false)
+	if len(m.messages) != 1 || m.messages[0].Kind != "ephemeral" || !strings.Contains(m.messages[0].Text, "driver's license") {
+		t.Fatalf("expected the extension to be refused for the license, got %+v", m.messages)
+	}
+	if after, _ := models.GetCheckoutByID(checkout.ID); !after.EndDate.Equal(checkout.EndDate) {
+		t.Errorf("expected the checkout to keep its end, got %v", after.EndDate)
+	}
+
+	// Cancelling a reservation takes the buttons off its announcement.
+	if _, err := models.RecordQualification("U123", models.QualificationLicense, "", time.Now().AddDate(1, 0, 0), admin); err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+	m = &recordingMessenger{}
+	later := h.cal.Now().AddDate(0, 0, 3)
+	if _, err := h.performCheckout(m, user, "Tulip", wholeDays(1), later, nil, false, "U123", "jo", models.SourceSlashCommand); err != nil {
+		t.Fatalf("failed to book Tulip: %v", err)
+	}
+	announcement := m.messages[len(m.messages)-1]
+
+	m = &recordingMessenger{}
+	h.HandleCancel(m, m, []string{"tulip"}, "U123", "jo")
+	if text := m.ackText(t); !strings.Contains(text, "has been cancelled") {
+		t.Errorf("unexpected cancel reply %q", text)
+	}
+	if len(m.messages) != 1 || m.messages[0].Kind != "update" || m.messages[0].TS != announcement.TS ||
+		!strings.Contains(m.messages[0].Text, "Cancelled by *jo*") {
+		t.Errorf("expected the reservation's announcement to be updated, got %+v", m.messages)
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"truck-checkout/internal/models"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// HandleOdometer records a start or end odometer reading against the
+// caller's checkout of a truck, e.g. `/odometer Tulip start 48210`.
+func (h *Handler) HandleOdometer(client Messenger, req Responder, args []string, userId string) {
+	if len(args) != 3 {
+		req.Ack(map[string]string{"text": "ℹ️ Use `/odometer [truck-name] start|end [reading]`, e.g. `/odometer Tulip start 48210`"})
+		return
+	}
+
+	truckName := cases.Title(language.English).String(strings.ToLower(args[0]))
+	kind := models.OdometerKind(strings.ToLower(args[1]))
+	if kind != models.OdometerStart && kind != models.OdometerEnd {
+		req.Ack(map[string]string{"text": "⚠️ Reading must be either `start` or `end`."})
+		return
+	}
+
+	reading, err := strconv.Atoi(strings.ReplaceAll(args[2], ",", ""))
+	if err != nil || reading < 0 {
+		req.Ack(map[string]string{"text": "⚠️ Invalid odometer reading. Use whole miles like `48210`."})
+		return
+	}
+
+	truck, err := models.GetTruckByName(truckName)
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+
+	checkout, err := h.mileageCheckout(truck, userId)
+	if errors.Is(err, models.ErrNoActiveCheckout) {
+		req.Ack(map[string]string{"text": fmt.Sprintf("ℹ️ You have no checkout of `%s` to record mileage against.", truckName)})
+		return
+	}
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+
+	if err := models.RecordOdometerReading(checkout.ID, kind, reading, userId); err != nil {
+		log.Printf("Failed to record odometer for truck %s: %v", truckName, err)
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+
+	req.Ack(map[string]string{
+		"text": fmt.Sprintf("✅ Recorded %s odometer of %d for `%s` (%s).", kind, reading, truckName, checkout.TeamName),
+	})
+}
+
+// HandleFuelPurchase logs fuel bought during the caller's checkout of a
+// truck, e.g. `/fuel Tulip 14.2 52.80 Shell on Ponce`.
+func (h *Handler) HandleFuelPurchase(client Messenger, req Responder, args []string, userId string) {
+	if len(args) < 3 {
+		req.Ack(map[string]string{"text": "ℹ️ Use `/fuel [truck-name] [gallons] [cost] [receipt note]`, e.g. `/fuel Tulip 14.2 52.80 Shell on Ponce`"})
+		return
+	}
+
+	truckName := cases.Title(language.English).String(strings.ToLower(args[0]))
+
+	gallons, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || gallons <= 0 {
+		req.Ack(map[string]string{"text": "⚠️ Invalid number of gallons. Use a positive number like `14.2`."})
+		return
+	}
+
+	cost, err := strconv.ParseFloat(strings.TrimPrefix(args[2], "$"), 64)
+	if err != nil || cost < 0 {
+		req.Ack(map[string]string{"text": "⚠️ Invalid cost. Use a dollar amount like `52.80`."})
+		return
+	}
+
+	truck, err := models.GetTruckByName(truckName)
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+
+	checkout, err := h.mileageCheckout(truck, userId)
+	if errors.Is(err, models.ErrNoActiveCheckout) {
+		req.Ack(map[string]string{"text": fmt.Sprintf("ℹ️ You have no checkout of `%s` to record fuel against.", truckName)})
+		return
+	}
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+
+	purchase := models.FuelPurchase{
+		CheckoutID:  checkout.ID,
+		Gallons:     gallons,
+		CostCents:   int64(math.Round(cost * 100)),
+		ReceiptNote: strings.Join(args[3:], " "),
+		PurchasedBy: userId,
+	}
+	if err := models.RecordFuelPurchase(purchase); err != nil {
+		log.Printf("Failed to record fuel purchase for truck %s: %v", truckName, err)
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+
+	req.Ack(map[string]string{
+		"text": fmt.Sprintf("⛽ Recorded %.1f gal ($%.2f) for `%s`, charged to %s.", gallons, cost, truckName, checkout.TeamName),
+	})
+}
+
+// mileageCheckout finds the checkout of truck that mileage and fuel from
+// userId are recorded against: their own in-progress checkout, or else their
+// most recent one. Only a fleet admin may record against someone else's, in
+// which case the truck's most recent checkout is used.
+func (h *Handler) mileageCheckout(truck *models.Truck, userId string) (*models.Checkout, error) {
+	checkout, err := models.GetLatestCheckoutByTruckID(truck.ID, userId)
+	if !errors.Is(err, models.ErrNoActiveCheckout) {
+		return checkout, err
+	}
+
+	checkout, err = models.GetLatestCheckoutByTruckID(truck.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	user, err := h.currentUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsFleetAdmin() {
+		log.Printf("Warning: User %s tried to record mileage for truck %s held by %s", userId, truck.Name, checkout.UserID)
+		return nil, models.ErrPermissionDenied
+	}
+	log.Printf("Override: fleet admin %s recorded mileage for truck %s held by %s", userId, truck.Name, checkout.UserID)
+	return checkout, nil
+}
+
+// HandleFuelReport shows miles and fuel cost per team for a month,
+// defaulting to the current one, e.g. `/fuelreport 2026-10`.
+func (h *Handler) HandleFuelReport(client Messenger, req Responder, args []string) {
+	month := h.cal.Now()
+	if len(args) > 0 {
+		parsed, err := time.ParseInLocation("2006-01", args[0], h.cal.Location())
+		if err != nil {
+			req.Ack(map[string]string{"text": "⚠️ Invalid month. Use `/fuelreport 2026-10`."})
+			return
+		}
+		month = parsed
+	}
+
+	report, err := models.GetTeamUsageReport(month.Year(), month.Month(), h.cal.Location())
+	if err != nil {
+		log.Printf("Failed to build fuel report: %v", err)
+		req.Ack(map[string]string{"text": "❌ Could not build the fuel report."})
+		return
+	}
+	if len(report) == 0 {
+		req.Ack(map[string]string{"text": fmt.Sprintf("ℹ️ No checkouts in %s.", month.Format("January 2006"))})
+		return
+	}
+
+	msg := fmt.Sprintf("⛽ *Fuel & mileage for %s:*\n", month.Format("January 2006"))
+	for _, u := range report {
+		msg += fmt.Sprintf("• %s: %d mi, %.1f gal, $%.2f (%d checkouts)\n",
+			u.TeamName, u.Miles, u.FuelGallons, float64(u.FuelCostCents)/100, u.Checkouts)
+	}
+
+	req.Ack(map[string]string{"text": msg})
+}
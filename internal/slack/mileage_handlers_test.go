@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"truck-checkout/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestOdometer_RecordsAgainstCallersCheckout(t *testing.T) {
+	models.ResetTestDB(t)
+	h := newTestHandler()
+	h.cfg.FleetAdmins = []string{"UADMIN"}
+
+	team := "beltline"
+	if err := models.InsertTruck("Tulip", &team, "", false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := models.GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to load truck: %v", err)
+	}
+	for _, u := range []struct{ id, name string }{{"U111", "alice"}, {"U222", "bob"}, {"U333", "carol"}} {
+		if _, err := models.GetOrCreateUserBySlackID(u.id, u.name, "beltline", testActor); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+
+	// bob had the truck yesterday; alice has it now.
+	now := time.Now()
+	checkouts := map[string]models.Checkout{}
+	for _, c := range []struct {
+		id, name string
+		start    time.Time
+	}{{"U222", "bob", now.Add(-30 * time.Hour)}, {"U111", "alice", now.Add(-time.Hour)}} {
+		checkout := models.Checkout{
+			ID:        uuid.New(),
+			TruckID:   truck.ID,
+			UserID:    c.id,
+			UserName:  c.name,
+			TeamName:  team,
+			StartDate: c.start,
+			EndDate:   c.start.Add(8 * time.Hour),
+		}
+		if err := models.CreateCheckout(checkout, nil, testActor); err != nil {
+			t.Fatalf("failed to create checkout: %v", err)
+		}
+		checkouts[c.id] = checkout
+	}
+
+	odometer := func(userID string, args ...string) string {
+		m := &recordingMessenger{}
+		h.HandleOdometer(m, m, append([]string{"Tulip"}, args...), userID)
+		return m.ackText(t)
+	}
+	recorded := func(userID string) int {
+		readings, err := models.GetOdometerReadingsByCheckoutID(checkouts[userID].ID)
+		if err != nil {
+			t.Fatalf("failed to load readings: %v", err)
+		}
+		return len(readings)
+	}
+
+	if text := odometer("U333", "start", "48000"); !strings.Contains(text, "permission") {
+		t.Errorf("expected someone with no checkout of the truck to be refused, got %q", text)
+	}
+	if text := odometer("U222", "end", "48100"); !strings.Contains(text, "Recorded end odometer") || recorded("U222") != 1 {
+		t.Errorf("expected bob's reading to go on his own checkout, got %q", text)
+	}
+	if text := odometer("U111", "start", "48150"); !strings.Contains(text, "Recorded start odometer") || recorded("U111") != 1 {
+		t.Errorf("expected alice's reading to go on her checkout, got %q", text)
+	}
+	if text := odometer("U111", "start", "48160"); !strings.Contains(text, "already been recorded") {
+		t.Errorf("expected a duplicate reading to be explained, got %q", text)
+	}
+	if text := odometer("UADMIN", "end", "48000"); !strings.Contains(text, "lower than the last odometer reading of 48150") {
+		t.Errorf("expected a low reading to be explained, got %q", text)
+	}
+	if text := odometer("UADMIN", "end", "48262"); !strings.Contains(text, "Recorded end odometer") || recorded("U111") != 2 {
+		t.Errorf("expected the admin's reading to go on alice's checkout, got %q", text)
+	}
+}
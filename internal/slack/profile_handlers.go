@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"truck-checkout/internal/models"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+const profileUsage = "ℹ️ Use `/profile` to see your profile, `/profile team [team]` to change team, `/profile phone [number|none]` to set your phone number, " +
+	"or `/profile license YYYY-MM-DD` and `/profile cert [class] YYYY-MM-DD` to record when your license or a certification expires."
+
+// HandleProfile shows or edits the user's own profile, e.g. `/profile`,
+// `/profile team beltline`, `/profile phone 404-555-0100` or
+// `/profile cert dump 2027-05-01`.
+func (h *Handler) HandleProfile(client *socketmode.Client, req Responder, args []string, userId string, userName string) {
+	actor := models.Actor{SlackUserID: userId, Source: models.SourceSlashCommand}
+
+	user, err := models.GetUserBySlackID(userId)
+	if err != nil {
+		req.Ack(map[string]string{"text": "❌ Error retrieving user information."})
+		return
+	}
+
+	if len(args) == 0 {
+		if user == nil {
+			req.Ack(map[string]string{"text": "ℹ️ You don't have a profile yet. Pick your team with `/profile team [team]` or check out a truck to get started."})
+			return
+		}
+		quals, err := models.GetQualifications(userId)
+		if err != nil {
+			log.Printf("Failed to load qualifications of %s: %v", userId, err)
+		}
+		req.Ack(map[string]string{"text": h.profileText(user, quals)})
+
+		// users.info is too slow to wait for before acking; the refreshed
+		// names show next time.
+		if _, err := syncSlackProfile(client, userId, actor); err != nil {
+			log.Printf("Failed to refresh Slack profile of %s: %v", userId, err)
+		}
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "team":
+		if len(args) != 2 || !models.IsValidTeam(strings.ToLower(args[1])) {
+			req.Ack(map[string]string{"text": fmt.Sprintf("⚠️ Pick one of: %s.", strings.Join(models.ValidTeams, ", "))})
+			return
+		}
+		team := strings.ToLower(args[1])
+		if user == nil {
+			if _, err := models.CreateUser(userId, userName, team, actor); err != nil {
+				log.Printf("Failed to create user %s (%s) with team %s: %v", userName, userId, team, err)
+				req.Ack(map[string]string{"text": "❌ Error creating user profile. Please try again."})
+				return
+			}
+		} else {
+			user.Team = team
+			if err := models.UpdateUser(*user, actor); err != nil {
+				log.Printf("Failed to change team of %s: %v", userId, err)
+				req.Ack(map[string]string{"text": h.errorMessage(err, "")})
+				return
+			}
+		}
+		log.Printf("User %s set their team to %s", userId, team)
+		req.Ack(map[string]string{"text": fmt.Sprintf("✅ You're now on the %s team.", teamLabel(team))})
+
+	case "phone":
+		if len(args) < 2 {
+			req.Ack(map[string]string{"text": profileUsage})
+			return
+		}
+		if user == nil {
+			req.Ack(map[string]string{"text": "⚠️ Pick your team with `/profile team [team]` first."})
+			return
+		}
+		user.Phone = strings.Join(args[1:], " ")
+		if strings.EqualFold(user.Phone, "none") {
+			user.Phone = ""
+		}
+		if err := models.UpdateUser(*user, actor); err != nil {
+			log.Printf("Failed to change phone of %s: %v", userId, err)
+			req.Ack(map[string]string{"text": h.errorMessage(err, "")})
+			return
+		}
+		if user.Phone == "" {
+			req.Ack(map[string]string{"text": "✅ Removed your phone number."})
+			return
+		}
+		req.Ack(map[string]string{"text": fmt.Sprintf("✅ Your phone number is now %s.", user.Phone)})
+
+	case "license", "cert":
+		kind, class := models.QualificationLicense, ""
+		want := 2
+		if strings.EqualFold(args[0], "cert") {
+			kind, want = models.QualificationCertification, 3
+		}
+		if len(args) != want {
+			req.Ack(map[string]string{"text": profileUsage})
+			return
+		}
+		if kind == models.QualificationCertification {
+			class = args[1]
+		}
+		expires, err := h.parseExpiryDate(args[len(args)-1])
+		if err != nil {
+			req.Ack(map[string]string{"text": "⚠️ Invalid expiry date. Use YYYY-MM-DD, e.g. `2027-05-01`."})
+			return
+		}
+		q, err := models.RecordQualification(userId, kind, class, expires, actor)
+		if err != nil {
+			log.Printf("Failed to record %s for %s: %v", kind, userId, err)
+			req.Ack(map[string]string{"text": h.errorMessage(err, "")})
+			return
+		}
+		log.Printf("User %s recorded their %s expiring %s", userId, q.Label(), args[len(args)-1])
+		req.Ack(map[string]string{"text": fmt.Sprintf("✅ Recorded your %s, expiring %s.", q.Label(), expires.Format("Jan 2, 2006"))})
+
+	default:
+		req.Ack(map[string]string{"text": profileUsage})
+	}
+}
+
+// profileText lays out a user's profile and driver qualifications.
+func (h *Handler) profileText(user *models.User, quals []models.Qualification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "👤 *%s*", user.Username)
+	if user.DisplayName != "" && user.DisplayName != user.Username {
+		fmt.Fprintf(&b, " (%s)", user.DisplayName)
+	}
+	fmt.Fprintf(&b, "\n• Team: %s", teamLabel(user.Team))
+	fmt.Fprintf(&b, "\n• Role: %s", strings.ReplaceAll(string(user.Role), "_", " "))
+	phone := "_not set_"
+	if user.Phone != "" {
+		phone = user.Phone
+	}
+	fmt.Fprintf(&b, "\n• Phone: %s", phone)
+
+	loc := h.cal.Location()
+	hasLicense := false
+	for _, q := range quals {
+		status := "expires"
+		if !q.ExpiresAt.After(time.Now()) {
+			status = "⚠️ expired"
+		}
+		fmt.Fprintf(&b, "\n• %s: %s %s", cases.Title(language.English).String(q.Label()), status, q.ExpiresAt.In(loc).Format("Jan 2, 2006"))
+		hasLicense = hasLicense || q.Kind == models.QualificationLicense
+	}
+	if !hasLicense {
+		b.WriteString("\n• Driver's license: _not on file_")
+	}
+	b.WriteString("\n_When you renew, record the new expiry date with `/profile license YYYY-MM-DD` or `/profile cert [class] YYYY-MM-DD`._")
+	return b.String()
+}
+
+func teamLabel(team string) string {
+	return cases.Title(language.English).String(strings.ReplaceAll(team, "_", " "))
+}
+
+// syncSlackProfile refreshes a user's name and account status from Slack's
+// users.info. deactivated is true if this is when the deactivation was noticed.
+func syncSlackProfile(client *socketmode.Client, slackUserID string, actor models.Actor) (deactivated bool, err error) {
+	info, err := client.GetUserInfo(slackUserID)
+	if err != nil {
+		return false, fmt.Errorf("users.info for %s: %w", slackUserID, err)
+	}
+	return models.SyncSlackProfile(slackUserID, models.SlackProfile{
+		RealName:    info.RealName,
+		DisplayName: info.Profile.DisplayName,
+		Deleted:     info.Deleted,
+	}, time.Now(), actor)
+}
+
+// RunProfileSync periodically refreshes every user's profile from Slack and
+// flags the open checkouts of anyone whose account was deactivated. It
+// blocks, so run it in a goroutine.
+func (h *Handler) RunProfileSync(client *socketmode.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	actor := models.Actor{SlackUserID: "scheduler", Source: models.SourceScheduler}
+	messenger := NewSocketMessenger(client)
+	for ; true; <-ticker.C {
+		users, err := models.GetAllUsers()
+		if err != nil {
+			log.Printf("Failed to load users for profile sync: %v", err)
+			continue
+		}
+		for _, user := range users {
+			deactivated, err := syncSlackProfile(client, user.SlackUserID, actor)
+			if err != nil {
+				log.Printf("Failed to sync profile of %s: %v", user.SlackUserID, err)
+				continue
+			}
+			if deactivated {
+				h.flagDeactivatedUser(messenger, user)
+			}
+		}
+	}
+}
+
+// flagDeactivatedUser tells the announce channel about checkouts still held
+// by someone whose Slack account was deactivated: in-progress ones for an
+// admin to release, and upcoming ones in a menu to cancel them from.
+func (h *Handler) flagDeactivatedUser(client Messenger, user models.User) {
+	log.Printf("Slack account of %s (%s) was deactivated", user.Username, user.SlackUserID)
+	now := time.Now()
+	open, err := models.GetOpenCheckoutsByUserID(user.SlackUserID, now)
+	if err != nil {
+		log.Printf("Failed to load open checkouts of %s: %v", user.SlackUserID, err)
+		return
+	}
+	if len(open) == 0 {
+		return
+	}
+
+	var inProgress []string
+	var upcoming []models.Checkout
+	for _, c := range open {
+		if c.StartDate.After(now) {
+			upcoming = append(upcoming, c)
+			continue
+		}
+		truckName := c.TruckID.String()
+		if truck, err := models.GetTruckByID(c.TruckID); err == nil {
+			truckName = truck.Name
+		}
+		inProgress = append(inProgress, fmt.Sprintf("• *%s* (%s): a fleet admin should `/release %s`", truckName, h.formatDateRange(c.StartDate, c.EndDate), truckName))
+	}
+
+	text := fmt.Sprintf("⚠️ %s's Slack account was deactivated, but they still hold %d checkout(s).", user.Username, len(open))
+	if len(inProgress) > 0 {
+		text += "\n" + strings.Join(inProgress, "\n")
+	}
+	blocks := []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil)}
+	if len(upcoming) > 0 {
+		blocks = append(blocks,
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", "Upcoming reservations a fleet admin can cancel:", false, false), nil, nil),
+			h.cancelMenu(upcoming),
+		)
+	}
+	if _, _, err := client.PostMessage(h.cfg.AnnounceChannel, slack.MsgOptionText(text, false), slack.MsgOptionBlocks(blocks...)); err != nil {
+		log.Printf("Failed to post message to #%s: %v", h.cfg.AnnounceChannel, err)
+	}
+}
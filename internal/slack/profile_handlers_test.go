@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"truck-checkout/internal/models"
+
+	"github.com/slack-go/slack"
+)
+
+func TestProfile_SelfServiceQualifications(t *testing.T) {
+	models.ResetTestDB(t)
+	h := newTestHandler()
+	if _, err := models.GetOrCreateUserBySlackID("U123", "jo", "beltline", testActor); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	f := newFakeSlack(t, h)
+	profile := func(text string) string {
+		return string(f.SlashCommand(slack.SlashCommand{Command: "/profile", Text: text, UserID: "U123", UserName: "jo"}))
+	}
+
+	if ack := profile("cert dump 2027-05-01"); !strings.Contains(ack, "Recorded your dump certification") {
+		t.Errorf("unexpected reply recording a certification: %s", ack)
+	}
+	if ack := profile("license 2027-06-01"); !strings.Contains(ack, "Recorded your driver's license") {
+		t.Errorf("unexpected reply recording a license: %s", ack)
+	}
+	if ack := profile("cert 2027-05-01"); !strings.Contains(ack, "/profile cert [class] YYYY-MM-DD") {
+		t.Errorf("expected the usage for a certification without a class, got %s", ack)
+	}
+
+	quals, err := models.GetQualifications("U123")
+	if err != nil {
+		t.Fatalf("failed to load qualifications: %v", err)
+	}
+	if len(quals) != 2 {
+		t.Fatalf("expected a license and a certification, got %+v", quals)
+	}
+	// The license is good for the whole of its expiry day.
+	lastDay := time.Date(2027, 6, 1, 17, 0, 0, 0, h.cal.Location())
+	if err := models.CheckDriverQualified("U123", nil, lastDay); err != nil {
+		t.Errorf("expected the license to cover its expiry day: %v", err)
+	}
+	if err := models.CheckDriverQualified("U123", nil, lastDay.AddDate(0, 0, 1)); err == nil {
+		t.Error("expected the license to have expired the next day")
+	}
+	for _, q := range quals {
+		events, err := models.GetAuditEventsBetween(q.RecordedAt.AddDate(0, 0, -1), q.RecordedAt.AddDate(0, 0, 1))
+		if err != nil {
+			t.Fatalf("failed to load audit events: %v", err)
+		}
+		recorded := false
+		for _, e := range events {
+			recorded = recorded || (e.EntityID == q.ID.String() && e.ActorID == "U123")
+		}
+		if !recorded {
+			t.Errorf("expected the user to be recorded as the actor, got %+v", events)
+		}
+	}
+
+	// The profile is answered from what's stored; users.info comes after.
+	ack := profile("")
+	if !strings.Contains(ack, "Dump Certification") || !strings.Contains(ack, "Driver's License") {
+		t.Errorf("expected both qualifications in the profile, got %s", ack)
+	}
+	f.WaitForCall("users.info", nil)
+}
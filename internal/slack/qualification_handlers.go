@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"truck-checkout/internal/models"
+
+	"github.com/slack-go/slack"
+)
+
+// qualificationReminderLead is how long before a license or certification
+// expires that its holder is reminded to renew it.
+const qualificationReminderLead = 30 * 24 * time.Hour
+
+// RunQualificationReminders periodically DMs users whose license or
+// certification expires within the next 30 days. Each expiry is reminded
+// about once. It blocks, so run it in a goroutine.
+func (h *Handler) RunQualificationReminders(client Messenger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		now := time.Now()
+		due, err := models.DueQualificationReminders(now, qualificationReminderLead)
+		if err != nil {
+			log.Printf("Failed to look up expiring qualifications: %v", err)
+			continue
+		}
+		for _, q := range due {
+			text := fmt.Sprintf("🪪 Your %s on file expires %s. Please renew it and send the new expiry date to a fleet admin, or you won't be able to check out trucks after then.",
+				q.Label(), q.ExpiresAt.In(h.cal.Location()).Format("Jan 2, 2006"))
+			if err := client.DirectMessage(q.SlackUserID, slack.MsgOptionText(text, false)); err != nil {
+				log.Printf("Failed to remind %s about their %s: %v", q.SlackUserID, q.Label(), err)
+				continue
+			}
+			if err := models.MarkQualificationReminded(q.ID, now); err != nil {
+				log.Printf("Failed to mark %s reminder for %s as sent: %v", q.Label(), q.SlackUserID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"truck-checkout/internal/calendar"
+	"truck-checkout/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/slack-go/slack"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// maxRecurringWeeks caps how far apart a series' first and last dates may
+// be, so booking one stays quick enough to answer before Slack times out.
+const maxRecurringWeeks = 26
+
+const recurringUsage = "ℹ️ Use `/recurring [truck-name] [weekdays] [first-date] [last-date] [days]`, e.g. `/recurring Watson tue,thu 2026-04-07 2026-06-30`."
+
+// HandleRecurring books a truck on the same weekdays every week between two
+// dates, e.g. `/recurring Watson tue,thu 2026-04-07 2026-06-30 1`. Each
+// occurrence lasts the given number of business days (default 1). Free
+// occurrences are booked and the ones that collide are listed. override is
+// set when a fleet admin adds `override` to book despite the policy.
+func (h *Handler) HandleRecurring(client Messenger, req Responder, args []string, override bool, userId string, userName string) {
+	if len(args) < 4 || len(args) > 5 {
+		req.Ack(map[string]string{"text": recurringUsage})
+		return
+	}
+
+	truckName := cases.Title(language.English).String(strings.ToLower(args[0]))
+	weekdays, err := calendar.ParseWeekdays(args[1])
+	if err != nil {
+		req.Ack(map[string]string{"text": "⚠️ Invalid weekdays. Use a list like `tue,thu`."})
+		return
+	}
+	loc := h.cal.Location()
+	first, err1 := time.ParseInLocation("2006-01-02", args[2], loc)
+	last, err2 := time.ParseInLocation("2006-01-02", args[3], loc)
+	if err1 != nil || err2 != nil {
+		req.Ack(map[string]string{"text": "⚠️ Invalid dates. Use YYYY-MM-DD, e.g. `2026-04-07`."})
+		return
+	}
+	if last.Before(first) {
+		req.Ack(map[string]string{"text": "⚠️ The last date must not be before the first."})
+		return
+	}
+	if last.After(first.AddDate(0, 0, 7*maxRecurringWeeks)) {
+		req.Ack(map[string]string{"text": fmt.Sprintf("⚠️ A recurring checkout can span at most %d weeks.", maxRecurringWeeks)})
+		return
+	}
+	businessDays := 1
+	if len(args) == 5 {
+		businessDays, err = strconv.Atoi(args[4])
+		if err != nil || businessDays < 1 {
+			req.Ack(map[string]string{"text": "⚠️ Invalid number of days. Use a positive integer."})
+			return
+		}
+	}
+
+	user, err := h.currentUser(userId)
+	if err != nil || user == nil || user.Team == "" {
+		req.Ack(map[string]string{
+			"text": fmt.Sprintf("⚠️ Please run `/checkout %s` once to set up your profile first.", truckName),
+		})
+		return
+	}
+
+	if override && !user.IsFleetAdmin() {
+		log.Printf("Warning: User %s tried to override the checkout policy without being a fleet admin", userId)
+		req.Ack(map[string]string{"text": h.errorMessage(models.ErrPermissionDenied, truckName)})
+		return
+	}
+
+	truck, err := models.GetTruckByName(truckName)
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+	if err := models.CheckTeamAccess(truck, user.Team); err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+
+	now := h.cal.Now()
+	actor := models.Actor{SlackUserID: userId, Source: models.SourceSlashCommand}
+
+	// Occurrences the user isn't qualified for are reported alongside the
+	// booking conflicts. The checkout policy is checked as each occurrence is
+	// booked, so the earlier ones count towards the quota.
+	var occurrences []models.Occurrence
+	var conflicts []models.SeriesConflict
+	for _, w := range h.cal.RecurringWindows(first, last, weekdays, businessDays) {
+		if !w.Start.After(now) {
+			continue
+		}
+		o := models.Occurrence{Start: w.Start, End: w.End}
+		if err := models.CheckDriverQualified(userId, truck, w.End); err != nil {
+			conflicts = append(conflicts, models.SeriesConflict{Occurrence: o, Err: err})
+			continue
+		}
+		occurrences = append(occurrences, o)
+	}
+	if len(occurrences) == 0 && len(conflicts) == 0 {
+		req.Ack(map[string]string{"text": "ℹ️ Those dates don't include any upcoming business days on those weekdays."})
+		return
+	}
+
+	series := models.CheckoutSeries{
+		ID:           uuid.New(),
+		TruckID:      truck.ID,
+		UserID:       userId,
+		UserName:     userName,
+		TeamName:     user.Team,
+		Weekdays:     calendar.FormatWeekdays(weekdays),
+		FirstDate:    first,
+		LastDate:     last,
+		BusinessDays: businessDays,
+	}
+	purpose := fmt.Sprintf("Recurring checkout every %s via slash command (%d business days)", series.Weekdays, businessDays)
+
+	check := policyCheck(h.cfg.CheckoutPolicy(), models.CheckoutRequest{
+		TruckName:    truck.Name,
+		UserID:       userId,
+		TeamName:     user.Team,
+		BusinessDays: businessDays,
+		Now:          now,
+	}, user, override)
+
+	var booked []models.Checkout
+	if len(occurrences) > 0 {
+		var taken []models.SeriesConflict
+		booked, taken, err = models.CreateCheckoutSeries(series, occurrences, purpose, check, actor)
+		conflicts = append(conflicts, taken...)
+		// When none could be booked the conflicts explain why, one by one.
+		if err != nil && len(taken) == 0 {
+			log.Printf("Failed to create recurring checkout of %s: %v", truckName, err)
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+	}
+
+	logOverrides(check, userName)
+
+	// A series can run to dozens of occurrences, too many calendar calls to
+	// make before the command has to be acked.
+	go func() {
+		for i := range booked {
+			h.addCalendarEvent(truck, &booked[i])
+		}
+	}()
+
+	when := h.seriesSummary(series)
+	if len(booked) > 0 {
+		message := fmt.Sprintf("🔁 *%s* reserved truck *%s* %s (%d occurrences)", userName, truck.Name, when, len(booked))
+		if _, _, err := client.PostMessage(h.cfg.AnnounceChannel, slack.MsgOptionText(message, false)); err != nil {
+			log.Printf("Failed to post message to #%s: %v", h.cfg.AnnounceChannel, err)
+		}
+	}
+
+	msg := fmt.Sprintf("✅ Booked %d of %d occurrences of `%s` %s.", len(booked), len(booked)+len(conflicts), truck.Name, when)
+	if len(booked) == 0 {
+		msg = fmt.Sprintf("🚫 None of the occurrences of `%s` %s could be booked.", truck.Name, when)
+	}
+	if len(conflicts) > 0 {
+		sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Start.Before(conflicts[j].Start) })
+		msg += "\n⚠️ Not booked:"
+		overridable := false
+		for _, c := range conflicts {
+			msg += fmt.Sprintf("\n• %s — %s", h.formatDateRange(c.Start, c.End), conflictReason(c.Err))
+			overridable = overridable || canOverride(c.Err, user)
+		}
+		if overridable {
+			msg += "\nℹ️ Add `override` to the end of the command to book the ones the policy stopped anyway."
+		}
+	}
+	if len(booked) > 0 {
+		msg += "\nUse `/cancel` to cancel one occurrence or the rest of the series."
+	}
+	req.Ack(map[string]string{"text": msg})
+}
+
+// seriesSummary describes when a series recurs, e.g. "every Tue, Thu from
+// Apr 7 to Jun 30".
+func (h *Handler) seriesSummary(series models.CheckoutSeries) string {
+	loc := h.cal.Location()
+	days := cases.Title(language.English).String(strings.ReplaceAll(series.Weekdays, ",", ", "))
+	return fmt.Sprintf("every %s from %s to %s", days, series.FirstDate.In(loc).Format("Jan 2"), series.LastDate.In(loc).Format("Jan 2, 2006"))
+}
+
+// conflictReason is the short explanation listed next to an occurrence that
+// could not be booked.
+func conflictReason(err error) string {
+	var policy *models.PolicyError
+	switch {
+	case errors.As(err, &policy):
+		reasons := make([]string, len(policy.Violations))
+		for i, v := range policy.Violations {
+			reasons[i] = v.Message
+		}
+		return strings.Join(reasons, " ")
+	case errors.Is(err, models.ErrTruckUnavailable), errors.Is(err, models.ErrCheckoutOverlap):
+		return "already booked"
+	case errors.Is(err, models.ErrInMaintenance):
+		return "out for maintenance"
+	default:
+		return err.Error()
+	}
+}
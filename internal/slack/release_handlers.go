@@ -7,56 +7,107 @@ import (
 	"log"
 	"truck-checkout/internal/models"
 
-	"github.com/slack-go/slack"
-	"github.com/slack-go/slack/socketmode"
+	"github.com/google/uuid"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
-// releaseas a single vehicle based on its name
-func HandleReleaseTruck(client *socketmode.Client, req *socketmode.Request, truckName string, userId string, userName string) {
+// releaseas a single vehicle based on its name. returned means the user
+// confirmed putting the keys and fuel card back in the lockbox.
+func (h *Handler) HandleReleaseTruck(client Messenger, req Responder, truckName string, userId string, userName string, returned bool) {
 	truckName = cases.Title(language.English).String(strings.ToLower(truckName))
 
+	actor := models.Actor{SlackUserID: userId, Source: models.SourceSlashCommand}
+
 	// Find the truck by name
 	truck, err := models.GetTruckByName(truckName)
 	if err != nil {
-		client.Ack(*req, map[string]string{"text": fmt.Sprintf("❌ Truck `%s` not found.", truckName)})
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
 		return
 	}
 
-	if !truck.IsCheckedOut {
-		client.Ack(*req, map[string]string{"text": fmt.Sprintf("ℹ️ Truck `%s` is not currently checked out.", truckName)})
+	// The checkouts table decides whether the truck is out, never the
+	// is_checked_out flag, which can lag behind it.
+	checkout, err := models.GetReleasableCheckoutByTruckID(truck.ID)
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
 		return
 	}
 
-	checkout, err := models.GetActiveCheckoutByTruckID(truck.ID)
+	user, err := h.currentUser(userId)
 	if err != nil {
-		log.Printf("Warning: Could not get checkout info for truck %s: %v", truckName, err)
-		// Continue with release even if we can't get checkout info
+		req.Ack(map[string]string{"text": "❌ Error retrieving user information."})
+		return
 	}
 
-	err = models.ReleaseTruckFromCheckout(truck.ID, userId)
+	override, err := models.AuthorizeCheckoutChange(user, checkout)
 	if err != nil {
-		log.Printf("Failed to release truck %s: %v", truckName, err)
-		client.Ack(*req, map[string]string{"text": "❌ Failed to release the truck."})
+		log.Printf("Warning: User %s tried to release truck %s held by %s", userId, truckName, checkout.UserID)
+		req.Ack(map[string]string{
+			"text": fmt.Sprintf("🚫 Truck `%s` is checked out by %s. Only they, their team lead or a fleet admin can release it.", truckName, checkout.UserName),
+		})
 		return
 	}
+	if override {
+		log.Printf("Override: %s (%s) released truck %s held by %s", userName, user.Role, truckName, checkout.UserName)
+		auditOverride(actor, "checkout", checkout.ID.String(), &truck.ID,
+			fmt.Sprintf("%s released a checkout held by %s", user.Role, checkout.UserID))
+	}
 
-	channelID := "vehicleupdates"
-	var message string
-	if checkout != nil {
-		message = fmt.Sprintf("🚛 *%s* released truck *%s* (previously checked out by %s)", userName, truckName, checkout.UserName)
-	} else {
-		message = fmt.Sprintf("🚛 *%s* released truck *%s*", userName, truckName)
+	text, outstanding, err := h.releaseTruck(client, truck, checkout, userName, actor, returned)
+	if err != nil {
+		req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+		return
+	}
+	if len(outstanding) > 0 {
+		req.Ack(custodyReminder(text, truck, outstanding))
+		return
 	}
+	req.Ack(map[string]string{"text": text})
+}
 
-	_, _, err = client.PostMessage(channelID, slack.MsgOptionText(message, false))
+// releaseTruck releases the truck's active checkout, which the caller has
+// already authorized, announces it and offers the truck to the waitlist. It
+// returns the reply text and, unless returned is set, any keys or fuel card
+// still recorded as out.
+func (h *Handler) releaseTruck(client Messenger, truck *models.Truck, checkout *models.Checkout, userName string, actor models.Actor, returned bool) (string, []models.CustodyEvent, error) {
+	if err := models.ReleaseTruckFromCheckout(truck.ID, actor); err != nil {
+		log.Printf("Failed to release truck %s: %v", truck.Name, err)
+		return "", nil, err
+	}
+
+	h.announceRelease(client, truck, checkout, userName)
+	log.Printf("Truck %s released by %s", truck.Name, userName)
+
+	// The truck is free for the rest of the checkout it was out on.
+	now := h.cal.Now()
+	released := *checkout
+	released.ReleasedAt = &now
+	h.moveCalendarEvent(truck, &released)
+	freedUntil := checkout.EndDate
+	if freedUntil.Before(now) {
+		// The checkout ran past its end; the truck is free from now on.
+		freedUntil = now
+	}
+	h.offerFreedTruck(client, truck, now, freedUntil)
+
+	text := fmt.Sprintf("✅ Truck `%s` has been released successfully!", truck.Name)
+	if returned {
+		if err := returnCustody(truck.ID, &checkout.ID, actor); err != nil {
+			log.Printf("Failed to record custody return for %s: %v", truck.Name, err)
+		}
+		return text, nil, nil
+	}
+	outstanding, err := models.OutstandingCustody(truck.ID)
 	if err != nil {
-		log.Printf("Failed to post message to #vehicleupdates: %v", err)
+		log.Printf("Failed to check custody for %s: %v", truck.Name, err)
 	}
-	log.Printf("Truck %s released by %s", truckName, userName)
+	return text, outstanding, nil
+}
 
-	client.Ack(*req, map[string]string{
-		"text": fmt.Sprintf("✅ Truck `%s` has been released successfully!", truckName),
-	})
+// auditOverride records that someone acted on a checkout that isn't theirs.
+func auditOverride(actor models.Actor, entityType, entityID string, truckID *uuid.UUID, reason string) {
+	if err := models.RecordOverride(actor, entityType, entityID, truckID, map[string]string{"reason": reason}); err != nil {
+		log.Printf("Failed to audit override by %s: %v", actor.SlackUserID, err)
+	}
 }
@@ -2,14 +2,42 @@ package handlers
 
 import (
 	"log"
-	"strconv"
 	"strings"
+	"time"
+
+	"truck-checkout/internal/calendar"
+	"truck-checkout/internal/config"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/socketmode"
+	gcal "google.golang.org/api/calendar/v3"
 )
 
-func HandleSlashCommand(client *socketmode.Client, evt socketmode.Event) {
+// Handler handles Slack commands and interactions with the settings main
+// loaded at startup.
+type Handler struct {
+	// cfg holds the announce channel, checkout limit and other settings.
+	cfg *config.Config
+	// cal decides which days count as business days and the working hours
+	// on each.
+	cal *calendar.BusinessCalendar
+	// calendarService keeps the trucks' Google calendars in step. Calendars
+	// are left alone when it is nil.
+	calendarService *gcal.Service
+}
+
+// NewHandler returns a Handler using cfg and cal. srv may be nil to leave
+// the trucks' Google calendars alone.
+func NewHandler(cfg *config.Config, cal *calendar.BusinessCalendar, srv *gcal.Service) *Handler {
+	return &Handler{cfg: cfg, cal: cal, calendarService: srv}
+}
+
+// HandleSlashCommand routes a slash command to its handler. Handlers talk to
+// Slack through a Messenger, except /profile and /audit, which also need
+// users.info and file uploads from the client itself.
+func (h *Handler) HandleSlashCommand(socketClient *socketmode.Client, evt socketmode.Event) {
+	client := NewSocketMessenger(socketClient)
+	req := NewSocketResponder(socketClient, evt.Request)
 	cmd, ok := evt.Data.(slack.SlashCommand)
 	if !ok {
 		log.Printf("Ignored unknown command event")
@@ -20,36 +48,36 @@ func HandleSlashCommand(client *socketmode.Client, evt socketmode.Event) {
 
 	switch cmd.Command {
 	case "/checkout":
-		args := strings.Fields(cmd.Text)
+		fields, override := cutOverride(strings.Fields(cmd.Text))
+		args, assetNames, ok := splitWith(fields)
+		if !ok {
+			req.Ack(map[string]string{
+				"text": "⚠️ Name the trailers or equipment after `with`, e.g. `/checkout Tulip with Chipper,Trailer`",
+			})
+			return
+		}
 		switch len(args) {
 		case 0:
-			client.Ack(*evt.Request, map[string]string{
-				"text": "ℹ️ Use `/checkout [truck-name]` or `/checkout [truck-name] [days]` to check out a truck.",
+			req.Ack(map[string]string{
+				"text": "ℹ️ Use `/checkout [truck-name]` or `/checkout [truck-name] [days]` to check out a truck, `/checkout [truck-name] 9am-12pm` for part of a day, or `/checkout any [days]` for whichever truck is free. Add `with Chipper,Trailer` to book equipment too.",
 			})
 			return
 		case 1:
-			HandleCheckout(client, evt.Request, args[0], 1, cmd.UserID, cmd.UserName, cmd.TriggerID, cmd.ChannelID)
+			h.HandleCheckout(client, req, args[0], wholeDays(1), assetNames, override, cmd.UserID, cmd.UserName, cmd.TriggerID, cmd.ChannelID)
 			return
 		case 2:
-			days, err := strconv.Atoi(args[1])
-			if err != nil || days < 1 {
-				log.Printf("Warning: User %s tried to check out for an invalid number of days: %s", cmd.UserID, args[1])
-				client.Ack(*evt.Request, map[string]string{
-					"text": "⚠️ Invalid number of days. Use a positive integer like `/checkout Tulip 4`",
-				})
-				return
-			}
-			if days > 6 { // ? Assuming max checkout period is 6 days
-				log.Printf("Warning: User %s tried to check out for %d days, which exceeds the limit.", cmd.UserID, days)
-				client.Ack(*evt.Request, map[string]string{
-					"text": "⚠️ Maximum checkout period is 6 days.",
+			length, err := parseCheckoutLength(args[1])
+			if err != nil {
+				log.Printf("Warning: User %s tried to check out for an invalid length: %s", cmd.UserID, args[1])
+				req.Ack(map[string]string{
+					"text": "⚠️ Invalid length. Use a number of business days like `/checkout Tulip 4` or a time range like `/checkout Tulip 9am-12pm`",
 				})
 				return
 			}
-			HandleCheckout(client, evt.Request, args[0], days, cmd.UserID, cmd.UserName, cmd.TriggerID, cmd.ChannelID)
+			h.HandleCheckout(client, req, args[0], length, assetNames, override, cmd.UserID, cmd.UserName, cmd.TriggerID, cmd.ChannelID)
 			return
 		default:
-			client.Ack(*evt.Request, map[string]string{
+			req.Ack(map[string]string{
 				"text": "⚠️ Too many arguments. Try `/checkout Tulip`",
 			})
 			return
@@ -59,58 +87,127 @@ func HandleSlashCommand(client *socketmode.Client, evt socketmode.Event) {
 		if len(args) > 0 {
 			switch args[0] {
 			case "available":
-				HandleTrucksAvailable(client, evt.Request)
+				h.HandleTrucksAvailable(client, req)
 				return
 			case "unavailable":
-				HandleTrucksCheckedOut(client, evt.Request)
+				h.HandleTrucksCheckedOut(client, req)
+				return
+			case "week":
+				h.HandleTrucksWeek(client, req, h.cal.Now())
+				return
+			}
+			if day, err := time.ParseInLocation(calendar.DateFormat, args[0], h.cal.Location()); err == nil {
+				h.HandleTrucksWeek(client, req, day)
 				return
 			}
 		}
 		// fallback
-		client.Ack(*evt.Request, map[string]string{
-			"text": "ℹ️ Try `/trucks available` to see today's available trucks.",
+		req.Ack(map[string]string{
+			"text": "ℹ️ Try `/trucks available` to see which trucks are free right now, or `/trucks week` or `/trucks 2026-11-03` for a week at a glance.",
 		})
 	case "/release":
 		args := strings.Fields(cmd.Text)
 		switch len(args) {
 		case 0:
 			// Later: open Block Kit modal for truck selection
-			client.Ack(*evt.Request, map[string]string{
-				"text": "ℹ️ Use `/release [truck-name]` to release a truck.",
+			req.Ack(map[string]string{
+				"text": "ℹ️ Use `/release [truck-name]` to release a truck, or `/release [truck-name] returned` once the keys and fuel card are back in the lockbox.",
 			})
 			return
 		case 1:
-			HandleReleaseTruck(client, evt.Request, args[0], cmd.UserID, cmd.UserName)
+			h.HandleReleaseTruck(client, req, args[0], cmd.UserID, cmd.UserName, false)
+			return
+		case 2:
+			if strings.EqualFold(args[1], "returned") {
+				h.HandleReleaseTruck(client, req, args[0], cmd.UserID, cmd.UserName, true)
+				return
+			}
+			req.Ack(map[string]string{
+				"text": "⚠️ Use `/release Tulip returned` to confirm the keys and fuel card are back in the lockbox.",
+			})
 			return
 		default:
-			client.Ack(*evt.Request, map[string]string{
+			req.Ack(map[string]string{
 				"text": "⚠️ Too many arguments. Try `/release Tulip`",
 			})
 			return
 		}
+	case "/request":
+		h.HandleRequest(client, req, strings.Fields(cmd.Text), cmd.UserID, cmd.UserName)
+	case "/profile":
+		h.HandleProfile(socketClient, req, strings.Fields(cmd.Text), cmd.UserID, cmd.UserName)
+	case "/whereis":
+		h.HandleWhereIs(client, req, strings.Fields(cmd.Text))
+	case "/recurring":
+		args, override := cutOverride(strings.Fields(cmd.Text))
+		h.HandleRecurring(client, req, args, override, cmd.UserID, cmd.UserName)
+	case "/cancel":
+		h.HandleCancel(client, req, strings.Fields(cmd.Text), cmd.UserID, cmd.UserName)
+	case "/waitlist":
+		h.HandleWaitlist(client, req, strings.Fields(cmd.Text), cmd.UserID, cmd.UserName)
+	case "/odometer":
+		h.HandleOdometer(client, req, strings.Fields(cmd.Text), cmd.UserID)
+	case "/fuel":
+		h.HandleFuelPurchase(client, req, strings.Fields(cmd.Text), cmd.UserID)
+	case "/fuelreport":
+		h.HandleFuelReport(client, req, strings.Fields(cmd.Text))
+	case "/audit":
+		h.HandleAudit(socketClient, req, strings.Fields(cmd.Text), cmd.UserID)
+	case "/fleet":
+		h.HandleFleet(client, req, strings.Fields(cmd.Text), cmd.UserID)
 	case "/swap":
-		client.Ack(*evt.Request, map[string]string{"text": "🔀 Handled /swap!"})
+		req.Ack(map[string]string{"text": "🔀 Handled /swap!"})
 	default:
-		client.Ack(*evt.Request, map[string]string{"text": "Unknown command"})
+		req.Ack(map[string]string{"text": "Unknown command"})
 	}
 }
 
-func HandleInteractive(client *socketmode.Client, evt socketmode.Event) {
+func (h *Handler) HandleInteractive(socketClient *socketmode.Client, evt socketmode.Event) {
+	client := NewSocketMessenger(socketClient)
+	req := NewSocketResponder(socketClient, evt.Request)
 	callback, ok := evt.Data.(slack.InteractionCallback)
 	if !ok {
 		log.Printf("Error: expected InteractionCallback")
-		client.Ack(*evt.Request)
+		req.Ack()
 		return
 	}
 
 	switch callback.Type {
 	case slack.InteractionTypeViewSubmission:
 		if callback.View.CallbackID == "team_selection" {
-			handleTeamSelectionModal(client, evt.Request, &callback)
+			h.handleTeamSelectionModal(client, req, &callback)
 		} 
 	case slack.InteractionTypeBlockActions:
-		handleButtonActions(client, evt.Request, &callback)
+		h.handleButtonActions(client, req, &callback)
 	default:
-		client.Ack(*evt.Request)
+		req.Ack()
+	}
+}
+
+// splitWith separates the asset names after a `with` keyword, e.g.
+// `Tulip 2 with Chipper, Trailer`, from the rest of the arguments. ok is
+// false if `with` is given without any names.
+func splitWith(args []string) (rest []string, assetNames []string, ok bool) {
+	for i, arg := range args {
+		if !strings.EqualFold(arg, "with") {
+			continue
+		}
+		for _, name := range strings.Split(strings.Join(args[i+1:], ","), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				assetNames = append(assetNames, name)
+			}
+		}
+		return args[:i], assetNames, len(assetNames) > 0
+	}
+	return args, nil, true
+}
+
+// cutOverride strips a trailing `override` keyword, with which a fleet admin
+// books despite the overridable checkout policy rules, e.g.
+// `/checkout Tulip 8 override`.
+func cutOverride(args []string) (rest []string, override bool) {
+	if n := len(args); n > 0 && strings.EqualFold(args[n-1], "override") {
+		return args[:n-1], true
 	}
+	return args, false
 }
@@ -2,24 +2,27 @@ package handlers
 
 import (
 	"fmt"
+	"log"
+	"slices"
+	"strings"
 	"time"
 	"truck-checkout/internal/models"
 
-	"github.com/slack-go/slack/socketmode"
+	"github.com/google/uuid"
 )
 
-func HandleTrucksAvailable(client *socketmode.Client, req *socketmode.Request) {
-	trucks, err := models.GetTrucksByCheckoutStatus(time.Now(), false)
+func (h *Handler) HandleTrucksAvailable(client Messenger, req Responder) {
+	trucks, err := models.GetTrucksByCheckoutStatus(h.cal.Now(), false)
 	if err != nil {
-		client.Ack(*req, map[string]string{"text": "❌ Could not retrieve available trucks."})
+		req.Ack(map[string]string{"text": "❌ Could not retrieve available trucks."})
 		return
 	}
 	if len(trucks) == 0 {
-		client.Ack(*req, map[string]string{"text": "🚫 No trucks are currently available today."})
+		req.Ack(map[string]string{"text": "🚫 No trucks are available right now."})
 		return
 	}
 
-	msg := "🟢 *Available Trucks Today:*\n"
+	msg := "🟢 *Available Trucks Right Now:*\n"
 	for _, t := range trucks {
 		team := "unassigned"
 		if t.DefaultTeam != nil {
@@ -28,21 +31,21 @@ func HandleTrucksAvailable(client *socketmode.Client, req *socketmode.Request) {
 		msg += fmt.Sprintf("• %s (%s)\n", t.Name, team)
 	}
 
-	client.Ack(*req, map[string]string{"text": msg})
+	req.Ack(map[string]string{"text": msg})
 }
 
-func HandleTrucksCheckedOut(client *socketmode.Client, req *socketmode.Request) {
-	trucks, err := models.GetTrucksByCheckoutStatus(time.Now(), true)
+func (h *Handler) HandleTrucksCheckedOut(client Messenger, req Responder) {
+	trucks, err := models.GetTrucksByCheckoutStatus(h.cal.Now(), true)
 	if err != nil {
-		client.Ack(*req, map[string]string{"text": "❌ Could not retrieve unavailable trucks."})
+		req.Ack(map[string]string{"text": "❌ Could not retrieve unavailable trucks."})
 		return
 	}
 	if len(trucks) == 0 {
-		client.Ack(*req, map[string]string{"text": "✅ All trucks are currently available!"})
+		req.Ack(map[string]string{"text": "✅ All trucks are currently available!"})
 		return
 	}
 
-	msg := "🔴 *Checked Out Trucks Today:*\n"
+	msg := "🔴 *Checked Out Trucks Right Now:*\n"
 	for _, t := range trucks {
 		team := "unassigned"
 		if t.DefaultTeam != nil {
@@ -51,5 +54,149 @@ func HandleTrucksCheckedOut(client *socketmode.Client, req *socketmode.Request)
 		msg += fmt.Sprintf("• %s (%s)\n", t.Name, team)
 	}
 
-	client.Ack(*req, map[string]string{"text": msg})
+	req.Ack(map[string]string{"text": msg})
+}
+
+// weekCellWidth is the width of one day's column in the week grid.
+const weekCellWidth = 14
+
+// HandleTrucksWeek shows which trucks are free on each business day of the
+// week containing day, e.g. `/trucks week` or `/trucks 2026-11-03`.
+func (h *Handler) HandleTrucksWeek(client Messenger, req Responder, day time.Time) {
+	day = day.In(h.cal.Location())
+	monday := time.Date(day.Year(), day.Month(), day.Day()-(int(day.Weekday())+6)%7, 0, 0, 0, 0, day.Location())
+
+	var days []time.Time
+	for d := monday; d.Before(monday.AddDate(0, 0, 7)); d = d.AddDate(0, 0, 1) {
+		if h.cal.IsBusinessDay(d) {
+			days = append(days, d)
+		}
+	}
+	if len(days) == 0 {
+		req.Ack(map[string]string{"text": fmt.Sprintf("ℹ️ There are no business days in the week of %s.", monday.Format("Jan 2"))})
+		return
+	}
+
+	trucks, err := models.GetTrucks()
+	if err != nil {
+		req.Ack(map[string]string{"text": "❌ Could not retrieve trucks."})
+		return
+	}
+	checkouts, err := models.GetCheckoutsBetween(monday, monday.AddDate(0, 0, 7))
+	if err != nil {
+		req.Ack(map[string]string{"text": "❌ Could not retrieve checkouts."})
+		return
+	}
+	maintenance, err := models.GetMaintenanceBetween(monday, monday.AddDate(0, 0, 7))
+	if err != nil {
+		req.Ack(map[string]string{"text": "❌ Could not retrieve maintenance."})
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🗓️ *Trucks for the week of %s:*\n```\n%-10s", monday.Format("Jan 2"), "")
+	for _, d := range days {
+		fmt.Fprintf(&b, "%-*s", weekCellWidth, d.Format("Mon 1/2"))
+	}
+	b.WriteString("\n")
+	for _, truck := range trucks {
+		fmt.Fprintf(&b, "%-10s", truck.Name)
+		for _, d := range days {
+			fmt.Fprintf(&b, "%-*s", weekCellWidth, weekCell(truck.ID, checkouts, maintenance, h.cal.Opening(d), h.cal.Closing(d)))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("```\n_Cells show the team that has the truck, or maintenance while it's out of service; * means only part of the day._")
+
+	req.Ack(map[string]string{"text": b.String()})
+}
+
+// weekCell describes a truck's bookings between open and close: "free",
+// "maintenance" if it is out of service, or the teams that have it, marked
+// with * if they don't cover the whole day. A released checkout only counts
+// until its release.
+func weekCell(truckID uuid.UUID, checkouts []models.Checkout, maintenance []models.MaintenanceBlock, open, close time.Time) string {
+	inShop := time.Duration(0)
+	for _, m := range maintenance {
+		if m.TruckID == truckID && m.StartDate.Before(close) && m.EndDate.After(open) {
+			inShop += overlap(m.StartDate, m.EndDate, open, close)
+		}
+	}
+	if inShop > 0 {
+		if inShop < close.Sub(open) {
+			return "maintenance*"
+		}
+		return "maintenance"
+	}
+
+	var teams []string
+	booked := time.Duration(0)
+	for _, c := range checkouts {
+		if c.TruckID != truckID || !c.StartDate.Before(close) || !c.HeldUntil().After(open) {
+			continue
+		}
+		if !slices.Contains(teams, c.TeamName) {
+			teams = append(teams, c.TeamName)
+		}
+		booked += overlap(c.StartDate, c.HeldUntil(), open, close)
+	}
+	if len(teams) == 0 {
+		return "free"
+	}
+
+	cell := strings.Join(teams, "+")
+	if len(cell) > weekCellWidth-2 {
+		cell = cell[:weekCellWidth-3] + "…"
+	}
+	if booked < close.Sub(open) {
+		cell += "*"
+	}
+	return cell
+}
+
+// overlap is how much of [start, end) falls within [open, close).
+func overlap(start, end, open, close time.Time) time.Duration {
+	if start.Before(open) {
+		start = open
+	}
+	if end.After(close) {
+		end = close
+	}
+	return end.Sub(start)
+}
+
+// ReconcileTruckStatus brings every truck's checked-out flag in line with its
+// checkouts and logs the trucks that had drifted. Bookings that have started
+// since the last run are recorded as taking the truck's keys and fuel card.
+func ReconcileTruckStatus() {
+	actor := models.Actor{SlackUserID: "scheduler", Source: models.SourceScheduler}
+	now := time.Now()
+	mismatches, err := models.ReconcileTruckStatus(now, actor)
+	if err != nil {
+		log.Printf("Failed to reconcile truck status: %v", err)
+	}
+	for _, m := range mismatches {
+		log.Printf("Fixed truck status: %s", m)
+	}
+
+	handoffs, err := models.HandOffStartedCheckouts(now, actor)
+	if err != nil {
+		log.Printf("Failed to record custody for started checkouts: %v", err)
+	}
+	for _, e := range handoffs {
+		log.Printf("Recorded %s of truck %s going to %s", e.Item.Label(), e.TruckID, e.HolderID)
+	}
+}
+
+// RunStatusReconciler reconciles truck status every interval, catching
+// checkouts that started or ran out since the last change. Call
+// ReconcileTruckStatus once at startup first. It blocks, so run it in a
+// goroutine.
+func RunStatusReconciler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ReconcileTruckStatus()
+	}
 }
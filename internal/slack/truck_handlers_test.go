@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"truck-checkout/internal/models"
+)
+
+func TestTrucksWeek_ShowsMaintenance(t *testing.T) {
+	models.ResetTestDB(t)
+	h := newTestHandler()
+	h.cfg.FleetAdmins = []string{"UADMIN"}
+
+	team := "beltline"
+	for _, name := range []string{"Tulip", "Watson"} {
+		if err := models.InsertTruck(name, &team, "", false, testActor); err != nil {
+			t.Fatalf("failed to insert truck: %v", err)
+		}
+	}
+
+	m := &recordingMessenger{}
+	h.HandleFleet(m, m, []string{"maintenance", "tulip", "2026-11-03", "2026-11-04"}, "UADMIN")
+	if text := m.ackText(t); !strings.Contains(text, "out of service from Nov 3 through Nov 4") {
+		t.Fatalf("expected Tulip to go into the shop, got %q", text)
+	}
+
+	m = &recordingMessenger{}
+	day, _ := time.ParseInLocation("2006-01-02", "2026-11-03", h.cal.Location())
+	h.HandleTrucksWeek(m, m, day)
+	var tulip, watson string
+	for _, line := range strings.Split(m.ackText(t), "\n") {
+		switch {
+		case strings.HasPrefix(line, "Tulip"):
+			tulip = line
+		case strings.HasPrefix(line, "Watson"):
+			watson = line
+		}
+	}
+	if strings.Count(tulip, "maintenance") != 2 || strings.Contains(tulip, "maintenance*") {
+		t.Errorf("expected Tulip in the shop all of Tuesday and Wednesday, got %q", tulip)
+	}
+	if strings.Contains(watson, "maintenance") {
+		t.Errorf("expected Watson to stay free, got %q", watson)
+	}
+}
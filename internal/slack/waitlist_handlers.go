@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"truck-checkout/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/slack-go/slack"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// How long a waitlisted user has to claim a freed truck before it moves on.
+const waitlistOfferTTL = 15 * time.Minute
+
+// HandleWaitlist joins or leaves the waitlist, e.g. `/waitlist Tulip`,
+// `/waitlist any 2026-10-21` or `/waitlist leave`.
+func (h *Handler) HandleWaitlist(client Messenger, req Responder, args []string, userId string, userName string) {
+	if len(args) == 0 || len(args) > 2 {
+		req.Ack(map[string]string{
+			"text": "ℹ️ Use `/waitlist [truck-name|any] [YYYY-MM-DD]` to join, or `/waitlist leave [YYYY-MM-DD]` to leave.",
+		})
+		return
+	}
+
+	day := h.cal.Now()
+	if len(args) == 2 {
+		parsed, err := time.ParseInLocation(models.WaitlistDayFormat, args[1], h.cal.Location())
+		if err != nil {
+			req.Ack(map[string]string{"text": "⚠️ Invalid date. Use a date like `2026-10-21`."})
+			return
+		}
+		day = parsed
+	}
+	dayLabel := day.Format("Mon Jan 2")
+
+	if strings.EqualFold(args[0], "leave") {
+		removed, err := models.LeaveWaitlist(userId, day)
+		if err != nil {
+			log.Printf("Failed to leave waitlist for %s: %v", userId, err)
+			req.Ack(map[string]string{"text": "❌ Could not update the waitlist."})
+			return
+		}
+		if removed == 0 {
+			req.Ack(map[string]string{"text": fmt.Sprintf("ℹ️ You're not on the waitlist for %s.", dayLabel)})
+			return
+		}
+		req.Ack(map[string]string{"text": fmt.Sprintf("👋 Removed you from the waitlist for %s.", dayLabel)})
+		return
+	}
+
+	var truckID *uuid.UUID
+	target := "any truck"
+	if !strings.EqualFold(args[0], "any") {
+		truckName := cases.Title(language.English).String(strings.ToLower(args[0]))
+		truck, err := models.GetTruckByName(truckName)
+		if err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+		// Waiting only makes sense for a truck the user could check out.
+		user, err := models.GetUserBySlackID(userId)
+		if err != nil {
+			req.Ack(map[string]string{"text": "❌ Error retrieving user information."})
+			return
+		}
+		var team string
+		if user != nil {
+			team = user.Team
+		}
+		if err := models.CheckTeamAccess(truck, team); err != nil {
+			req.Ack(map[string]string{"text": h.errorMessage(err, truckName)})
+			return
+		}
+		truckID = &truck.ID
+		target = fmt.Sprintf("`%s`", truck.Name)
+	}
+
+	if _, err := models.JoinWaitlist(truckID, userId, userName, day); err != nil {
+		log.Printf("Failed to join waitlist for %s: %v", userId, err)
+		req.Ack(map[string]string{"text": h.errorMessage(err, "")})
+		return
+	}
+
+	req.Ack(map[string]string{
+		"text": fmt.Sprintf("📝 You're on the waitlist for %s on %s. I'll DM you if it frees up.", target, dayLabel),
+	})
+}
+
+// offerFreedTruck offers a truck that just became free between from and to
+// to the first person on the waitlist for one of the business days it's now
+// free for, via a DM with a time-limited Claim button.
+func (h *Handler) offerFreedTruck(client Messenger, truck *models.Truck, from, to time.Time) {
+	now := h.cal.Now()
+	days, err := h.freeDays(truck, from, to, now)
+	if err != nil {
+		log.Printf("Failed to check availability of %s for the waitlist: %v", truck.Name, err)
+		return
+	}
+	entry, err := models.OfferTruckToNextWaiter(truck.ID, days, now.Add(waitlistOfferTTL))
+	if err != nil {
+		log.Printf("Failed to offer truck %s to waitlist: %v", truck.Name, err)
+		return
+	}
+	if entry == nil {
+		return
+	}
+
+	dayLabel := entry.Day
+	if day, err := h.waitlistDay(entry); err == nil {
+		dayLabel = day.Format("Mon Jan 2")
+	}
+	text := fmt.Sprintf("🚛 Truck *%s* just freed up for %s! Claim it within %d minutes or it goes to the next person on the waitlist.",
+		truck.Name, dayLabel, int(waitlistOfferTTL.Minutes()))
+	claimButton := slack.NewButtonBlockElement(
+		"claim_waitlist_offer",
+		entry.ID.String(),
+		slack.NewTextBlockObject("plain_text", "Claim", true, false),
+	).WithStyle(slack.StylePrimary)
+
+	err = client.DirectMessage(entry.SlackUserID,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+			slack.NewActionBlock("waitlist_offer", claimButton),
+		),
+	)
+	if err != nil {
+		log.Printf("Failed to DM waitlist offer for %s to %s: %v", truck.Name, entry.SlackUserID, err)
+		return
+	}
+	log.Printf("Offered truck %s to waitlisted user %s", truck.Name, entry.UserName)
+}
+
+// freeDays returns the business days from from's date through to's date on
+// which the truck is free for the rest of the working day after now.
+func (h *Handler) freeDays(truck *models.Truck, from, to, now time.Time) ([]time.Time, error) {
+	loc := h.cal.Location()
+	from, to = from.In(loc), to.In(loc)
+	var days []time.Time
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !h.cal.IsBusinessDay(day) {
+			continue
+		}
+		start, end := h.cal.Opening(day), h.cal.Closing(day)
+		if start.Before(now) {
+			start = now
+		}
+		if !end.After(start) {
+			continue
+		}
+		free, err := models.GetTruckAvailability(truck.ID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		if len(free) == 1 && free[0].Start.Equal(start) && free[0].End.Equal(end) {
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+// waitlistDay is the start of the day a waitlist entry is for.
+func (h *Handler) waitlistDay(entry *models.WaitlistEntry) (time.Time, error) {
+	return time.ParseInLocation(models.WaitlistDayFormat, entry.Day, h.cal.Location())
+}
+
+// handleClaimWaitlistOffer checks out the offered truck, for the day the
+// user was waiting for, for the user who clicked Claim.
+func (h *Handler) handleClaimWaitlistOffer(client Messenger, callback *slack.InteractionCallback, value string) {
+	userId := callback.User.ID
+
+	entryID, err := uuid.Parse(value)
+	if err != nil {
+		log.Printf("Invalid waitlist offer ID %q: %v", value, err)
+		return
+	}
+
+	reply := func(text string) {
+		if err := client.DirectMessage(userId, slack.MsgOptionText(text, false)); err != nil {
+			log.Printf("Failed to DM %s: %v", userId, err)
+		}
+	}
+
+	entry, err := models.ClaimWaitlistOffer(entryID, userId)
+	if err != nil {
+		reply(h.errorMessage(err, ""))
+		return
+	}
+
+	// If the checkout falls through, the claim is given back so the truck
+	// goes to the next person instead of sitting unclaimed.
+	var truck *models.Truck
+	giveBack := func() {
+		if err := models.AbandonWaitlistClaim(entry.ID); err != nil {
+			log.Printf("Failed to give back waitlist claim %s: %v", entry.ID, err)
+			return
+		}
+		if truck == nil {
+			return
+		}
+		if day, err := h.waitlistDay(entry); err == nil {
+			h.offerFreedTruck(client, truck, day, day.AddDate(0, 0, 1))
+		}
+	}
+
+	truck, err = models.GetTruckByID(*entry.OfferedTruckID)
+	if err != nil {
+		log.Printf("Failed to load offered truck for entry %s: %v", entry.ID, err)
+		giveBack()
+		reply("❌ Could not find the offered truck.")
+		return
+	}
+
+	user, err := models.GetUserBySlackID(userId)
+	if err != nil || user == nil {
+		giveBack()
+		reply(fmt.Sprintf("⚠️ Pick your team with `/profile team [team]` before claiming a truck. I've passed `%s` on to the next person on the waitlist.", truck.Name))
+		return
+	}
+
+	day, err := h.waitlistDay(entry)
+	if err != nil {
+		log.Printf("Invalid day %q on waitlist entry %s: %v", entry.Day, entry.ID, err)
+		giveBack()
+		reply("❌ Could not read the day you were waiting for.")
+		return
+	}
+	from := day
+	if now := h.cal.Now(); from.Before(now) {
+		from = now
+	}
+
+	responseText, err := h.performCheckout(client, user, truck.Name, wholeDays(1), from, nil, false, userId, entry.UserName, models.SourceButton)
+	if err != nil {
+		log.Printf("Waitlist claim checkout error: %v", err)
+		giveBack()
+		reply(h.errorMessage(err, truck.Name))
+		return
+	}
+	reply(responseText)
+}
+
+// RunWaitlistExpiry periodically expires unclaimed offers and passes the
+// truck on to the next person in line. It blocks, so run it in a goroutine.
+func (h *Handler) RunWaitlistExpiry(client Messenger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := models.ExpireWaitlistOffers(time.Now())
+		if err != nil {
+			log.Printf("Failed to expire waitlist offers: %v", err)
+			continue
+		}
+		for _, entry := range expired {
+			if entry.OfferedTruckID == nil {
+				continue
+			}
+			truck, err := models.GetTruckByID(*entry.OfferedTruckID)
+			if err != nil {
+				log.Printf("Failed to load truck for expired offer %s: %v", entry.ID, err)
+				continue
+			}
+			day, err := h.waitlistDay(&entry)
+			if err != nil {
+				log.Printf("Invalid day %q on waitlist entry %s: %v", entry.Day, entry.ID, err)
+				continue
+			}
+			h.offerFreedTruck(client, truck, day, day.AddDate(0, 0, 1))
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"truck-checkout/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/slack-go/slack"
+)
+
+func TestClaimWaitlistOffer_FailedCheckoutPassesTruckOn(t *testing.T) {
+	models.ResetTestDB(t)
+	h := newTestHandler()
+	openAllHours(h)
+
+	team := "beltline"
+	if err := models.InsertTruck("Tulip", &team, "", false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := models.GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to load truck: %v", err)
+	}
+	// U111 is first in line but has no license on file; U222 can drive.
+	for _, u := range []struct{ id, name string }{{"U111", "alice"}, {"U222", "bob"}} {
+		if _, err := models.GetOrCreateUserBySlackID(u.id, u.name, "beltline", testActor); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	admin := models.Actor{SlackUserID: "UADMIN", Source: models.SourceSlashCommand}
+	if _, err := models.RecordQualification("U222", models.QualificationLicense, "", time.Now().AddDate(1, 0, 0), admin); err != nil {
+		t.Fatalf("failed to record license: %v", err)
+	}
+	now := h.cal.Now()
+	first, err := models.JoinWaitlist(&truck.ID, "U111", "alice", now)
+	if err != nil {
+		t.Fatalf("failed to join waitlist: %v", err)
+	}
+	second, err := models.JoinWaitlist(&truck.ID, "U222", "bob", now)
+	if err != nil {
+		t.Fatalf("failed to join waitlist: %v", err)
+	}
+
+	m := &recordingMessenger{}
+	h.offerFreedTruck(m, truck, now, now)
+	if len(m.dmsTo("U111")) != 1 {
+		t.Fatalf("expected an offer to the first waiter, got %+v", m.messages)
+	}
+
+	h.handleClaimWaitlistOffer(m, &slack.InteractionCallback{User: slack.User{ID: "U111"}}, first.ID.String())
+	if dms := m.dmsTo("U111"); len(dms) != 2 || !strings.Contains(dms[1].Text, "license") {
+		t.Errorf("expected the first waiter to hear about their license, got %+v", dms)
+	}
+	if entry, err := models.GetWaitlistEntryByID(first.ID); err != nil || entry.Status != models.WaitlistExpired {
+		t.Errorf("expected the failed claim to be given back, got %+v (%v)", entry, err)
+	}
+
+	offers := m.dmsTo("U222")
+	if len(offers) != 1 || !strings.Contains(offers[0].Text, "just freed up") {
+		t.Fatalf("expected the truck to be offered to the next waiter, got %+v", offers)
+	}
+	// The announcement failing doesn't undo a claim that went through.
+	m.failPosts = true
+	h.handleClaimWaitlistOffer(m, &slack.InteractionCallback{User: slack.User{ID: "U222"}}, second.ID.String())
+	if _, err := models.GetActiveCheckoutByTruckID(truck.ID); err != nil {
+		t.Errorf("expected the next waiter to have checked out the truck: %v", err)
+	}
+	if entry, err := models.GetWaitlistEntryByID(second.ID); err != nil || entry.Status != models.WaitlistClaimed {
+		t.Errorf("expected the claim to stand, got %+v (%v)", entry, err)
+	}
+	if dms := m.dmsTo("U222"); len(dms) != 2 || !strings.Contains(dms[1].Text, "Tulip") || strings.Contains(dms[1].Text, "❌") {
+		t.Errorf("expected the next waiter to be told they have the truck, got %+v", dms)
+	}
+}
+
+func TestCancelSeriesRemainder_OffersFreedDays(t *testing.T) {
+	models.ResetTestDB(t)
+	h := newTestHandler()
+	openAllHours(h)
+
+	team := "beltline"
+	if err := models.InsertTruck("Tulip", &team, "", false, testActor); err != nil {
+		t.Fatalf("failed to insert truck: %v", err)
+	}
+	truck, err := models.GetTruckByName("Tulip")
+	if err != nil {
+		t.Fatalf("failed to load truck: %v", err)
+	}
+	for _, u := range []struct{ id, name string }{{"U111", "alice"}, {"U222", "bob"}} {
+		if _, err := models.GetOrCreateUserBySlackID(u.id, u.name, team, testActor); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+
+	today := h.cal.Now().Truncate(24 * time.Hour)
+	first, last := today.AddDate(0, 0, 2), today.AddDate(0, 0, 3)
+	series := models.CheckoutSeries{
+		ID:           uuid.New(),
+		TruckID:      truck.ID,
+		UserID:       "U111",
+		UserName:     "alice",
+		TeamName:     team,
+		Weekdays:     strings.ToLower(first.Format("Mon") + "," + last.Format("Mon")),
+		FirstDate:    first,
+		LastDate:     last,
+		BusinessDays: 1,
+	}
+	occurrences := []models.Occurrence{{Start: first, End: first.AddDate(0, 0, 1)}, {Start: last, End: last.AddDate(0, 0, 1)}}
+	if _, _, err := models.CreateCheckoutSeries(series, occurrences, "", nil, testActor); err != nil {
+		t.Fatalf("failed to create series: %v", err)
+	}
+	// bob is waiting on the last occurrence's day.
+	if _, err := models.JoinWaitlist(&truck.ID, "U222", "bob", last); err != nil {
+		t.Fatalf("failed to join waitlist: %v", err)
+	}
+
+	m := &recordingMessenger{}
+	text := h.cancelSeriesRemainder(m, &slack.InteractionCallback{User: slack.User{ID: "U111", Name: "alice"}}, series.ID.String())
+	if !strings.Contains(text, "Cancelled the 2 remaining occurrences") {
+		t.Fatalf("unexpected reply %q", text)
+	}
+	if offers := m.dmsTo("U222"); len(offers) != 1 || !strings.Contains(offers[0].Text, "just freed up") {
+		t.Errorf("expected the freed day to be offered to the waiter, got %+v", offers)
+	}
+}